@@ -0,0 +1,163 @@
+package constantweight
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	
+	"github.com/aerius-labs/hash-sig-go/encoding"
+	"github.com/aerius-labs/hash-sig-go/th"
+)
+
+// ConstantWeightEncoding implements a binary constant-weight incomparable
+// encoding: every codeword has exactly weight of its dimension coordinates
+// set to 1. Two distinct vectors of equal weight are never comparable.
+//
+// The message hash output is read as an integer X in [0, base^n) and mapped
+// uniformly onto the C(dimension, weight) codewords by rejection sampling:
+// X is rejected (and signing retries with fresh randomness) if it falls into
+// the incomplete final block of size base^n mod C(dimension, weight).
+type ConstantWeightEncoding struct {
+	messageHash encoding.MessageHash
+	dimension   int // v - number of chains
+	weight      int // k - number of coordinates set to 1
+	
+	numCodewords *big.Int // C(v, k)
+	acceptBound  *big.Int // largest multiple of C(v, k) not above base^n
+}
+
+// NewConstantWeightEncoding creates a new constant-weight encoding with the
+// given dimension and weight. The message hash must provide at least
+// log2(C(dimension, weight)) bits of output.
+func NewConstantWeightEncoding(messageHash encoding.MessageHash, dimension, weight int) *ConstantWeightEncoding {
+	if dimension < 1 || dimension > 256 {
+		panic("dimension must be between 1 and 256")
+	}
+	if weight < 0 || weight > dimension {
+		panic(fmt.Sprintf("weight %d out of range [0, %d]", weight, dimension))
+	}
+	
+	numCodewords := new(big.Int).Binomial(int64(dimension), int64(weight))
+	hashSpace := new(big.Int).Exp(
+		big.NewInt(int64(messageHash.Base())),
+		big.NewInt(int64(messageHash.Dimension())),
+		nil,
+	)
+	if hashSpace.Cmp(numCodewords) < 0 {
+		panic("message hash output is too small to index all codewords")
+	}
+	
+	acceptBound := new(big.Int).Div(hashSpace, numCodewords)
+	acceptBound.Mul(acceptBound, numCodewords)
+	
+	return &ConstantWeightEncoding{
+		messageHash:  messageHash,
+		dimension:    dimension,
+		weight:       weight,
+		numCodewords: numCodewords,
+		acceptBound:  acceptBound,
+	}
+}
+
+// Encode implements the constant-weight encoding
+// Returns error if the hash falls outside the uniformly mappable range (need retry with new ρ)
+func (c *ConstantWeightEncoding) Encode(P th.Params, msg []byte, rho []byte, epoch uint32) (encoding.Codeword, error) {
+	chunks := c.messageHash.Hash(P, msg, rho, epoch)
+	
+	// Interpret chunks as little-endian digits in base Base()
+	x := new(big.Int)
+	base := big.NewInt(int64(c.messageHash.Base()))
+	for i := len(chunks) - 1; i >= 0; i-- {
+		x.Mul(x, base)
+		x.Add(x, big.NewInt(int64(chunks[i])))
+	}
+	
+	if x.Cmp(c.acceptBound) >= 0 {
+		return nil, fmt.Errorf("%w: message hash outside uniform range", encoding.ErrEncodingFailed)
+	}
+	
+	return c.unrank(x.Mod(x, c.numCodewords)), nil
+}
+
+// unrank maps r in [0, C(v, k)) to the r-th weight-k vector in
+// lexicographic order of the positions set to 1
+func (c *ConstantWeightEncoding) unrank(r *big.Int) encoding.Codeword {
+	codeword := make(encoding.Codeword, c.dimension)
+	remaining := c.weight
+	count := new(big.Int)
+	
+	for i := 0; i < c.dimension && remaining > 0; i++ {
+		// Number of codewords that set position i, given the choices so far
+		count.Binomial(int64(c.dimension-i-1), int64(remaining-1))
+		if r.Cmp(count) < 0 {
+			codeword[i] = 1
+			remaining--
+		} else {
+			r.Sub(r, count)
+		}
+	}
+	
+	return codeword
+}
+
+// RandRandomness generates randomness for encoding
+func (c *ConstantWeightEncoding) RandRandomness(rng io.Reader) ([]byte, error) {
+	rand := make([]byte, c.RandLen())
+	if err := c.RandRandomnessInto(rand, rng); err != nil {
+		return nil, err
+	}
+	return rand, nil
+}
+
+// RandRandomnessInto fills dst, which must be RandLen() bytes, with randomness
+func (c *ConstantWeightEncoding) RandRandomnessInto(dst []byte, rng io.Reader) error {
+	return encoding.FillRandomness(dst, c.RandLen(), rng)
+}
+
+// RandLen returns the randomness length of the underlying message hash
+func (c *ConstantWeightEncoding) RandLen() int {
+	return c.messageHash.RandLen()
+}
+
+// Dimension returns v, the number of chains
+func (c *ConstantWeightEncoding) Dimension() int {
+	return c.dimension
+}
+
+// Validate checks the configuration of the underlying message hash
+func (c *ConstantWeightEncoding) Validate() error {
+	return encoding.ValidateMessageHash(c.messageHash)
+}
+
+// MessageLen returns the message length the underlying message hash
+// accepts, or 0 if it accepts any length
+func (c *ConstantWeightEncoding) MessageLen() int {
+	return encoding.MessageLen(c.messageHash)
+}
+
+// Base returns 2, since every coordinate is 0 or 1
+func (c *ConstantWeightEncoding) Base() int {
+	return 2
+}
+
+// ChunkSize returns 1
+func (c *ConstantWeightEncoding) ChunkSize() int {
+	return 1
+}
+
+// Weight returns k, the number of coordinates set in every codeword
+func (c *ConstantWeightEncoding) Weight() int {
+	return c.weight
+}
+
+// MaxTries returns the maximum number of encoding attempts.
+// Each attempt is rejected with probability below 1/2, and far below
+// that whenever the message hash output is much larger than C(v, k).
+func (c *ConstantWeightEncoding) MaxTries() int {
+	return 128
+}
+
+// NeedsRetry returns true (hashes outside the uniform range are rejected)
+func (c *ConstantWeightEncoding) NeedsRetry() bool {
+	return true
+}
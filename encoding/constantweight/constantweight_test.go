@@ -0,0 +1,36 @@
+package constantweight
+
+import (
+	"math/big"
+	"testing"
+	
+	"github.com/aerius-labs/hash-sig-go/th/message_hash"
+)
+
+// Test that unranking enumerates every weight-k vector exactly once
+func TestUnrankIsBijective(t *testing.T) {
+	mh := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	enc := NewConstantWeightEncoding(mh, 8, 3)
+	
+	seen := make(map[string]bool)
+	for r := int64(0); r < 56; r++ { // C(8, 3) = 56
+		codeword := enc.unrank(big.NewInt(r))
+		
+		weight := 0
+		for _, x := range codeword {
+			if x > 1 {
+				t.Fatalf("Rank %d produced non-binary coordinate %d", r, x)
+			}
+			weight += int(x)
+		}
+		if weight != 3 {
+			t.Fatalf("Rank %d produced weight %d", r, weight)
+		}
+		
+		key := string(codeword)
+		if seen[key] {
+			t.Fatalf("Rank %d produced a duplicate codeword %v", r, codeword)
+		}
+		seen[key] = true
+	}
+}
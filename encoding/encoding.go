@@ -2,6 +2,7 @@ package encoding
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	
 	"github.com/aerius-labs/hash-sig-go/th"
@@ -10,6 +11,23 @@ import (
 // ErrEncodingFailed indicates encoding failed and needs retry with new randomness
 var ErrEncodingFailed = errors.New("encoding failed, retry needed")
 
+// SumMismatchError is returned by sum-constrained encodings (Target-Sum)
+// when the chunks of an attempt do not hit the target. It matches
+// ErrEncodingFailed under errors.Is.
+type SumMismatchError struct {
+	Target int
+	Sum    int
+}
+
+func (e *SumMismatchError) Error() string {
+	return fmt.Sprintf("%v: expected sum %d, got %d", ErrEncodingFailed, e.Target, e.Sum)
+}
+
+// Unwrap returns ErrEncodingFailed
+func (e *SumMismatchError) Unwrap() error {
+	return ErrEncodingFailed
+}
+
 // Codeword represents an encoded message as chunks
 type Codeword []uint8
 
@@ -20,8 +38,9 @@ type IncomparableEncoding interface {
 	// Returns ErrEncodingFailed if encoding fails (needs new randomness)
 	Encode(P th.Params, msg []byte, rho []byte, epoch uint32) (Codeword, error)
 	
-	// RandRandomness generates randomness for encoding
-	RandRandomness(rng io.Reader) []byte
+	// RandRandomness generates randomness for encoding. It reads exactly
+	// RandLen bytes from rng and returns an error if rng fails or runs short.
+	RandRandomness(rng io.Reader) ([]byte, error)
 	
 	// Dimension returns the number of chunks in a codeword (v)
 	Dimension() int
@@ -38,4 +57,59 @@ type IncomparableEncoding interface {
 	// NeedsRetry indicates if this encoding may fail and need retries
 	// (true for Target-Sum, false for Winternitz)
 	NeedsRetry() bool
-}
\ No newline at end of file
+}
+
+// DeterministicRandomness is an optional extension of IncomparableEncoding
+// for encodings that derive the randomness of each signing attempt from the
+// attempt index instead of an RNG, making the retry sequence reproducible
+type DeterministicRandomness interface {
+	// AttemptRandomness returns the randomness for the given zero-based attempt
+	AttemptRandomness(epoch uint32, msg []byte, attempt int) []byte
+}
+
+// RandomnessFiller is an optional extension of IncomparableEncoding for
+// encodings that can draw randomness into a caller-owned buffer, so that
+// retry loops allocate it once instead of per attempt
+type RandomnessFiller interface {
+	// RandLen returns the length of the randomness in bytes
+	RandLen() int
+	
+	// RandRandomnessInto fills dst like RandRandomness. It panics unless
+	// len(dst) == RandLen().
+	RandRandomnessInto(dst []byte, rng io.Reader) error
+}
+
+// FillRandomness reads exactly randLen bytes from rng into dst, for
+// implementations of RandomnessFiller. It panics unless len(dst) ==
+// randLen.
+func FillRandomness(dst []byte, randLen int, rng io.Reader) error {
+	if len(dst) != randLen {
+		panic(fmt.Sprintf("randomness buffer has %d bytes, expected %d", len(dst), randLen))
+	}
+	if _, err := io.ReadFull(rng, dst); err != nil {
+		return fmt.Errorf("failed to generate randomness: %w", err)
+	}
+	return nil
+}
+
+// EncodePreparer is an optional extension of IncomparableEncoding for
+// retrying encodings that can reuse randomness-independent work across
+// attempts on the same (parameter, message, epoch)
+type EncodePreparer interface {
+	// PrepareEncode precomputes the randomness-independent inputs
+	PrepareEncode(P th.Params, msg []byte, epoch uint32) PreparedMessage
+	
+	// EncodePrepared computes Encode(P, msg, rho, epoch) for the inputs
+	// the handle was prepared from
+	EncodePrepared(prepared PreparedMessage, rho []byte) (Codeword, error)
+}
+
+// ChunkEncoder is an optional extension of IncomparableEncoding for
+// encodings whose codeword is a function of the message-hash chunks alone
+type ChunkEncoder interface {
+	// CodewordFromChunks returns the codeword Encode would produce for a
+	// message hash with these output chunks. It returns an error for the
+	// wrong number of chunks or a chunk not below the base, and whatever
+	// Encode would return for chunks that fail to encode.
+	CodewordFromChunks(messageChunks []uint8) (Codeword, error)
+}
@@ -21,4 +21,55 @@ type MessageHash interface {
 	
 	// ChunkSize returns the chunk size in bits (w)
 	ChunkSize() int
-}
\ No newline at end of file
+}
+
+// PreparedMessage is an opaque, implementation-specific precomputed form of
+// the randomness-independent message-hash inputs (parameter, message and
+// epoch), produced by MessagePreparer.PrepareMessage
+type PreparedMessage interface{}
+
+// MessagePreparer is an optional extension of MessageHash for
+// implementations that can precompute everything except the randomness,
+// so retried encodings only redo the randomness-dependent work
+type MessagePreparer interface {
+	// PrepareMessage precomputes the randomness-independent inputs
+	PrepareMessage(params th.Params, msg []byte, epoch uint32) PreparedMessage
+	
+	// HashPrepared computes Hash(params, msg, rand, epoch) for the inputs
+	// the handle was prepared from
+	HashPrepared(prepared PreparedMessage, rand []byte) []byte
+}
+
+// Validator is an optional extension of MessageHash and IncomparableEncoding
+// for configurations that can be checked beyond what their constructors
+// enforce, e.g. that a message hash has enough output entropy for its chunks
+type Validator interface {
+	// Validate returns a descriptive error for an unusable configuration
+	Validate() error
+}
+
+// ValidateMessageHash returns mh.Validate() if mh implements Validator,
+// and nil otherwise
+func ValidateMessageHash(mh MessageHash) error {
+	if v, ok := mh.(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// FixedLengthMessages is an optional extension of MessageHash and
+// IncomparableEncoding for those that encode messages of one length only,
+// e.g. because the message is packed into a fixed number of field elements
+type FixedLengthMessages interface {
+	// MessageLen returns the accepted message length in bytes
+	MessageLen() int
+}
+
+// MessageLen returns the message length mh accepts in bytes, or 0 if it
+// accepts any length
+func MessageLen(mh MessageHash) int {
+	if f, ok := mh.(FixedLengthMessages); ok {
+		return f.MessageLen()
+	}
+	return 0
+}
@@ -1,10 +1,16 @@
 package targetsum
 
 import (
+	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
+	"math/big"
+	
+	"golang.org/x/crypto/sha3"
 	
 	"github.com/aerius-labs/hash-sig-go/encoding"
+	"github.com/aerius-labs/hash-sig-go/hypercube"
 	"github.com/aerius-labs/hash-sig-go/th"
 )
 
@@ -12,11 +18,27 @@ import (
 type TargetSumEncoding struct {
 	messageHash encoding.MessageHash
 	targetSum   int  // T - the target sum value
+	maxTries    int
 }
 
+// DefaultMaxTries is the number of encoding attempts NewTargetSumEncoding
+// allows, based on empirical testing in the paper
+const DefaultMaxTries = 100000
+
 // NewTargetSumEncoding creates a new Target-Sum encoding
 // targetSum should be close to v*(2^w-1)/2 for best performance
 func NewTargetSumEncoding(messageHash encoding.MessageHash, targetSum int) *TargetSumEncoding {
+	return NewTargetSumEncodingWithTries(messageHash, targetSum, DefaultMaxTries)
+}
+
+// NewTargetSumEncodingWithTries creates a Target-Sum encoding that gives up
+// signing after maxTries encoding attempts, e.g. to fail fast in
+// latency-sensitive services
+func NewTargetSumEncodingWithTries(messageHash encoding.MessageHash, targetSum, maxTries int) *TargetSumEncoding {
+	if maxTries < 1 {
+		panic(fmt.Sprintf("max tries %d must be at least 1", maxTries))
+	}
+	
 	// Verify target sum is reasonable
 	base := messageHash.Base()
 	dimension := messageHash.Dimension()
@@ -29,6 +51,7 @@ func NewTargetSumEncoding(messageHash encoding.MessageHash, targetSum int) *Targ
 	return &TargetSumEncoding{
 		messageHash: messageHash,
 		targetSum:   targetSum,
+		maxTries:    maxTries,
 	}
 }
 
@@ -36,8 +59,57 @@ func NewTargetSumEncoding(messageHash encoding.MessageHash, targetSum int) *Targ
 // Returns error if the chunks don't sum to the target (need retry with new ρ)
 func (t *TargetSumEncoding) Encode(P th.Params, msg []byte, rho []byte, epoch uint32) (encoding.Codeword, error) {
 	// Apply message hash to get chunks
-	chunks := t.messageHash.Hash(P, msg, rho, epoch)
-	
+	return t.checkSum(t.messageHash.Hash(P, msg, rho, epoch))
+}
+
+// targetSumPrepared is the prepared form of Encode inputs
+type targetSumPrepared struct {
+	// prepared is set when the message hash implements MessagePreparer
+	prepared encoding.PreparedMessage
+	P        th.Params
+	msg      []byte
+	epoch    uint32
+}
+
+// PrepareEncode precomputes the message-hash inputs that stay fixed across
+// retries, when the message hash supports it
+func (t *TargetSumEncoding) PrepareEncode(P th.Params, msg []byte, epoch uint32) encoding.PreparedMessage {
+	prepared := &targetSumPrepared{P: P, msg: msg, epoch: epoch}
+	if preparer, ok := t.messageHash.(encoding.MessagePreparer); ok {
+		prepared.prepared = preparer.PrepareMessage(P, msg, epoch)
+	}
+	return prepared
+}
+
+// EncodePrepared implements Encode for prepared inputs
+func (t *TargetSumEncoding) EncodePrepared(prepared encoding.PreparedMessage, rho []byte) (encoding.Codeword, error) {
+	tp, ok := prepared.(*targetSumPrepared)
+	if !ok {
+		panic("prepared message was not produced by TargetSumEncoding")
+	}
+	if tp.prepared == nil {
+		return t.checkSum(t.messageHash.Hash(tp.P, tp.msg, rho, tp.epoch))
+	}
+	return t.checkSum(t.messageHash.(encoding.MessagePreparer).HashPrepared(tp.prepared, rho))
+}
+
+// CodewordFromChunks returns the codeword for message-hash output chunks,
+// which are the codeword itself if they sum to the target. Otherwise it
+// returns a *encoding.SumMismatchError, as Encode would.
+func (t *TargetSumEncoding) CodewordFromChunks(chunks []uint8) (encoding.Codeword, error) {
+	if len(chunks) != t.Dimension() {
+		return nil, fmt.Errorf("expected %d chunks, got %d", t.Dimension(), len(chunks))
+	}
+	for i, chunk := range chunks {
+		if int(chunk) >= t.Base() {
+			return nil, fmt.Errorf("chunk %d is %d, not below base %d", i, chunk, t.Base())
+		}
+	}
+	return t.checkSum(chunks)
+}
+
+// checkSum turns message-hash chunks into a codeword if they hit the target
+func (t *TargetSumEncoding) checkSum(chunks []byte) (encoding.Codeword, error) {
 	// Compute sum
 	sum := 0
 	for _, chunk := range chunks {
@@ -46,7 +118,7 @@ func (t *TargetSumEncoding) Encode(P th.Params, msg []byte, rho []byte, epoch ui
 	
 	// Check if sum equals target
 	if sum != t.targetSum {
-		return nil, fmt.Errorf("%w: expected sum %d, got %d", encoding.ErrEncodingFailed, t.targetSum, sum)
+		return nil, &encoding.SumMismatchError{Target: t.targetSum, Sum: sum}
 	}
 	
 	// Success - return chunks as codeword
@@ -59,12 +131,22 @@ func (t *TargetSumEncoding) Encode(P th.Params, msg []byte, rho []byte, epoch ui
 }
 
 // RandRandomness generates randomness for encoding
-func (t *TargetSumEncoding) RandRandomness(rng io.Reader) []byte {
-	// Generate random bytes based on the message hash's randomness length
-	randLen := t.messageHash.RandLen()
-	rand := make([]byte, randLen)
-	rng.Read(rand)
-	return rand
+func (t *TargetSumEncoding) RandRandomness(rng io.Reader) ([]byte, error) {
+	rand := make([]byte, t.RandLen())
+	if err := t.RandRandomnessInto(rand, rng); err != nil {
+		return nil, err
+	}
+	return rand, nil
+}
+
+// RandRandomnessInto fills dst, which must be RandLen() bytes, with randomness
+func (t *TargetSumEncoding) RandRandomnessInto(dst []byte, rng io.Reader) error {
+	return encoding.FillRandomness(dst, t.RandLen(), rng)
+}
+
+// RandLen returns the randomness length of the underlying message hash
+func (t *TargetSumEncoding) RandLen() int {
+	return t.messageHash.RandLen()
 }
 
 // Dimension returns v (number of chunks)
@@ -72,6 +154,17 @@ func (t *TargetSumEncoding) Dimension() int {
 	return t.messageHash.Dimension()
 }
 
+// Validate checks the configuration of the underlying message hash
+func (t *TargetSumEncoding) Validate() error {
+	return encoding.ValidateMessageHash(t.messageHash)
+}
+
+// MessageLen returns the message length the underlying message hash
+// accepts, or 0 if it accepts any length
+func (t *TargetSumEncoding) MessageLen() int {
+	return encoding.MessageLen(t.messageHash)
+}
+
 // Base returns 2^w
 func (t *TargetSumEncoding) Base() int {
 	return t.messageHash.Base()
@@ -84,8 +177,7 @@ func (t *TargetSumEncoding) ChunkSize() int {
 
 // MaxTries returns the maximum number of encoding attempts
 func (t *TargetSumEncoding) MaxTries() int {
-	// Based on empirical testing in the paper
-	return 100000
+	return t.maxTries
 }
 
 // NeedsRetry returns true (Target-Sum may need retries)
@@ -93,10 +185,72 @@ func (t *TargetSumEncoding) NeedsRetry() bool {
 	return true
 }
 
+// ExpectedTries returns the expected number of encoding attempts per
+// signature, 1/Pr[sum == target], assuming uniformly distributed chunks.
+// It returns +Inf if the target is unreachable.
+func (t *TargetSumEncoding) ExpectedTries() float64 {
+	p := ProbabilityOfTarget(t.messageHash.Dimension(), t.messageHash.Base(), t.targetSum)
+	if p == 0 {
+		return math.Inf(1)
+	}
+	return 1 / p
+}
+
+// ProbabilityOfTarget returns the probability that dimension uniform chunks
+// in [0, base-1] sum to target: the size of the target layer of the
+// hypercube divided by base^dimension
+func ProbabilityOfTarget(dimension, base, target int) float64 {
+	count := hypercube.CountVerticesTargetSum(base, dimension, target)
+	total := new(big.Int).Exp(big.NewInt(int64(base)), big.NewInt(int64(dimension)), nil)
+	p, _ := new(big.Rat).SetFrac(count, total).Float64()
+	return p
+}
+
 // ComputeOptimalTarget computes the optimal target sum T
 // delta should be 1.0 or 1.1 as suggested in the paper
 func ComputeOptimalTarget(dimension int, chunkSize int, delta float64) int {
 	base := 1 << chunkSize
 	maxChunkValue := base - 1
 	return int(delta * float64(dimension) * float64(maxChunkValue) / 2.0)
-}
\ No newline at end of file
+}
+
+// Domain separator for counter-derived randomness
+var counterRhoDomainSep = []byte("hash-sig-go target-sum counter rho")
+
+// CounterRhoTargetSumEncoding is a Target-Sum encoding whose per-attempt
+// randomness is derived from a seed and the retry counter, so the sequence
+// of attempts (and thus the resulting signature) is reproducible
+type CounterRhoTargetSumEncoding struct {
+	*TargetSumEncoding
+	seed []byte
+}
+
+// NewTargetSumEncodingCounterRho creates a Target-Sum encoding with
+// counter-derived randomness. Signing with it is deterministic in
+// (seed, epoch, message), which is useful for analysing convergence.
+func NewTargetSumEncodingCounterRho(messageHash encoding.MessageHash, targetSum int, seed []byte) *CounterRhoTargetSumEncoding {
+	return &CounterRhoTargetSumEncoding{
+		TargetSumEncoding: NewTargetSumEncoding(messageHash, targetSum),
+		seed:              append([]byte(nil), seed...),
+	}
+}
+
+// AttemptRandomness derives rho = SHAKE128(sep || len(seed) || seed || epoch || attempt || msg)
+func (t *CounterRhoTargetSumEncoding) AttemptRandomness(epoch uint32, msg []byte, attempt int) []byte {
+	shake := sha3.NewShake128()
+	shake.Write(counterRhoDomainSep)
+	
+	// Length-prefix the seed so seed and epoch cannot be shifted into each other
+	var header [16]byte
+	binary.LittleEndian.PutUint32(header[0:4], uint32(len(t.seed)))
+	shake.Write(header[0:4])
+	shake.Write(t.seed)
+	binary.LittleEndian.PutUint32(header[4:8], epoch)
+	binary.LittleEndian.PutUint64(header[8:16], uint64(attempt))
+	shake.Write(header[4:16])
+	shake.Write(msg)
+	
+	rho := make([]byte, t.messageHash.RandLen())
+	shake.Read(rho)
+	return rho
+}
@@ -0,0 +1,136 @@
+package targetsum
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"math"
+	"testing"
+	
+	"github.com/aerius-labs/hash-sig-go/encoding"
+	"github.com/aerius-labs/hash-sig-go/th/message_hash"
+)
+
+// Test target probabilities on small hypercubes
+func TestProbabilityOfTarget(t *testing.T) {
+	testCases := []struct {
+		dimension, base, target int
+		expected                float64
+	}{
+		{1, 2, 0, 0.5},
+		{2, 2, 1, 0.5},      // 01, 10 out of 4
+		{2, 3, 2, 3.0 / 9},  // 02, 11, 20
+		{3, 4, 9, 1.0 / 64}, // only 333
+		{3, 4, 10, 0},       // above the maximum sum
+	}
+	
+	for _, tc := range testCases {
+		got := ProbabilityOfTarget(tc.dimension, tc.base, tc.target)
+		if math.Abs(got-tc.expected) > 1e-12 {
+			t.Errorf("ProbabilityOfTarget(%d, %d, %d) = %g, want %g", tc.dimension, tc.base, tc.target, got, tc.expected)
+		}
+	}
+}
+
+// Test the expected number of signing attempts for the SHA3 test configuration
+func TestExpectedTries(t *testing.T) {
+	mh := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	
+	// The mean sum is 360; hitting it exactly takes a few dozen tries
+	tries := NewTargetSumEncoding(mh, ComputeOptimalTarget(48, 4, 1.0)).ExpectedTries()
+	if tries < 10 || tries > 100 {
+		t.Fatalf("Unexpected expected tries at the mean: %g", tries)
+	}
+	
+	// Moving away from the mean makes signing more expensive
+	if far := NewTargetSumEncoding(mh, 200).ExpectedTries(); far <= tries {
+		t.Fatalf("Expected more tries far from the mean: %g <= %g", far, tries)
+	}
+}
+
+// Test that CodewordFromChunks agrees with Encode on the message-hash output
+func TestCodewordFromChunks(t *testing.T) {
+	mh := message_hash.NewSHA3MessageHash(24, 24, 8, 2)
+	
+	param := make([]byte, 24)
+	rand.Read(param)
+	msg := make([]byte, 32)
+	rand.Read(msg)
+	rho := make([]byte, 24)
+	rand.Read(rho)
+	
+	chunks := mh.Hash(param, msg, rho, 1)
+	sum := 0
+	for _, c := range chunks {
+		sum += int(c)
+	}
+	
+	// An encoding whose target is this sum accepts, any other rejects
+	for _, target := range []int{sum, (sum + 1) % 25} {
+		enc := NewTargetSumEncoding(mh, target)
+		want, wantErr := enc.Encode(param, msg, rho, 1)
+		got, err := enc.CodewordFromChunks(chunks)
+		if (err == nil) != (wantErr == nil) || !bytes.Equal(got, want) {
+			t.Fatalf("Target %d: CodewordFromChunks = %v, %v; Encode = %v, %v", target, got, err, want, wantErr)
+		}
+		if target != sum && !errors.Is(err, encoding.ErrEncodingFailed) {
+			t.Fatalf("Expected a sum mismatch, got %v", err)
+		}
+	}
+	
+	if _, err := NewTargetSumEncoding(mh, sum).CodewordFromChunks(chunks[1:]); err == nil {
+		t.Fatal("Expected an error for the wrong number of chunks")
+	}
+}
+
+// Test the configurable encoding-attempt budget
+func TestNewTargetSumEncodingWithTries(t *testing.T) {
+	mh := message_hash.NewSHA3MessageHash(24, 24, 8, 2)
+	
+	if got := NewTargetSumEncoding(mh, 12).MaxTries(); got != DefaultMaxTries {
+		t.Fatalf("Default MaxTries = %d, want %d", got, DefaultMaxTries)
+	}
+	if got := NewTargetSumEncodingWithTries(mh, 12, 5000).MaxTries(); got != 5000 {
+		t.Fatalf("MaxTries = %d, want 5000", got)
+	}
+	
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected a panic for a budget of 0 tries")
+		}
+	}()
+	NewTargetSumEncodingWithTries(mh, 12, 0)
+}
+
+// Test that RandRandomnessInto fills exactly RandLen bytes of a caller buffer
+func TestRandRandomnessInto(t *testing.T) {
+	enc := NewTargetSumEncoding(message_hash.NewSHA3MessageHash(24, 20, 8, 2), 12)
+	if enc.RandLen() != 20 {
+		t.Fatalf("RandLen = %d, want 20", enc.RandLen())
+	}
+	
+	source := bytes.Repeat([]byte{0xab}, 40)
+	rho := make([]byte, enc.RandLen())
+	if err := enc.RandRandomnessInto(rho, bytes.NewReader(source)); err != nil {
+		t.Fatalf("RandRandomnessInto failed: %v", err)
+	}
+	if !bytes.Equal(rho, source[:20]) {
+		t.Fatalf("Buffer not filled from the reader: %x", rho)
+	}
+	want, _ := enc.RandRandomness(bytes.NewReader(source))
+	if !bytes.Equal(rho, want) {
+		t.Fatal("RandRandomnessInto differs from RandRandomness")
+	}
+	
+	if err := enc.RandRandomnessInto(rho, bytes.NewReader(source[:10])); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("Expected a short-read error, got %v", err)
+	}
+	
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected a panic for a buffer of the wrong length")
+		}
+	}()
+	enc.RandRandomnessInto(make([]byte, 24), bytes.NewReader(source))
+}
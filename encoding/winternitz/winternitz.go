@@ -2,6 +2,7 @@ package winternitz
 
 import (
 	"encoding/binary"
+	"fmt"
 	"io"
 	"math"
 	
@@ -32,12 +33,7 @@ func NewWinternitzEncoding(messageHash encoding.MessageHash, chunkSize int, numC
 	}
 	
 	// Verify checksum length is correct
-	base := 1 << chunkSize
-	numChunksMessage := messageHash.Dimension()
-	maxChecksum := numChunksMessage * (base - 1)
-	expectedChecksumChunks := int(math.Floor(math.Log(float64(maxChecksum))/math.Log(float64(base)))) + 1
-	
-	if numChunksChecksum != expectedChecksumChunks {
+	if numChunksChecksum != ComputeChecksumLength(messageHash.Dimension(), chunkSize) {
 		panic("incorrect number of checksum chunks")
 	}
 	
@@ -49,15 +45,33 @@ func NewWinternitzEncoding(messageHash encoding.MessageHash, chunkSize int, numC
 	}
 }
 
+// NewWinternitzEncodingAuto creates a new Winternitz encoding, computing
+// the number of checksum chunks from the message hash dimension
+func NewWinternitzEncodingAuto(messageHash encoding.MessageHash, chunkSize int) *WinternitzEncoding {
+	return NewWinternitzEncoding(messageHash, chunkSize, ComputeChecksumLength(messageHash.Dimension(), chunkSize))
+}
+
 // Encode implements the Winternitz encoding
 func (w *WinternitzEncoding) Encode(P th.Params, msg []byte, rho []byte, epoch uint32) (encoding.Codeword, error) {
 	// Apply message hash to get message chunks
-	messageChunks := w.messageHash.Hash(P, msg, rho, epoch)
+	return w.CodewordFromChunks(w.messageHash.Hash(P, msg, rho, epoch))
+}
+
+// CodewordFromChunks builds the codeword for message-hash output chunks:
+// the chunks followed by the checksum chunks. It returns an error if
+// messageChunks does not have n₀ chunks or a chunk is not below the base.
+func (w *WinternitzEncoding) CodewordFromChunks(messageChunks []uint8) (encoding.Codeword, error) {
+	if len(messageChunks) != w.numChunksMessage {
+		return nil, fmt.Errorf("expected %d message chunks, got %d", w.numChunksMessage, len(messageChunks))
+	}
 	
 	// Compute checksum
 	base := uint64(w.Base())
 	checksum := uint64(0)
-	for _, chunk := range messageChunks {
+	for i, chunk := range messageChunks {
+		if uint64(chunk) >= base {
+			return nil, fmt.Errorf("chunk %d is %d, not below base %d", i, chunk, base)
+		}
 		checksum += base - 1 - uint64(chunk)
 	}
 	
@@ -65,6 +79,7 @@ func (w *WinternitzEncoding) Encode(P th.Params, msg []byte, rho []byte, epoch u
 	checksumBytes := make([]byte, 8)
 	binary.LittleEndian.PutUint64(checksumBytes, checksum)
 	
+	// The constructor validated the chunk size
 	checksumChunks, err := bitutil.BytesToChunks(checksumBytes, w.chunkSize)
 	if err != nil {
 		return nil, err
@@ -79,12 +94,22 @@ func (w *WinternitzEncoding) Encode(P th.Params, msg []byte, rho []byte, epoch u
 }
 
 // RandRandomness generates randomness for encoding
-func (w *WinternitzEncoding) RandRandomness(rng io.Reader) []byte {
-	// Generate random bytes based on the message hash's randomness length
-	randLen := w.messageHash.RandLen()
-	rand := make([]byte, randLen)
-	rng.Read(rand)
-	return rand
+func (w *WinternitzEncoding) RandRandomness(rng io.Reader) ([]byte, error) {
+	rand := make([]byte, w.RandLen())
+	if err := w.RandRandomnessInto(rand, rng); err != nil {
+		return nil, err
+	}
+	return rand, nil
+}
+
+// RandRandomnessInto fills dst, which must be RandLen() bytes, with randomness
+func (w *WinternitzEncoding) RandRandomnessInto(dst []byte, rng io.Reader) error {
+	return encoding.FillRandomness(dst, w.RandLen(), rng)
+}
+
+// RandLen returns the randomness length of the underlying message hash
+func (w *WinternitzEncoding) RandLen() int {
+	return w.messageHash.RandLen()
 }
 
 // Dimension returns v = n₀ + n₁
@@ -92,6 +117,29 @@ func (w *WinternitzEncoding) Dimension() int {
 	return w.numChunksMessage + w.numChunksChecksum
 }
 
+// NumMessageChunks returns n₀, the number of message-hash chunks, which
+// come first in a codeword
+func (w *WinternitzEncoding) NumMessageChunks() int {
+	return w.numChunksMessage
+}
+
+// NumChecksumChunks returns n₁, the number of checksum chunks, which
+// follow the message chunks in a codeword
+func (w *WinternitzEncoding) NumChecksumChunks() int {
+	return w.numChunksChecksum
+}
+
+// Validate checks the configuration of the underlying message hash
+func (w *WinternitzEncoding) Validate() error {
+	return encoding.ValidateMessageHash(w.messageHash)
+}
+
+// MessageLen returns the message length the underlying message hash
+// accepts, or 0 if it accepts any length
+func (w *WinternitzEncoding) MessageLen() int {
+	return encoding.MessageLen(w.messageHash)
+}
+
 // Base returns 2^w
 func (w *WinternitzEncoding) Base() int {
 	return 1 << w.chunkSize
@@ -0,0 +1,81 @@
+package winternitz
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+	
+	"github.com/aerius-labs/hash-sig-go/th/message_hash"
+)
+
+// Test that the auto constructor picks the same checksum length as the
+// explicitly parameterised reference configurations, as reported by the
+// chunk-count accessors
+func TestNewWinternitzEncodingAuto(t *testing.T) {
+	testCases := []struct {
+		chunkSize         int
+		dimension         int
+		numChunksChecksum int
+	}{
+		{1, 155, 8}, // Poseidon w=1
+		{2, 78, 4},  // Poseidon w=2
+		{4, 39, 3},  // Poseidon w=4
+		{4, 48, 3},  // SHA3 test configuration
+		{8, 32, 2},
+	}
+	
+	for _, tc := range testCases {
+		mh := message_hash.NewSHA3MessageHash(24, 24, tc.dimension, tc.chunkSize)
+		enc := NewWinternitzEncodingAuto(mh, tc.chunkSize)
+		
+		if got := enc.NumChecksumChunks(); got != tc.numChunksChecksum {
+			t.Errorf("w=%d, n0=%d: got %d checksum chunks, want %d", tc.chunkSize, tc.dimension, got, tc.numChunksChecksum)
+		}
+		if got := enc.NumMessageChunks(); got != tc.dimension {
+			t.Errorf("w=%d, n0=%d: got %d message chunks", tc.chunkSize, tc.dimension, got)
+		}
+		if enc.Dimension() != enc.NumMessageChunks()+enc.NumChecksumChunks() {
+			t.Errorf("w=%d, n0=%d: dimension %d is not n0 + n1", tc.chunkSize, tc.dimension, enc.Dimension())
+		}
+	}
+}
+
+// Test that CodewordFromChunks on the message-hash output matches Encode
+func TestCodewordFromChunks(t *testing.T) {
+	mh := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	enc := NewWinternitzEncodingAuto(mh, 4)
+	
+	param := make([]byte, 24)
+	rand.Read(param)
+	msg := make([]byte, 32)
+	rand.Read(msg)
+	rho, err := enc.RandRandomness(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate randomness: %v", err)
+	}
+	
+	want, err := enc.Encode(param, msg, rho, 5)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	got, err := enc.CodewordFromChunks(mh.Hash(param, msg, rho, 5))
+	if err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("CodewordFromChunks = %v, Encode = %v", got, want)
+	}
+	
+	// All-zero chunks carry the maximal checksum 48*15 = 720 = 0x2d0
+	zero, err := enc.CodewordFromChunks(make([]uint8, 48))
+	if err != nil {
+		t.Fatalf("CodewordFromChunks failed on all-zero chunks: %v", err)
+	}
+	if !bytes.Equal(zero[48:], []byte{0x0, 0xd, 0x2}) {
+		t.Fatalf("Unexpected checksum chunks %v", zero[48:])
+	}
+	
+	if _, err := enc.CodewordFromChunks(make([]uint8, 47)); err == nil {
+		t.Fatal("Expected an error for the wrong number of chunks")
+	}
+	if _, err := enc.CodewordFromChunks(append(make([]uint8, 47), 16)); err == nil {
+		t.Fatal("Expected an error for a chunk out of range")
+	}
+}
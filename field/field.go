@@ -10,6 +10,11 @@ import (
 // BabyBear prime: 2^31 - 2^27 + 1 = 2013265921
 const P uint64 = 2013265921
 
+// PBigInt returns P as a fresh big.Int, which the caller may modify
+func PBigInt() *big.Int {
+	return new(big.Int).SetUint64(P)
+}
+
 // Element represents a field element in BabyBear
 type Element = babybear.Element
 
@@ -30,14 +35,14 @@ func One() Element {
 	return babybear.NewElement(1)
 }
 
-// FromBytes creates element from bytes (little-endian)
+// FromBytes creates element from bytes (big-endian, reduced modulo P)
 func FromBytes(b []byte) Element {
 	var e Element
 	e.SetBytes(b)
 	return e
 }
 
-// ToBytes converts element to bytes (little-endian)
+// ToBytes converts element to its canonical 4-byte big-endian encoding
 func ToBytes(e Element) []byte {
 	b := e.Bytes()
 	return b[:]
@@ -46,4 +51,83 @@ func ToBytes(e Element) []byte {
 // ToBigInt converts to big.Int
 func ToBigInt(e Element) *big.Int {
 	return e.BigInt(big.NewInt(0))
-}
\ No newline at end of file
+}
+
+// BytesToElements reads data as consecutive 4-byte big-endian words, each
+// reduced modulo P, into n elements. A trailing partial word is padded with
+// zero bytes on the right, and elements beyond the data are zero. This is
+// the encoding of Poseidon tweakable-hash parameters and domain elements.
+func BytesToElements(data []byte, n int) []Element {
+	result := make([]Element, n)
+	for i := 0; i < n; i++ {
+		offset := i * 4
+		if offset+4 <= len(data) {
+			result[i].SetBytes(data[offset : offset+4])
+		} else if offset < len(data) {
+			// Partial element
+			partial := make([]byte, 4)
+			copy(partial, data[offset:])
+			result[i].SetBytes(partial)
+		}
+	}
+	return result
+}
+
+// ElementsToBytes encodes each element as its canonical 4-byte big-endian
+// word. It inverts BytesToElements on canonical input.
+func ElementsToBytes(elems []Element) []byte {
+	result := make([]byte, len(elems)*4)
+	PutElements(result, elems)
+	return result
+}
+
+// PutElements writes the encoding ElementsToBytes returns into dst, which
+// must hold at least 4*len(elems) bytes
+func PutElements(dst []byte, elems []Element) {
+	for i := range elems {
+		b := elems[i].Bytes()
+		copy(dst[4*i:4*i+4], b[:])
+	}
+}
+
+// BytesToElementsBaseP interprets data as a little-endian integer and
+// returns its n least significant base-P digits, least significant first.
+// Higher digits are dropped. This is the encoding of Poseidon message-hash
+// inputs.
+func BytesToElementsBaseP(data []byte, n int) []Element {
+	acc := new(big.Int).SetBytes(reverseBytes(data))
+	p := new(big.Int).SetUint64(P)
+	digit := new(big.Int)
+	
+	result := make([]Element, n)
+	for i := 0; i < n; i++ {
+		acc.DivMod(acc, p, digit)
+		result[i].SetBigInt(digit)
+	}
+	return result
+}
+
+// ElementsToBytesBaseP inverts BytesToElementsBaseP: it evaluates elems as
+// base-P digits, least significant first, and returns the little-endian
+// encoding of the integer in exactly byteLen bytes. It panics if the
+// integer does not fit in byteLen bytes.
+func ElementsToBytesBaseP(elems []Element, byteLen int) []byte {
+	acc := new(big.Int)
+	p := new(big.Int).SetUint64(P)
+	for i := len(elems) - 1; i >= 0; i-- {
+		acc.Mul(acc, p)
+		acc.Add(acc, elems[i].BigInt(new(big.Int)))
+	}
+	
+	// FillBytes writes big-endian with zero padding; reverse for little-endian
+	return reverseBytes(acc.FillBytes(make([]byte, byteLen)))
+}
+
+// reverseBytes returns a reversed copy of b
+func reverseBytes(b []byte) []byte {
+	result := make([]byte, len(b))
+	for i := range b {
+		result[i] = b[len(b)-1-i]
+	}
+	return result
+}
@@ -0,0 +1,70 @@
+package field
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Test the word encoding the Poseidon tweakable hash relies on: big-endian
+// 4-byte words reduced modulo P, a right-padded partial word, zero fill
+func TestBytesToElements(t *testing.T) {
+	data := []byte{
+		0x00, 0x00, 0x00, 0x01,
+		0x78, 0x00, 0x00, 0x01, // P
+		0xff, 0xff, 0xff, 0xff,
+		0xab, // partial word 0xab000000
+	}
+	want := []uint64{1, 0, 268435453, 855638015, 0}
+	
+	elems := BytesToElements(data, len(want))
+	for i := range want {
+		if got := elems[i].Uint64(); got != want[i] {
+			t.Errorf("Element %d: got %d, want %d", i, got, want[i])
+		}
+	}
+	
+	// Canonical words round-trip
+	canonical := []byte{0x00, 0x00, 0x00, 0x01, 0x12, 0x34, 0x56, 0x78}
+	if got := ElementsToBytes(BytesToElements(canonical, 2)); !bytes.Equal(got, canonical) {
+		t.Fatalf("Round trip gave %x, want %x", got, canonical)
+	}
+}
+
+// Test the base-P encoding the Poseidon message hash relies on: digits of
+// the little-endian integer, least significant first, exact-length inverse
+func TestBytesToElementsBaseP(t *testing.T) {
+	data := append(bytes.Repeat([]byte{0xff}, 9), 0x01)
+	want := []uint64{196854997, 340013584, 2330, 0}
+	
+	elems := BytesToElementsBaseP(data, len(want))
+	for i := range want {
+		if got := elems[i].Uint64(); got != want[i] {
+			t.Errorf("Digit %d: got %d, want %d", i, got, want[i])
+		}
+	}
+	
+	if got := ElementsToBytesBaseP(elems, len(data)); !bytes.Equal(got, data) {
+		t.Fatalf("Round trip gave %x, want %x", got, data)
+	}
+	// Leading zero bytes of the integer are kept as padding
+	if got := ElementsToBytesBaseP(BytesToElementsBaseP(make([]byte, 32), 9), 32); !bytes.Equal(got, make([]byte, 32)) {
+		t.Fatalf("All-zero round trip gave %x", got)
+	}
+}
+
+// Test that PBigInt is P, that it is the modulus elements reduce by, and
+// that modifying one copy does not affect the next
+func TestPBigInt(t *testing.T) {
+	p := PBigInt()
+	if !p.IsUint64() || p.Uint64() != P {
+		t.Fatalf("PBigInt() = %v, expected %d", p, P)
+	}
+	if e := NewElement(P); !e.IsZero() {
+		t.Fatal("P does not reduce to zero")
+	}
+	
+	p.SetInt64(7)
+	if PBigInt().Uint64() != P {
+		t.Fatal("Modifying a PBigInt result changed the next one")
+	}
+}
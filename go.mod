@@ -5,9 +5,11 @@ go 1.23.2
 require (
 	github.com/consensys/gnark-crypto v0.19.0
 	golang.org/x/crypto v0.35.0
+	lukechampine.com/blake3 v1.4.1
 )
 
 require (
 	github.com/bits-and-blooms/bitset v1.20.0 // indirect
+	github.com/klauspost/cpuid/v2 v2.0.9 // indirect
 	golang.org/x/sys v0.30.0 // indirect
 )
@@ -1,13 +1,34 @@
 package hypercube
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"math/big"
 	"sync"
+	"sync/atomic"
 )
 
-// MaxDimension is the maximum dimension precomputed for layer sizes
+// MaxDimension is the dimension up to which layer sizes are precomputed,
+// for bases whose tables up to it fit the layer-info budget. Larger
+// dimensions are supported and computed on first use.
 const MaxDimension = 100
 
+// DefaultLayerInfoBudget is the default layer-info budget: the number of
+// layers, v*(w-1)+1 for base w and dimension v, that the largest table
+// GetLayerInfoChecked builds may have. Each layer holds two big integers.
+const DefaultLayerInfoBudget = 1 << 14
+
+// ErrLayerInfoBudget indicates a layer table larger than the budget
+var ErrLayerInfoBudget = errors.New("layer table exceeds precomputation budget")
+
+// layerInfoBudget is the budget set by SetLayerInfoBudget
+var layerInfoBudget atomic.Int64
+
+func init() {
+	layerInfoBudget.Store(DefaultLayerInfoBudget)
+}
+
 // LayerInfo holds the sizes of each layer and their cumulative sums
 type LayerInfo struct {
 	Sizes      []*big.Int // Number of vertices in each layer d
@@ -25,41 +46,146 @@ func (l *LayerInfo) SizesSumInRange(start, end int) *big.Int {
 // AllLayerInfoForBase is a vector of LayerInfo, indexed by dimension v
 type AllLayerInfoForBase []*LayerInfo
 
+// layerCacheEntry is the cached layer data of one base with its last use
+type layerCacheEntry struct {
+	info    AllLayerInfoForBase
+	lastUse atomic.Uint64
+}
+
 // Global cache for layer info (sizes and prefix sums) for each base w
 var (
-	allLayerInfoCache = make(map[int]AllLayerInfoForBase)
+	allLayerInfoCache = make(map[int]*layerCacheEntry)
 	cacheMutex        sync.RWMutex
+	
+	// layerCacheLimit is the maximum number of cached bases, 0 for no limit
+	layerCacheLimit int
+	// layerCacheClock orders cache uses for least-recently-used eviction
+	layerCacheClock atomic.Uint64
 )
 
-// getAllLayerData returns cached layer data for given base
-func getAllLayerData(w int) AllLayerInfoForBase {
+// getAllLayerData returns cached layer data for given base, covering at least
+// dimension v. Dimensions beyond MaxDimension are computed on first use.
+func getAllLayerData(w, v int) AllLayerInfoForBase {
 	cacheMutex.RLock()
-	info, exists := allLayerInfoCache[w]
+	entry, exists := allLayerInfoCache[w]
+	if exists && len(entry.info) > v {
+		entry.lastUse.Store(layerCacheClock.Add(1))
+		info := entry.info
+		cacheMutex.RUnlock()
+		return info
+	}
 	cacheMutex.RUnlock()
 
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+	// Double-check after acquiring write lock
+	entry, exists = allLayerInfoCache[w]
 	if !exists {
-		cacheMutex.Lock()
-		// Double-check after acquiring write lock
-		if info, exists = allLayerInfoCache[w]; !exists {
-			info = prepareLayerInfo(w)
-			allLayerInfoCache[w] = info
+		evictLayerCache(layerCacheLimit - 1)
+		entry = &layerCacheEntry{}
+		allLayerInfoCache[w] = entry
+	}
+	if len(entry.info) <= v {
+		vMax := v
+		if withinLayerInfoBudget(w, MaxDimension) {
+			vMax = max(v, MaxDimension)
+		}
+		entry.info = prepareLayerInfo(w, entry.info, vMax)
+	}
+	entry.lastUse.Store(layerCacheClock.Add(1))
+	return entry.info
+}
+
+// evictLayerCache drops least recently used bases until at most n remain.
+// n < 0 means no limit. Callers must hold the write lock.
+func evictLayerCache(n int) {
+	if n < 0 {
+		return
+	}
+	for len(allLayerInfoCache) > n {
+		oldest, oldestUse := 0, uint64(math.MaxUint64)
+		for w, entry := range allLayerInfoCache {
+			if use := entry.lastUse.Load(); use < oldestUse {
+				oldest, oldestUse = w, use
+			}
 		}
-		cacheMutex.Unlock()
+		delete(allLayerInfoCache, oldest)
 	}
+}
 
-	return info
+// ClearLayerCache drops all cached layer tables, binomial coefficients and
+// target-sum counts.
+// They are recomputed on next use.
+func ClearLayerCache() {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+	clear(allLayerInfoCache)
+	clear(binomialCache)
+	clear(targetSumCache)
+}
+
+// SetLayerCacheLimit bounds the layer cache to the n most recently used
+// bases, evicting the others; n <= 0 removes the bound. Evicted tables are
+// recomputed on next use, and LayerInfo values already handed out stay valid.
+func SetLayerCacheLimit(n int) {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+	layerCacheLimit = max(n, 0)
+	if layerCacheLimit > 0 {
+		evictLayerCache(layerCacheLimit)
+	}
 }
 
 // GetLayerInfo returns cached layer info for given base and dimension
 func GetLayerInfo(w, v int) *LayerInfo {
-	allInfo := getAllLayerData(w)
+	allInfo := getAllLayerData(w, v)
 	return allInfo[v]
 }
 
+// GetLayerInfoChecked is GetLayerInfo, but returns an error wrapping
+// ErrLayerInfoBudget instead of building a table of more than the
+// layer-info budget's layers (see SetLayerInfoBudget). With the default
+// budget, e.g. w=256 and v=100 are refused: their 25501-layer table, and
+// those of all smaller dimensions it is built from, would take hundreds of
+// megabytes.
+func GetLayerInfoChecked(w, v int) (*LayerInfo, error) {
+	if w < 2 || v < 1 {
+		return nil, fmt.Errorf("invalid base %d or dimension %d", w, v)
+	}
+	if !withinLayerInfoBudget(w, v) {
+		return nil, fmt.Errorf("%w: %d layers for w=%d, v=%d, budget is %d",
+			ErrLayerInfoBudget, v*(w-1)+1, w, v, layerInfoBudget.Load())
+	}
+	return GetLayerInfo(w, v), nil
+}
+
+// SetLayerInfoBudget sets the number of layers GetLayerInfoChecked allows
+// in a table and returns the previous budget. Callers that need larger
+// tables raise it deliberately; n <= 0 restores DefaultLayerInfoBudget.
+// Bases whose tables up to MaxDimension exceed the budget are only
+// computed up to the dimensions asked for.
+func SetLayerInfoBudget(n int) int {
+	if n <= 0 {
+		n = DefaultLayerInfoBudget
+	}
+	return int(layerInfoBudget.Swap(int64(n)))
+}
+
+// withinLayerInfoBudget reports whether the table of base w and dimension
+// v has at most the budgeted number of layers
+func withinLayerInfoBudget(w, v int) bool {
+	return int64(v)*int64(w-1)+1 <= layerInfoBudget.Load()
+}
+
 // prepareLayerInfo computes layer sizes and prefix sums by Lemma 8 in eprint 2025/889
-func prepareLayerInfo(w int) AllLayerInfoForBase {
-	vMax := MaxDimension
+// up to dimension vMax, extending the already computed dimensions in prev.
+// The layers of prev are shared, not copied, so readers of the old slice are unaffected.
+func prepareLayerInfo(w int, prev AllLayerInfoForBase, vMax int) AllLayerInfoForBase {
 	allInfo := make(AllLayerInfoForBase, vMax+1)
+	copy(allInfo, prev)
+	if prev != nil {
+		return extendLayerInfo(w, allInfo, len(prev))
+	}
 
 	// Initialize with empty LayerInfo
 	for i := range allInfo {
@@ -82,8 +208,13 @@ func prepareLayerInfo(w int) AllLayerInfoForBase {
 		PrefixSums: dim1PrefixSums,
 	}
 
-	// Inductive step: compute for dimensions v = 2 to vMax
-	for v := 2; v <= vMax; v++ {
+	return extendLayerInfo(w, allInfo, 2)
+}
+
+// extendLayerInfo fills allInfo[vStart:] inductively from allInfo[vStart-1]
+func extendLayerInfo(w int, allInfo AllLayerInfoForBase, vStart int) AllLayerInfoForBase {
+	// Inductive step: compute for dimensions v = vStart to len(allInfo)-1
+	for v := vStart; v < len(allInfo); v++ {
 		maxD := (w - 1) * v
 
 		// Compute the sizes for the current dimension v
@@ -127,11 +258,28 @@ func prepareLayerInfo(w int) AllLayerInfoForBase {
 // MapToVertex maps an integer x in [0, layer_size(v, d)) to a vertex in layer d
 // of the hypercube [0, w-1]^v
 func MapToVertex(w, v, d int, x *big.Int) []byte {
+	// Layer 0 holds only the all-(w-1) vertex and layer v*(w-1) only the
+	// all-zero one, so they need no unranking
+	if x.Sign() == 0 && (d == 0 || d == v*(w-1)) {
+		out := make([]byte, v)
+		if d == 0 {
+			for i := range out {
+				out[i] = byte(w - 1)
+			}
+		}
+		return out
+	}
+	return mapToVertexUnrank(w, v, d, x)
+}
+
+// mapToVertexUnrank implements MapToVertex by combinatorial unranking,
+// coordinate by coordinate
+func mapToVertexUnrank(w, v, d int, x *big.Int) []byte {
 	xCurr := new(big.Int).Set(x)
 	out := make([]byte, 0, v)
 	dCurr := d
 
-	layerData := getAllLayerData(w)
+	layerData := getAllLayerData(w, v)
 
 	// Assert x < layer_size(v, d)
 	if xCurr.Cmp(layerData[v].Sizes[d]) >= 0 {
@@ -174,14 +322,14 @@ func MapToVertex(w, v, d int, x *big.Int) []byte {
 
 // HypercubePartSize returns the total size of layers 0 to d (inclusive) in hypercube [0, w-1]^v
 func HypercubePartSize(w, v, d int) *big.Int {
-	layerData := getAllLayerData(w)
+	layerData := getAllLayerData(w, v)
 	return new(big.Int).Set(layerData[v].PrefixSums[d])
 }
 
 // HypercubeFindLayer finds maximal d such that the total size L_<d of layers 0 to d-1 (inclusive)
 // in hypercube [0, w-1]^v is not bigger than x. Returns d and x-L_<d
 func HypercubeFindLayer(w, v int, x *big.Int) (int, *big.Int) {
-	layerData := getAllLayerData(w)
+	layerData := getAllLayerData(w, v)
 	prefixSums := layerData[v].PrefixSums
 
 	// Assert x < total size (w^v)
@@ -216,7 +364,7 @@ func MapToInteger(w, v, d int, a []byte) *big.Int {
 	xCurr := big.NewInt(0)
 	dCurr := w - 1 - int(a[v-1])
 
-	layerData := getAllLayerData(w)
+	layerData := getAllLayerData(w, v)
 
 	for i := v - 2; i >= 0; i-- {
 		ji := w - 1 - int(a[i])
@@ -234,6 +382,81 @@ func MapToInteger(w, v, d int, a []byte) *big.Int {
 	return xCurr
 }
 
+// CountVerticesTargetSum returns the number of vertices of [0, w-1]^v whose
+// coordinates sum to target. This is the size of layer v*(w-1) - target.
+func CountVerticesTargetSum(w, v, target int) *big.Int {
+	maxSum := v * (w - 1)
+	if target < 0 || target > maxSum {
+		return big.NewInt(0)
+	}
+	
+	// Beyond MaxDimension, a single count is cheaper than extending the layer
+	// tables; memoize it since estimators ask for the same count repeatedly
+	if v > MaxDimension {
+		key := [3]int{w, v, target}
+		cacheMutex.RLock()
+		count, ok := targetSumCache[key]
+		cacheMutex.RUnlock()
+		if !ok {
+			count = countVerticesWithSum(w, v, target)
+			cacheMutex.Lock()
+			if len(targetSumCache) < binomialCacheSize {
+				targetSumCache[key] = count
+			}
+			cacheMutex.Unlock()
+		}
+		return new(big.Int).Set(count)
+	}
+	return new(big.Int).Set(getAllLayerData(w, v)[v].Sizes[maxSum-target])
+}
+
+// countVerticesWithSum counts vertices of [0, w-1]^v with coordinate sum s
+// by inclusion-exclusion over the coordinates that exceed w-1:
+// sum_k (-1)^k C(v, k) C(s - k*w + v - 1, v - 1)
+func countVerticesWithSum(w, v, s int) *big.Int {
+	count := big.NewInt(0)
+	for k := 0; k <= v && k*w <= s; k++ {
+		term := new(big.Int).Mul(binomial(v, k), binomial(s-k*w+v-1, v-1))
+		if k%2 == 0 {
+			count.Add(count, term)
+		} else {
+			count.Sub(count, term)
+		}
+	}
+	return count
+}
+
+// binomialCacheSize bounds the number of memoized binomial coefficients,
+// and separately of memoized target-sum counts
+const binomialCacheSize = 1 << 16
+
+// Memoized binomial coefficients and target-sum counts beyond MaxDimension,
+// guarded by cacheMutex like the layer tables
+var (
+	binomialCache  = make(map[[2]int]*big.Int)
+	targetSumCache = make(map[[3]int]*big.Int)
+)
+
+// binomial returns C(n, k). The result may be shared with the cache and
+// must not be modified.
+func binomial(n, k int) *big.Int {
+	key := [2]int{n, k}
+	cacheMutex.RLock()
+	c, ok := binomialCache[key]
+	cacheMutex.RUnlock()
+	if ok {
+		return c
+	}
+	
+	c = new(big.Int).Binomial(int64(n), int64(k))
+	cacheMutex.Lock()
+	if len(binomialCache) < binomialCacheSize {
+		binomialCache[key] = c
+	}
+	cacheMutex.Unlock()
+	return c
+}
+
 // Helper functions
 func min(a, b int) int {
 	if a < b {
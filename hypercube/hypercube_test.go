@@ -1,6 +1,7 @@
 package hypercube
 
 import (
+	"errors"
 	"math/big"
 	"testing"
 )
@@ -154,4 +155,205 @@ func TestBigMap(t *testing.T) {
 	if expectedD != d {
 		t.Errorf("Big map vertex in wrong layer: %d, want %d", expectedD, d)
 	}
-}
\ No newline at end of file
+}
+
+// Test a round trip at v=256, beyond MaxDimension. Both mappings are
+// iterative, so stack usage does not grow with v.
+func TestMapVertexRoundtripV256(t *testing.T) {
+	w := 4
+	v := 256
+	
+	for _, d := range []int{0, 1, 384, 767, 768} {
+		size := GetLayerInfo(w, v).Sizes[d]
+		last := new(big.Int).Sub(size, big.NewInt(1))
+		mid := new(big.Int).Rsh(size, 1)
+		
+		for _, x := range []*big.Int{big.NewInt(0), mid, last} {
+			a := MapToVertex(w, v, d, x)
+			if len(a) != v {
+				t.Fatalf("Vertex has dimension %d, want %d", len(a), v)
+			}
+			
+			sum := 0
+			for _, ai := range a {
+				sum += int(ai)
+			}
+			if (w-1)*v-sum != d {
+				t.Fatalf("Vertex in layer %d, want %d", (w-1)*v-sum, d)
+			}
+			
+			if y := MapToInteger(w, v, d, a); x.Cmp(y) != 0 {
+				t.Fatalf("Roundtrip failed for d=%d: got %s, want %s", d, y, x)
+			}
+		}
+	}
+}
+
+// Test CountVerticesTargetSum against brute-force enumeration and the
+// inclusion-exclusion formula
+func TestCountVerticesTargetSum(t *testing.T) {
+	for _, tc := range []struct{ w, v int }{{2, 3}, {3, 4}, {4, 3}, {5, 2}} {
+		// Enumerate all w^v vertices and histogram their sums
+		counts := make([]int64, tc.v*(tc.w-1)+1)
+		total := 1
+		for i := 0; i < tc.v; i++ {
+			total *= tc.w
+		}
+		for x := 0; x < total; x++ {
+			sum := 0
+			for y, i := x, 0; i < tc.v; i, y = i+1, y/tc.w {
+				sum += y % tc.w
+			}
+			counts[sum]++
+		}
+		
+		for s, expected := range counts {
+			if got := CountVerticesTargetSum(tc.w, tc.v, s); got.Int64() != expected {
+				t.Errorf("CountVerticesTargetSum(%d, %d, %d) = %s, want %d", tc.w, tc.v, s, got, expected)
+			}
+			if got := countVerticesWithSum(tc.w, tc.v, s); got.Int64() != expected {
+				t.Errorf("countVerticesWithSum(%d, %d, %d) = %s, want %d", tc.w, tc.v, s, got, expected)
+			}
+		}
+		
+		if got := CountVerticesTargetSum(tc.w, tc.v, len(counts)); got.Sign() != 0 {
+			t.Errorf("Target above the maximum sum should have no vertices, got %s", got)
+		}
+	}
+	
+	// Both methods agree at a larger dimension as well
+	for _, s := range []int{0, 100, 360, 700} {
+		if CountVerticesTargetSum(16, 48, s).Cmp(countVerticesWithSum(16, 48, s)) != 0 {
+			t.Errorf("Layer table and inclusion-exclusion disagree for sum %d", s)
+		}
+	}
+	
+	// Beyond MaxDimension the memoized count matches the extended layer table,
+	// and the returned value is a copy the caller may modify
+	v := MaxDimension + 20
+	for _, s := range []int{0, 1, v, 2 * v} {
+		want := GetLayerInfo(3, v).Sizes[2*v-s]
+		first := CountVerticesTargetSum(3, v, s)
+		first.SetInt64(-1)
+		if got := CountVerticesTargetSum(3, v, s); got.Cmp(want) != 0 {
+			t.Errorf("Memoized count for v=%d, sum %d: got %s, want %s", v, s, got, want)
+		}
+	}
+}
+
+// Test that memoized binomials match math/big on both the cold and warm path
+func TestBinomialCache(t *testing.T) {
+	for pass := 0; pass < 2; pass++ {
+		for n := 0; n <= 60; n++ {
+			for k := 0; k <= n; k++ {
+				want := new(big.Int).Binomial(int64(n), int64(k))
+				if got := binomial(n, k); got.Cmp(want) != 0 {
+					t.Fatalf("Pass %d: C(%d, %d) = %s, want %s", pass, n, k, got, want)
+				}
+			}
+		}
+	}
+}
+
+// Test that eviction and clearing bound the cache without changing results
+func TestLayerCacheLimit(t *testing.T) {
+	t.Cleanup(func() { SetLayerCacheLimit(0) })
+	
+	cachedBases := func() int {
+		cacheMutex.RLock()
+		defer cacheMutex.RUnlock()
+		return len(allLayerInfoCache)
+	}
+	want := map[int]string{}
+	for _, w := range []int{3, 5, 7} {
+		want[w] = HypercubePartSize(w, 10, 8).String()
+	}
+	
+	SetLayerCacheLimit(2)
+	if n := cachedBases(); n > 2 {
+		t.Fatalf("%d bases cached with limit 2", n)
+	}
+	
+	// Concurrent use across more bases than the limit
+	done := make(chan struct{})
+	for g := 0; g < 8; g++ {
+		go func(g int) {
+			defer func() { done <- struct{}{} }()
+			for i := 0; i < 20; i++ {
+				w := []int{3, 5, 7}[(g+i)%3]
+				if got := HypercubePartSize(w, 10, 8).String(); got != want[w] {
+					t.Errorf("w=%d: got %s, want %s", w, got, want[w])
+				}
+			}
+		}(g)
+	}
+	for g := 0; g < 8; g++ {
+		<-done
+	}
+	if n := cachedBases(); n > 2 {
+		t.Fatalf("%d bases cached with limit 2", n)
+	}
+	
+	ClearLayerCache()
+	if n := cachedBases(); n != 0 {
+		t.Fatalf("%d bases cached after clearing", n)
+	}
+	if got := HypercubePartSize(5, 10, 8).String(); got != want[5] {
+		t.Fatalf("After clearing: got %s, want %s", got, want[5])
+	}
+}
+
+// Test that GetLayerInfoChecked enforces the layer-info budget
+func TestGetLayerInfoChecked(t *testing.T) {
+	t.Cleanup(func() { SetLayerInfoBudget(0) })
+	
+	info, err := GetLayerInfoChecked(4, 10)
+	if err != nil {
+		t.Fatalf("Small table refused: %v", err)
+	}
+	if info != GetLayerInfo(4, 10) {
+		t.Fatal("GetLayerInfoChecked differs from GetLayerInfo")
+	}
+	
+	if _, err := GetLayerInfoChecked(256, 100); !errors.Is(err, ErrLayerInfoBudget) {
+		t.Fatalf("Expected ErrLayerInfoBudget for w=256, v=100, got %v", err)
+	}
+	if _, err := GetLayerInfoChecked(1, 10); err == nil {
+		t.Fatal("Expected an error for base 1")
+	}
+	
+	// The budget is adjustable and bounds tables inclusively
+	if prev := SetLayerInfoBudget(100); prev != DefaultLayerInfoBudget {
+		t.Fatalf("Previous budget %d, want %d", prev, DefaultLayerInfoBudget)
+	}
+	if _, err := GetLayerInfoChecked(4, 40); !errors.Is(err, ErrLayerInfoBudget) {
+		t.Fatalf("Expected ErrLayerInfoBudget under a budget of 100, got %v", err)
+	}
+	if _, err := GetLayerInfoChecked(4, 33); err != nil {
+		t.Fatalf("Table of exactly 100 layers refused: %v", err)
+	}
+}
+
+// Test that the extreme-layer fast paths of MapToVertex agree with unranking
+func TestMapToVertexExtremeLayers(t *testing.T) {
+	for _, tc := range []struct{ w, v int }{{2, 1}, {4, 8}, {16, 64}, {256, 4}} {
+		for _, d := range []int{0, tc.v * (tc.w - 1)} {
+			got := MapToVertex(tc.w, tc.v, d, big.NewInt(0))
+			want := mapToVertexUnrank(tc.w, tc.v, d, big.NewInt(0))
+			if string(got) != string(want) {
+				t.Fatalf("w=%d, v=%d, d=%d: fast path %v, unranking %v", tc.w, tc.v, d, got, want)
+			}
+			if MapToInteger(tc.w, tc.v, d, got).Sign() != 0 {
+				t.Fatalf("w=%d, v=%d, d=%d: vertex does not map back to 0", tc.w, tc.v, d)
+			}
+		}
+	}
+	
+	// Only x = 0 exists in the extreme layers
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected a panic for x = 1 in layer 0")
+		}
+	}()
+	MapToVertex(4, 8, 0, big.NewInt(1))
+}
@@ -1,6 +1,9 @@
 package bitutil
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // BytesToChunks splits bytes into w-bit chunks
 // Similar to bytes_to_chunks in the Rust implementation
@@ -43,6 +46,33 @@ func BytesToChunks(bytes []byte, chunkSize int) ([]uint8, error) {
 	return out, nil
 }
 
+// ChunksToBytes packs w-bit chunks back into bytes
+// It is the inverse of BytesToChunks: chunks are placed starting from the
+// least significant bits of each byte
+func ChunksToBytes(chunks []uint8, chunkSize int) ([]byte, error) {
+	// Only chunk sizes 1, 2, 4, or 8 are valid
+	if chunkSize != 1 && chunkSize != 2 && chunkSize != 4 && chunkSize != 8 {
+		return nil, errors.New("chunk size must be 1, 2, 4, or 8")
+	}
+	
+	chunksPerByte := 8 / chunkSize
+	if len(chunks)%chunksPerByte != 0 {
+		return nil, fmt.Errorf("number of chunks %d is not a multiple of %d", len(chunks), chunksPerByte)
+	}
+	
+	maxChunk := uint8((1 << chunkSize) - 1)
+	out := make([]byte, len(chunks)/chunksPerByte)
+	for i, chunk := range chunks {
+		if chunk > maxChunk {
+			return nil, fmt.Errorf("chunk %d has value %d, which does not fit in %d bits", i, chunk, chunkSize)
+		}
+		shift := (i % chunksPerByte) * chunkSize
+		out[i/chunksPerByte] |= chunk << shift
+	}
+	
+	return out, nil
+}
+
 // TruncateBits truncates data to exactly numBits bits
 // Returns a new slice with the truncated data
 func TruncateBits(data []byte, numBits int) []byte {
@@ -68,9 +98,56 @@ func TruncateBits(data []byte, numBits int) []byte {
 	return result
 }
 
-// ExtractWBitChunks extracts w-bit chunks from data starting at bit offset
+// ExtractWBitChunks extracts w-bit chunks from data starting at bit 0
 // Returns exactly numChunks chunks
 func ExtractWBitChunks(data []byte, w int, numChunks int) ([]uint32, error) {
+	return ExtractWBitChunksAt(data, w, numChunks, 0)
+}
+
+// ExtractWBitChunksAt extracts w-bit chunks from data starting at bit
+// bitOffset, counting bits least-significant-first within each byte as
+// ExtractWBitChunks does. The offset need not be byte aligned, and chunks
+// may straddle byte boundaries.
+// Returns exactly numChunks chunks
+func ExtractWBitChunksAt(data []byte, w int, numChunks int, bitOffset int) ([]uint32, error) {
+	if w <= 0 || w > 32 {
+		return nil, errors.New("w must be between 1 and 32")
+	}
+	if numChunks < 0 {
+		return nil, fmt.Errorf("negative number of chunks %d", numChunks)
+	}
+	if bitOffset < 0 {
+		return nil, fmt.Errorf("negative bit offset %d", bitOffset)
+	}
+	
+	totalBits := uint64(len(data)) * 8
+	requiredBits := uint64(bitOffset) + uint64(w)*uint64(numChunks)
+	if totalBits < requiredBits {
+		return nil, errors.New("insufficient data for requested chunks")
+	}
+	
+	chunks := make([]uint32, numChunks)
+	bitPos := bitOffset
+	
+	for i := 0; i < numChunks; i++ {
+		chunk := uint32(0)
+		for j := 0; j < w; j++ {
+			bit := (data[bitPos/8] >> (bitPos % 8)) & 1
+			chunk |= uint32(bit) << j
+			bitPos++
+		}
+		chunks[i] = chunk
+	}
+	
+	return chunks, nil
+}
+
+// ExtractWBitChunksBE extracts w-bit chunks from data, walking bits
+// most-significant-first within each byte, the opposite of
+// ExtractWBitChunks. The first bit read becomes the most significant bit of
+// its chunk.
+// Returns exactly numChunks chunks
+func ExtractWBitChunksBE(data []byte, w int, numChunks int) ([]uint32, error) {
 	if w <= 0 || w > 32 {
 		return nil, errors.New("w must be between 1 and 32")
 	}
@@ -87,16 +164,12 @@ func ExtractWBitChunks(data []byte, w int, numChunks int) ([]uint32, error) {
 	for i := 0; i < numChunks; i++ {
 		chunk := uint32(0)
 		for j := 0; j < w; j++ {
-			byteIdx := bitPos / 8
-			bitIdx := bitPos % 8
-			if byteIdx < len(data) {
-				bit := (data[byteIdx] >> bitIdx) & 1
-				chunk |= uint32(bit) << j
-			}
+			bit := (data[bitPos/8] >> (7 - bitPos%8)) & 1
+			chunk = chunk<<1 | uint32(bit)
 			bitPos++
 		}
 		chunks[i] = chunk
 	}
 	
 	return chunks, nil
-}
\ No newline at end of file
+}
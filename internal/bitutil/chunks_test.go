@@ -3,6 +3,7 @@ package bitutil
 import (
 	"bytes"
 	"crypto/rand"
+	"math/big"
 	"reflect"
 	"testing"
 )
@@ -98,16 +99,9 @@ func TestBytesToChunksReversible(t *testing.T) {
 		}
 		
 		// Reconstruct bytes from chunks
-		reconstructed := make([]byte, len(original))
-		chunksPerByte := 8 / actualSize
-		
-		for i := 0; i < len(original); i++ {
-			var b byte
-			for j := 0; j < chunksPerByte; j++ {
-				chunkIdx := i*chunksPerByte + j
-				b |= chunks[chunkIdx] << (j * actualSize)
-			}
-			reconstructed[i] = b
+		reconstructed, err := ChunksToBytes(chunks, actualSize)
+		if err != nil {
+			t.Fatalf("ChunksToBytes failed: %v", err)
 		}
 		
 		if !bytes.Equal(original, reconstructed) {
@@ -116,6 +110,40 @@ func TestBytesToChunksReversible(t *testing.T) {
 	}
 }
 
+// Test ChunksToBytes on known vectors and invalid input
+func TestChunksToBytes(t *testing.T) {
+	// Inverse of the TestBytesToChunksSpecific vector
+	chunks := []uint8{0b00, 0b11, 0b10, 0b01, 0b10, 0b01, 0b10, 0b10}
+	expected := []byte{0b01101100, 0b10100110}
+	
+	packed, err := ChunksToBytes(chunks, 2)
+	if err != nil {
+		t.Fatalf("ChunksToBytes failed: %v", err)
+	}
+	if !bytes.Equal(packed, expected) {
+		t.Fatalf("ChunksToBytes mismatch\nGot:      %v\nExpected: %v", packed, expected)
+	}
+	
+	errorCases := []struct {
+		name      string
+		chunks    []uint8
+		chunkSize int
+	}{
+		{"InvalidChunkSize", []uint8{0, 0, 0, 0, 0, 0, 0, 0}, 3},
+		{"CountNotMultiple", []uint8{1, 2, 3}, 2},
+		{"ChunkTooLarge", []uint8{0x10, 0x0}, 4},
+		{"BitTooLarge", []uint8{0, 1, 0, 2, 0, 0, 0, 0}, 1},
+	}
+	
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ChunksToBytes(tc.chunks, tc.chunkSize); err == nil {
+				t.Fatal("Expected an error")
+			}
+		})
+	}
+}
+
 // Test TruncateBits function
 func TestTruncateBits(t *testing.T) {
 	testCases := []struct {
@@ -207,6 +235,118 @@ func TestExtractWBitChunks(t *testing.T) {
 	}
 }
 
+// Test ExtractWBitChunksAt at offsets that are not byte aligned
+func TestExtractWBitChunksAt(t *testing.T) {
+	// Test data: 0xFF, 0x00, 0xAA = 11111111, 00000000, 10101010
+	data := []byte{0xFF, 0x00, 0xAA}
+	
+	// Bits 3..22: the top of 0xFF, all of 0x00, then 0xAA's bits 0..6
+	chunks, err := ExtractWBitChunksAt(data, 5, 4, 3)
+	if err != nil {
+		t.Fatalf("ExtractWBitChunksAt failed: %v", err)
+	}
+	expected := []uint32{0x1F, 0x00, 0x10, 0x0A}
+	if !reflect.DeepEqual(chunks, expected) {
+		t.Errorf("ExtractWBitChunksAt mismatch\nGot:      %v\nExpected: %v", chunks, expected)
+	}
+	
+	// One more chunk would need bit 27 of a 24-bit input
+	if _, err := ExtractWBitChunksAt(data, 5, 5, 3); err == nil {
+		t.Error("Accepted chunks past the end of the data")
+	}
+	if _, err := ExtractWBitChunksAt(data, 5, 1, -1); err == nil {
+		t.Error("Accepted a negative bit offset")
+	}
+	
+	// Offset 0 matches ExtractWBitChunks
+	plain, _ := ExtractWBitChunks(data, 4, 6)
+	atZero, _ := ExtractWBitChunksAt(data, 4, 6, 0)
+	if !reflect.DeepEqual(plain, atZero) {
+		t.Errorf("Offset 0 gives %v, ExtractWBitChunks gives %v", atZero, plain)
+	}
+	
+	// Every offset matches shifting the little-endian integer the bytes encode
+	random := []byte{0x3C, 0xA5, 0x96, 0x0F, 0xE1, 0x7B, 0x42, 0xD8}
+	value := new(big.Int)
+	for i := len(random) - 1; i >= 0; i-- {
+		value.Lsh(value, 8)
+		value.Or(value, big.NewInt(int64(random[i])))
+	}
+	for _, w := range []int{1, 3, 5, 7, 13} {
+		for offset := 0; offset < 16; offset++ {
+			numChunks := (len(random)*8 - offset) / w
+			chunks, err := ExtractWBitChunksAt(random, w, numChunks, offset)
+			if err != nil {
+				t.Fatalf("w=%d offset=%d: %v", w, offset, err)
+			}
+			for i, chunk := range chunks {
+				want := new(big.Int).Rsh(value, uint(offset+i*w))
+				want.And(want, big.NewInt(1<<w-1))
+				if uint64(chunk) != want.Uint64() {
+					t.Fatalf("w=%d offset=%d chunk %d: got %d, expected %d", w, offset, i, chunk, want.Uint64())
+				}
+			}
+		}
+	}
+}
+
+// Test ExtractWBitChunksBE against the LSB-first default on the same vector
+func TestExtractWBitChunksBE(t *testing.T) {
+	// Test data: 0xFF, 0x00, 0xAA = 11111111, 00000000, 10101010
+	data := []byte{0xFF, 0x00, 0xAA}
+	
+	testCases := []struct {
+		w         int
+		numChunks int
+		lsb       []uint32
+		msb       []uint32
+	}{
+		{
+			// Every byte here has two equal nibbles, so the orders agree
+			w:         4,
+			numChunks: 6,
+			lsb:       []uint32{0xF, 0xF, 0x0, 0x0, 0xA, 0xA},
+			msb:       []uint32{0xF, 0xF, 0x0, 0x0, 0xA, 0xA},
+		},
+		{
+			// Single bits of 0xAA come out in opposite order
+			w:         1,
+			numChunks: 24,
+			lsb:       []uint32{1, 1, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 1, 0, 1, 0, 1},
+			msb:       []uint32{1, 1, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 1, 0, 1, 0, 1, 0},
+		},
+		{
+			// Chunks straddling byte boundaries
+			w:         3,
+			numChunks: 8,
+			lsb:       []uint32{7, 7, 3, 0, 0, 4, 2, 5},
+			msb:       []uint32{7, 7, 6, 0, 0, 2, 5, 2},
+		},
+	}
+	
+	for _, tc := range testCases {
+		lsb, err := ExtractWBitChunks(data, tc.w, tc.numChunks)
+		if err != nil {
+			t.Fatalf("ExtractWBitChunks failed: %v", err)
+		}
+		if !reflect.DeepEqual(lsb, tc.lsb) {
+			t.Errorf("ExtractWBitChunks mismatch for w=%d\nGot:      %v\nExpected: %v", tc.w, lsb, tc.lsb)
+		}
+		
+		msb, err := ExtractWBitChunksBE(data, tc.w, tc.numChunks)
+		if err != nil {
+			t.Fatalf("ExtractWBitChunksBE failed: %v", err)
+		}
+		if !reflect.DeepEqual(msb, tc.msb) {
+			t.Errorf("ExtractWBitChunksBE mismatch for w=%d\nGot:      %v\nExpected: %v", tc.w, msb, tc.msb)
+		}
+	}
+	
+	if _, err := ExtractWBitChunksBE(data, 8, 4); err == nil {
+		t.Error("ExtractWBitChunksBE should reject requests beyond the input")
+	}
+}
+
 // Benchmark BytesToChunks
 func BenchmarkBytesToChunks(b *testing.B) {
 	data := make([]byte, 256)
@@ -32,8 +32,12 @@ type SHA3PRF struct {
 	outputLen int
 }
 
-// NewSHA3PRF creates a new SHA3-based PRF
+// NewSHA3PRF creates a new SHA3-based PRF. SHA3-256 yields 32 bytes, so
+// outputLen must be at most 32; use NewSHAKE256PRF for longer outputs.
 func NewSHA3PRF(keyLen, outputLen int) *SHA3PRF {
+	if outputLen > 32 {
+		panic("SHA3PRF output length must be <= 32 bytes")
+	}
 	return &SHA3PRF{
 		keyLen:    keyLen,
 		outputLen: outputLen,
@@ -72,7 +76,71 @@ func (p *SHA3PRF) Apply(key []byte, epoch uint32, chainIndex uint64) th.Domain {
 	return fullHash
 }
 
+// ApplyN squeezes n bytes for (key, epoch, chainIndex) regardless of
+// outputLen. It uses SHAKE256, not SHA3-256, so its output is that of
+// SHAKE256PRF and not an extension of Apply's.
+func (p *SHA3PRF) ApplyN(key []byte, epoch uint32, chainIndex uint64, n int) []byte {
+	return shake256PRF(key, epoch, chainIndex, n)
+}
+
 // OutputLen returns the output length in bytes
 func (p *SHA3PRF) OutputLen() int {
 	return p.outputLen
-}
\ No newline at end of file
+}
+
+// SHAKE256PRF implements a PRF using SHAKE256, supporting any output length
+type SHAKE256PRF struct {
+	keyLen    int
+	outputLen int
+}
+
+// NewSHAKE256PRF creates a new SHAKE256-based PRF
+func NewSHAKE256PRF(keyLen, outputLen int) *SHAKE256PRF {
+	return &SHAKE256PRF{
+		keyLen:    keyLen,
+		outputLen: outputLen,
+	}
+}
+
+// KeyGen generates a new PRF key
+func (p *SHAKE256PRF) KeyGen(rng io.Reader) []byte {
+	key := make([]byte, p.keyLen)
+	if _, err := io.ReadFull(rng, key); err != nil {
+		panic("failed to generate PRF key: " + err.Error())
+	}
+	return key
+}
+
+// Apply computes PRF(key, epoch, chainIndex), squeezing outputLen bytes
+func (p *SHAKE256PRF) Apply(key []byte, epoch uint32, chainIndex uint64) th.Domain {
+	return shake256PRF(key, epoch, chainIndex, p.outputLen)
+}
+
+// ApplyN squeezes n bytes of PRF(key, epoch, chainIndex). Apply's output
+// is its first outputLen bytes.
+func (p *SHAKE256PRF) ApplyN(key []byte, epoch uint32, chainIndex uint64, n int) []byte {
+	return shake256PRF(key, epoch, chainIndex, n)
+}
+
+// shake256PRF squeezes n bytes of
+// SHAKE256(domain separator || key || epoch || chainIndex)
+func shake256PRF(key []byte, epoch uint32, chainIndex uint64, n int) []byte {
+	h := sha3.NewShake256()
+	
+	// Write domain separator || key || epoch || chainIndex
+	h.Write(prfDomainSep)
+	h.Write(key)
+	var tail [12]byte
+	binary.BigEndian.PutUint32(tail[:4], epoch)
+	binary.BigEndian.PutUint64(tail[4:], chainIndex)
+	h.Write(tail[:])
+	
+	out := make([]byte, n)
+	h.Read(out)
+	return out
+}
+
+// OutputLen returns the output length in bytes
+func (p *SHAKE256PRF) OutputLen() int {
+	return p.outputLen
+}
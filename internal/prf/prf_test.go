@@ -0,0 +1,87 @@
+package prf
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+)
+
+// Test that SHAKE256PRF produces full-length outputs beyond 32 bytes
+func TestSHAKE256PRFLongOutput(t *testing.T) {
+	p := NewSHAKE256PRF(24, 48)
+	key := p.KeyGen(rand.Reader)
+	
+	out := p.Apply(key, 3, 7)
+	if len(out) != 48 {
+		t.Fatalf("Expected 48 bytes, got %d", len(out))
+	}
+	// The tail must be real output, not padding
+	if bytes.Equal(out[32:], make([]byte, 16)) {
+		t.Fatal("Output beyond 32 bytes is zero")
+	}
+	if !bytes.Equal(out, p.Apply(key, 3, 7)) {
+		t.Fatal("SHAKE256PRF is not deterministic")
+	}
+	if bytes.Equal(out, p.Apply(key, 3, 8)) {
+		t.Fatal("Different chain indices produced the same output")
+	}
+	// A shorter output is a prefix of the longer one
+	if !bytes.Equal(NewSHAKE256PRF(24, 16).Apply(key, 3, 7), out[:16]) {
+		t.Fatal("Shorter output is not a prefix")
+	}
+}
+
+// Test that ApplyN extends the SHAKE256 output prefix-stably
+func TestApplyNPrefixStable(t *testing.T) {
+	sha3PRF := NewSHA3PRF(24, 24)
+	shakePRF := NewSHAKE256PRF(24, 24)
+	key := sha3PRF.KeyGen(rand.Reader)
+	
+	long := sha3PRF.ApplyN(key, 9, 2, 100)
+	if len(long) != 100 {
+		t.Fatalf("Expected 100 bytes, got %d", len(long))
+	}
+	if !bytes.Equal(long[:24], shakePRF.Apply(key, 9, 2)) {
+		t.Fatal("ApplyN does not extend SHAKE256PRF.Apply")
+	}
+	if !bytes.Equal(long[:40], shakePRF.ApplyN(key, 9, 2, 40)) {
+		t.Fatal("ApplyN is not prefix-stable")
+	}
+	// Apply is unchanged and still SHA3-256 based
+	if bytes.Equal(sha3PRF.Apply(key, 9, 2), long[:24]) {
+		t.Fatal("SHA3PRF.Apply should not use SHAKE256")
+	}
+}
+
+// Test that SHA3PRF rejects outputs longer than SHA3-256 provides
+func TestSHA3PRFRejectsLongOutput(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected panic for outputLen > 32")
+		}
+	}()
+	NewSHA3PRF(24, 48)
+}
+
+// Test ChaChaPRF against a pinned output, so that chain starts stay the
+// same across runs and platforms
+func TestChaChaPRFDeterministic(t *testing.T) {
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	p := NewChaChaPRF(32, 24)
+	
+	out := p.Apply(key, 7, 3)
+	if got := hex.EncodeToString(out); got != "f89d69065451e12c2fc5bb17b515a5878f7a82fe4f7424c5" {
+		t.Fatalf("Unexpected output %s", got)
+	}
+	if bytes.Equal(out, p.Apply(key, 7, 4)) || bytes.Equal(out, p.Apply(key, 8, 3)) {
+		t.Fatal("Different inputs produced the same output")
+	}
+	// Longer outputs extend the keystream
+	if !bytes.Equal(NewChaChaPRF(32, 100).Apply(key, 7, 3)[:24], out) {
+		t.Fatal("Shorter output is not a prefix")
+	}
+}
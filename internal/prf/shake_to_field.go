@@ -6,6 +6,7 @@ import (
 	
 	"golang.org/x/crypto/sha3"
 	"github.com/consensys/gnark-crypto/field/babybear"
+	"github.com/aerius-labs/hash-sig-go/field"
 	"github.com/aerius-labs/hash-sig-go/th"
 )
 
@@ -68,7 +69,7 @@ func (p *ShakePRFtoField) Apply(key []byte, epoch uint32, chainIndex uint64) th.
 		
 		// Convert bytes to uint64 (big-endian) and take mod
 		val := binary.BigEndian.Uint64(prfOutput[chunkStart:chunkEnd])
-		val = val % 2013265921 // BabyBear prime
+		val = val % field.P
 		
 		var elem babybear.Element
 		elem.SetUint64(val)
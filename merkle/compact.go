@@ -0,0 +1,190 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	
+	"github.com/aerius-labs/hash-sig-go/th"
+)
+
+// CompactHashTree holds the same tree as a HashTree but stores only its top
+// levels. Nodes below are recomputed on demand from the leaves of the
+// subtree containing the requested epoch, using a leaf-derivation function
+// and the stored padding nodes. Path output is identical to the full tree's.
+type CompactHashTree struct {
+	depth  int
+	cut    int             // levels below cut are recomputed
+	top    []HashTreeLayer // levels cut..depth
+	lower  []compactLayer  // levels 0..cut-1
+	leafFn func(index uint32) th.Domain
+	th     th.TweakableHash
+	params th.Params
+}
+
+// compactLayer describes a recomputed layer: its padded extent and padding nodes
+type compactLayer struct {
+	startIndex int
+	numNodes   int
+	front      th.Domain // front padding node, nil if absent
+	back       th.Domain // back padding node, nil if absent
+}
+
+// Compact converts the tree into a CompactHashTree keeping its top
+// topLevels levels (the root counts as one; depth+1 keeps everything).
+// The real leaves are [leafStart, leafEnd), and leafFn must return the leaf
+// hash the tree was built with for each of them.
+func (t *HashTree) Compact(topLevels, leafStart, leafEnd int, leafFn func(index uint32) th.Domain) (*CompactHashTree, error) {
+	if topLevels < 1 || topLevels > t.depth+1 {
+		return nil, fmt.Errorf("top levels %d not in [1, %d]", topLevels, t.depth+1)
+	}
+	if len(t.layers) != t.depth+1 {
+		return nil, fmt.Errorf("tree has %d layers, expected %d", len(t.layers), t.depth+1)
+	}
+	leaves := &t.layers[0]
+	layerEnd := leaves.startIndex + len(leaves.nodes)
+	if leafStart < leaves.startIndex || leafStart > leaves.startIndex+1 ||
+		leafEnd > layerEnd || leafEnd < layerEnd-1 || leafStart >= leafEnd {
+		return nil, fmt.Errorf("leaf range [%d, %d) does not match leaf layer [%d, %d)", leafStart, leafEnd, leaves.startIndex, layerEnd)
+	}
+	
+	cut := t.depth + 1 - topLevels
+	lower := make([]compactLayer, cut)
+	realStart, realEnd := leafStart, leafEnd
+	for level := 0; level < cut; level++ {
+		layer := &t.layers[level]
+		end := layer.startIndex + len(layer.nodes)
+		lower[level] = compactLayer{startIndex: layer.startIndex, numNodes: len(layer.nodes)}
+		if layer.startIndex < realStart {
+			lower[level].front = layer.nodes[0]
+		}
+		if end > realEnd {
+			lower[level].back = layer.nodes[len(layer.nodes)-1]
+		}
+		
+		// The next level's real nodes are the parents of this padded layer
+		realStart, realEnd = layer.startIndex>>1, end>>1
+	}
+	
+	// Copy the top layers so the lower ones can be garbage collected
+	top := append([]HashTreeLayer(nil), t.layers[cut:]...)
+	
+	return &CompactHashTree{
+		depth:  t.depth,
+		cut:    cut,
+		top:    top,
+		lower:  lower,
+		leafFn: leafFn,
+		th:     t.th,
+		params: t.params,
+	}, nil
+}
+
+// GetDepth returns the depth of the tree
+func (t *CompactHashTree) GetDepth() int {
+	return t.depth
+}
+
+// Root returns a copy of the root hash of the tree
+func (t *CompactHashTree) Root() th.Domain {
+	rootLayer := &t.top[len(t.top)-1]
+	if len(rootLayer.nodes) == 0 {
+		return nil
+	}
+	return bytes.Clone(rootLayer.nodes[0])
+}
+
+// Path returns the authentication path for a given epoch, recomputing the
+// 2^cut-leaf subtree that contains it
+func (t *CompactHashTree) Path(epoch uint32) HashTreeOpening {
+	coPath := make([]th.Domain, 0, t.depth)
+	
+	// Nodes of the subtree containing epoch, level by level; nil marks
+	// positions outside the padded layer
+	base := int(epoch) >> t.cut << t.cut
+	var level []th.Domain
+	for l := 0; l < t.cut; l++ {
+		layer := &t.lower[l]
+		first := base >> l
+		nodes := make([]th.Domain, 1<<(t.cut-l))
+		for i := range nodes {
+			pos := first + i
+			switch {
+			case pos < layer.startIndex || pos >= layer.startIndex+layer.numNodes:
+				// Outside the padded layer
+			case pos == layer.startIndex && layer.front != nil:
+				nodes[i] = layer.front
+			case pos == layer.startIndex+layer.numNodes-1 && layer.back != nil:
+				nodes[i] = layer.back
+			case l == 0:
+				nodes[i] = t.leafFn(uint32(pos))
+			default:
+				tweak := t.th.TreeTweak(uint8(l), uint32(pos))
+				nodes[i] = t.th.Apply(t.params, tweak, []th.Domain{level[2*i], level[2*i+1]})
+			}
+		}
+		
+		sibling := (int(epoch)>>l)^1 - first
+		if nodes[sibling] != nil {
+			coPath = append(coPath, bytes.Clone(nodes[sibling]))
+		} else {
+			// Should not happen with proper padding
+			coPath = append(coPath, t.th.RandDomain(rand.Reader))
+		}
+		level = nodes
+	}
+	
+	// The stored levels work as in HashTree.Path
+	currentIndex := int(epoch) >> t.cut
+	for l := t.cut; l < t.depth; l++ {
+		layer := &t.top[l-t.cut]
+		siblingRelIndex := (currentIndex - layer.startIndex) ^ 1
+		if siblingRelIndex >= 0 && siblingRelIndex < len(layer.nodes) {
+			coPath = append(coPath, bytes.Clone(layer.nodes[siblingRelIndex]))
+		} else {
+			coPath = append(coPath, t.th.RandDomain(rand.Reader))
+		}
+		currentIndex >>= 1
+	}
+	
+	return HashTreeOpening{CoPath: coPath}
+}
+
+// Wipe overwrites every stored node, including padding, with zeros
+func (t *CompactHashTree) Wipe() {
+	for i := range t.top {
+		for _, node := range t.top[i].nodes {
+			clear(node)
+		}
+	}
+	for i := range t.lower {
+		clear(t.lower[i].front)
+		clear(t.lower[i].back)
+	}
+}
+
+// Equal reports whether two compact trees have the same depth, parameter,
+// stored levels and padding nodes. Their leaf functions and tweakable
+// hashes are not compared.
+func (t *CompactHashTree) Equal(other *CompactHashTree) bool {
+	if t == nil || other == nil {
+		return t == other
+	}
+	if t.depth != other.depth || t.cut != other.cut || !bytes.Equal(t.params, other.params) ||
+		len(t.top) != len(other.top) || len(t.lower) != len(other.lower) {
+		return false
+	}
+	for i := range t.top {
+		if !t.top[i].equal(&other.top[i]) {
+			return false
+		}
+	}
+	for i, l := range t.lower {
+		o := other.lower[i]
+		if l.startIndex != o.startIndex || l.numNodes != o.numNodes ||
+			!bytes.Equal(l.front, o.front) || !bytes.Equal(l.back, o.back) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/rand"
+	"math/big"
+	"testing"
+	
+	"github.com/aerius-labs/hash-sig-go/th"
+	"github.com/aerius-labs/hash-sig-go/th/tweak_hash"
+)
+
+// Test that a CompactHashTree produces exactly the full tree's paths
+func TestCompactHashTreeMatchesFull(t *testing.T) {
+	thash := tweak_hash.NewSHA3TweakableHash(24, 24)
+	param := thash.RandParameter(rand.Reader)
+	
+	const depth = 8
+	// Sparse ranges with and without padding at either end
+	ranges := [][2]int{{0, 256}, {3, 200}, {64, 128}, {17, 18}, {1, 254}}
+	for _, r := range ranges {
+		start, end := r[0], r[1]
+		leafHashes := make([]th.Domain, end-start)
+		for i := range leafHashes {
+			leafHashes[i] = thash.RandDomain(rand.Reader)
+		}
+		leafFn := func(index uint32) th.Domain {
+			return leafHashes[int(index)-start]
+		}
+		full := NewHashTree(rand.Reader, thash, depth, start, param, leafHashes)
+		
+		for _, topLevels := range []int{1, 3, depth, depth + 1} {
+			compact, err := full.Compact(topLevels, start, end, leafFn)
+			if err != nil {
+				t.Fatalf("Compact(%d) of [%d, %d) failed: %v", topLevels, start, end, err)
+			}
+			if !bytes.Equal(compact.Root(), full.Root()) {
+				t.Fatalf("Roots differ for [%d, %d), top levels %d", start, end, topLevels)
+			}
+			
+			// Random epochs plus both ends of the range
+			epochs := []int{start, end - 1}
+			for i := 0; i < 8; i++ {
+				n, _ := rand.Int(rand.Reader, big.NewInt(int64(end-start)))
+				epochs = append(epochs, start+int(n.Int64()))
+			}
+			for _, epoch := range epochs {
+				want := full.Path(uint32(epoch)).CoPath
+				got := compact.Path(uint32(epoch)).CoPath
+				if len(got) != len(want) {
+					t.Fatalf("Path length %d, expected %d", len(got), len(want))
+				}
+				for level := range want {
+					if !bytes.Equal(got[level], want[level]) {
+						t.Fatalf("[%d, %d), top levels %d, epoch %d: co-path differs at level %d",
+							start, end, topLevels, epoch, level)
+					}
+				}
+			}
+		}
+	}
+}
+
+// Test that Compact rejects inconsistent arguments
+func TestCompactRejectsBadArguments(t *testing.T) {
+	thash := tweak_hash.NewSHA3TweakableHash(24, 24)
+	param := thash.RandParameter(rand.Reader)
+	leafHashes := make([]th.Domain, 10)
+	for i := range leafHashes {
+		leafHashes[i] = thash.RandDomain(rand.Reader)
+	}
+	tree := NewHashTree(rand.Reader, thash, 5, 3, param, leafHashes)
+	leafFn := func(uint32) th.Domain { return nil }
+	
+	for _, args := range [][3]int{{0, 3, 13}, {7, 3, 13}, {3, 0, 13}, {3, 3, 20}, {3, 5, 13}} {
+		if _, err := tree.Compact(args[0], args[1], args[2], leafFn); err == nil {
+			t.Fatalf("Expected error for Compact%v", args)
+		}
+	}
+}
@@ -1,9 +1,14 @@
 package merkle
 
 import (
+	"bytes"
 	"crypto/rand"
+	"errors"
+	"fmt"
 	"io"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	
 	"github.com/aerius-labs/hash-sig-go/th"
 )
@@ -32,14 +37,33 @@ func NewHashTreeLayer(startIndex int, nodes []th.Domain) HashTreeLayer {
 	}
 }
 
+// equal reports whether two layers start at the same index and have the
+// same nodes
+func (l *HashTreeLayer) equal(other *HashTreeLayer) bool {
+	if l.startIndex != other.startIndex || len(l.nodes) != len(other.nodes) {
+		return false
+	}
+	for i := range l.nodes {
+		if !bytes.Equal(l.nodes[i], other.nodes[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// paddingNeeds reports whether a layer of numNodes nodes starting at startIndex
+// needs a front padding node (start must be even) and a back padding node
+// (end must be odd)
+func paddingNeeds(startIndex, numNodes int) (needsFront, needsBack bool) {
+	endIndex := startIndex + numNodes - 1
+	needsFront = (startIndex & 1) == 1
+	needsBack = (endIndex & 1) == 0
+	return needsFront, needsBack
+}
+
 // padded creates a padded layer ensuring start is even and end is odd
 func (l *HashTreeLayer) padded(rng io.Reader, thash th.TweakableHash, nodes []th.Domain, startIndex int) *HashTreeLayer {
-	endIndex := startIndex + len(nodes) - 1
-	
-	// Check if we need front padding (start must be even)
-	needsFront := (startIndex & 1) == 1
-	// Check if we need back padding (end must be odd)
-	needsBack := (endIndex & 1) == 0
+	needsFront, needsBack := paddingNeeds(startIndex, len(nodes))
 	
 	actualStartIndex := startIndex
 	if needsFront {
@@ -65,12 +89,41 @@ func (l *HashTreeLayer) padded(rng io.Reader, thash th.TweakableHash, nodes []th
 	}
 }
 
+// LayerNodeCounts returns the number of nodes stored at each level (leaves
+// first, root last) of a tree built by NewHashTree for numLeaves leaves
+// starting at startIndex, including padding nodes
+func LayerNodeCounts(depth, startIndex, numLeaves int) []int {
+	counts := make([]int, 0, depth+1)
+	
+	start, count := startIndex, numLeaves
+	for level := 0; level <= depth; level++ {
+		needsFront, needsBack := paddingNeeds(start, count)
+		if needsFront {
+			start--
+			count++
+		}
+		if needsBack {
+			count++
+		}
+		counts = append(counts, count)
+		
+		// The next level holds one parent per pair of padded nodes
+		start >>= 1
+		count /= 2
+	}
+	
+	return counts
+}
+
 // HashTree represents a sparse Merkle tree (Construction 1)
 type HashTree struct {
 	depth  int
 	layers []HashTreeLayer
 	th     th.TweakableHash
 	params th.Params
+	
+	// leafEnd is one past the last real (non-padding) leaf, or -1 if unknown
+	leafEnd int
 }
 
 // GetDepth returns the depth of the tree
@@ -89,21 +142,68 @@ func NewHashTreeFromLayers(depth int, layers []HashTreeLayer, params th.Params,
 		panic("TweakableHash cannot be nil - required for tree operations")
 	}
 	return &HashTree{
-		depth:  depth,
-		layers: layers,
-		params: params,
-		th:     thash,
+		depth:   depth,
+		layers:  layers,
+		params:  params,
+		th:      thash,
+		leafEnd: -1,
 	}
 }
 
+// TweakableHash returns the tweakable hash the tree was built with
+func (t *HashTree) TweakableHash() th.TweakableHash {
+	return t.th
+}
+
+// Equal reports whether two trees have the same depth, parameter and
+// nodes. Their tweakable hashes are not compared.
+func (t *HashTree) Equal(other *HashTree) bool {
+	if t == nil || other == nil {
+		return t == other
+	}
+	if t.depth != other.depth || !bytes.Equal(t.params, other.params) || len(t.layers) != len(other.layers) {
+		return false
+	}
+	for i := range t.layers {
+		if !t.layers[i].equal(&other.layers[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Parameter returns the public parameter the tree was hashed under
+func (t *HashTree) Parameter() th.Params {
+	return t.params
+}
+
 // HashTreeOpening represents a Merkle authentication path
 type HashTreeOpening struct {
 	CoPath []th.Domain
 }
 
+// Directions returns, for each level of the opening, whether the node on
+// the path from the leaf of epoch is the left child, i.e. whether its
+// co-path sibling is hashed on the right. Level l is bit l of epoch.
+func (o HashTreeOpening) Directions(epoch uint32) []bool {
+	dirs := make([]bool, len(o.CoPath))
+	for level := range dirs {
+		dirs[level] = (epoch>>level)&1 == 0
+	}
+	return dirs
+}
+
 // NewHashTree builds a new sparse hash tree
 func NewHashTree(rng io.Reader, thash th.TweakableHash, depth int, startIndex int, 
 	parameter th.Params, leafHashes []th.Domain) *HashTree {
+	return NewHashTreeObserved(rng, thash, depth, startIndex, parameter, leafHashes, nil)
+}
+
+// NewHashTreeObserved is NewHashTree with a callback invoked after each layer
+// is complete, with the level of that layer (0 for the leaves). A nil
+// callback is allowed.
+func NewHashTreeObserved(rng io.Reader, thash th.TweakableHash, depth int, startIndex int, 
+	parameter th.Params, leafHashes []th.Domain, onLayer func(level int)) *HashTree {
 	
 	if startIndex+len(leafHashes) > (1 << depth) {
 		panic("not enough space for leaves")
@@ -114,61 +214,211 @@ func NewHashTree(rng io.Reader, thash th.TweakableHash, depth int, startIndex in
 	// Start with the leaf layer, padded accordingly
 	layer := (&HashTreeLayer{}).padded(rng, thash, leafHashes, startIndex)
 	layers = append(layers, *layer)
+	if onLayer != nil {
+		onLayer(0)
+	}
 	
 	// Build tree layer by layer
 	for level := 0; level < depth; level++ {
 		prev := &layers[level]
 		parentStart := prev.startIndex >> 1
 		
-		// Hash pairs in parallel
-		numParents := len(prev.nodes) / 2
-		parents := make([]th.Domain, numParents)
-		
-		// Use goroutines for parallel hashing if we have many nodes
-		if numParents > 100 {
-			var wg sync.WaitGroup
-			wg.Add(numParents)
-			
-			for i := 0; i < numParents; i++ {
-				go func(idx int) {
-					defer wg.Done()
-					posInLevel := uint32(parentStart + idx)
-					tweak := thash.TreeTweak(uint8(level+1), posInLevel)
-					children := []th.Domain{
-						prev.nodes[2*idx],
-						prev.nodes[2*idx+1],
-					}
-					parents[idx] = thash.Apply(parameter, tweak, children)
-				}(i)
-			}
-			wg.Wait()
-		} else {
-			// Sequential for small trees
-			for i := 0; i < numParents; i++ {
-				posInLevel := uint32(parentStart + i)
-				tweak := thash.TreeTweak(uint8(level+1), posInLevel)
-				children := []th.Domain{
-					prev.nodes[2*i],
-					prev.nodes[2*i+1],
-				}
-				parents[i] = thash.Apply(parameter, tweak, children)
-			}
-		}
+		parents := hashParents(thash, parameter, level, parentStart, prev.nodes)
 		
 		// Pad the parent layer
 		parentLayer := (&HashTreeLayer{}).padded(rng, thash, parents, parentStart)
 		layers = append(layers, *parentLayer)
+		if onLayer != nil {
+			onLayer(level + 1)
+		}
 	}
 	
 	return &HashTree{
-		depth:  depth,
-		layers: layers,
-		th:     thash,
-		params: parameter,
+		depth:   depth,
+		layers:  layers,
+		th:      thash,
+		params:  parameter,
+		leafEnd: startIndex + len(leafHashes),
+	}
+}
+
+// ComputeRoot returns the root NewHashTree would build for the same
+// arguments, but keeps only one level at a time: O(number of leaves) memory
+// instead of every layer. Padding nodes are drawn from rng exactly as
+// NewHashTree draws them, so the roots match only if rng yields the same
+// stream as the one the tree was built with, e.g. the same seeded reader.
+// Unless the leaves fill the whole tree, a fresh random rng gives a
+// different root.
+func ComputeRoot(rng io.Reader, thash th.TweakableHash, depth int, startIndex int,
+	parameter th.Params, leafHashes []th.Domain) th.Domain {
+	
+	if startIndex+len(leafHashes) > (1 << depth) {
+		panic("not enough space for leaves")
+	}
+	
+	layer := (&HashTreeLayer{}).padded(rng, thash, leafHashes, startIndex)
+	for level := 0; level < depth; level++ {
+		parentStart := layer.startIndex >> 1
+		parents := hashParents(thash, parameter, level, parentStart, layer.nodes)
+		layer = (&HashTreeLayer{}).padded(rng, thash, parents, parentStart)
 	}
+	return layer.nodes[0]
+}
+
+// DefaultParallelThreshold is the initial parallel threshold, see
+// SetParallelThreshold
+const DefaultParallelThreshold = 100
+
+// parallelThreshold is the number of parents above which a layer is hashed
+// in parallel; negative disables parallel hashing
+var parallelThreshold atomic.Int64
+
+func init() {
+	parallelThreshold.Store(DefaultParallelThreshold)
+}
+
+// SetParallelThreshold sets how many parent nodes a tree layer must have
+// for tree building to hash it in parallel goroutines: layers with more
+// than n parents are. Expensive hashes such as Poseidon break even with
+// lower thresholds than SHA3. A negative n disables parallel hashing. The
+// threshold applies to trees built after the call, and does not affect
+// hashes that batch layers themselves (th.BatchTweakableHash). It returns
+// the previous threshold.
+func SetParallelThreshold(n int) int {
+	return int(parallelThreshold.Swap(int64(n)))
+}
+
+// ParallelThreshold returns the threshold set by SetParallelThreshold
+func ParallelThreshold() int {
+	return int(parallelThreshold.Load())
 }
 
-// Root returns the root hash of the tree
+// hashParents hashes consecutive pairs of children at the given level into
+// the parents at level+1, starting at position parentStart
+func hashParents(thash th.TweakableHash, parameter th.Params, level int, parentStart int, children []th.Domain) []th.Domain {
+	// Hash pairs in parallel
+	numParents := len(children) / 2
+	parents := make([]th.Domain, numParents)
+	
+	if batch, ok := thash.(th.BatchTweakableHash); ok {
+		// Let the hash amortize setup and parallelize internally
+		tweaks := make([]th.Tweak, numParents)
+		pairs := make([][]th.Domain, numParents)
+		for i := 0; i < numParents; i++ {
+			tweaks[i] = thash.TreeTweak(uint8(level+1), uint32(parentStart+i))
+			pairs[i] = []th.Domain{
+				children[2*i],
+				children[2*i+1],
+			}
+		}
+		return batch.ApplyBatch(parameter, tweaks, pairs)
+	}
+	
+	// Parents share one backing array, capped so none can grow into the next
+	outLen := thash.OutputLen()
+	backing := make([]byte, numParents*outLen)
+	for i := range parents {
+		parents[i] = backing[i*outLen : (i+1)*outLen : (i+1)*outLen]
+	}
+	
+	if threshold := ParallelThreshold(); threshold >= 0 && numParents > threshold {
+		// Use goroutines for parallel hashing if we have many nodes
+		var wg sync.WaitGroup
+		wg.Add(numParents)
+		
+		for i := 0; i < numParents; i++ {
+			go func(idx int) {
+				defer wg.Done()
+				posInLevel := uint32(parentStart + idx)
+				tweak := thash.TreeTweak(uint8(level+1), posInLevel)
+				pair := []th.Domain{
+					children[2*idx],
+					children[2*idx+1],
+				}
+				th.ApplyInto(thash, parents[idx], parameter, tweak, pair)
+			}(i)
+		}
+		wg.Wait()
+		return parents
+	}
+	
+	// Sequential for small trees
+	for i := 0; i < numParents; i++ {
+		posInLevel := uint32(parentStart + i)
+		tweak := thash.TreeTweak(uint8(level+1), posInLevel)
+		pair := []th.Domain{
+			children[2*i],
+			children[2*i+1],
+		}
+		th.ApplyInto(thash, parents[i], parameter, tweak, pair)
+	}
+	return parents
+}
+
+// SetLeafEnd records that the real leaves of a tree rebuilt with
+// NewHashTreeFromLayers end just before index end, which AppendLeaves
+// needs to tell a trailing padding node from a leaf. end must be the end of
+// the leaf layer, or one less if its last node is padding.
+func (t *HashTree) SetLeafEnd(end int) error {
+	if len(t.layers) == 0 {
+		return errors.New("tree has no layers")
+	}
+	leaves := &t.layers[0]
+	layerEnd := leaves.startIndex + len(leaves.nodes)
+	if end != layerEnd && end != layerEnd-1 {
+		return fmt.Errorf("leaf end %d does not match leaf layer [%d, %d)", end, leaves.startIndex, layerEnd)
+	}
+	t.leafEnd = end
+	return nil
+}
+
+// AppendLeaves adds leaf hashes directly after the last leaf of the tree.
+// Only nodes above the new leaves (and the padding they replace) are
+// recomputed; everything to their left, including front padding, is kept.
+// The root changes, but paths of existing leaves taken after the append
+// verify against the new root.
+func (t *HashTree) AppendLeaves(rng io.Reader, newLeafHashes []th.Domain) error {
+	if len(newLeafHashes) == 0 {
+		return nil
+	}
+	if t.leafEnd < 0 {
+		return errors.New("leaf range unknown; call SetLeafEnd first")
+	}
+	if t.leafEnd+len(newLeafHashes) > (1 << t.depth) {
+		return errors.New("not enough space for leaves")
+	}
+	
+	// Nodes at positions >= changed are replaced or added at each level
+	changed := t.leafEnd
+	fresh := newLeafHashes
+	for level := 0; level <= t.depth; level++ {
+		layer := &t.layers[level]
+		
+		// Layers start at an even position, so only back padding can be needed.
+		// The capped slice makes append copy instead of overwriting old nodes.
+		keep := changed - layer.startIndex
+		nodes := append(layer.nodes[:keep:keep], fresh...)
+		if _, needsBack := paddingNeeds(layer.startIndex, len(nodes)); needsBack {
+			nodes = append(nodes, t.th.RandDomain(rng))
+		}
+		layer.nodes = nodes
+		
+		if level == t.depth {
+			break
+		}
+		
+		// Recompute every parent with a changed child
+		parentChanged := changed >> 1
+		first := 2*parentChanged - layer.startIndex
+		fresh = hashParents(t.th, t.params, level, parentChanged, nodes[first:])
+		changed = parentChanged
+	}
+	
+	t.leafEnd += len(newLeafHashes)
+	return nil
+}
+
+// Root returns a copy of the root hash of the tree
 func (t *HashTree) Root() th.Domain {
 	if len(t.layers) == 0 {
 		return nil
@@ -177,10 +427,11 @@ func (t *HashTree) Root() th.Domain {
 	if len(rootLayer.nodes) == 0 {
 		return nil
 	}
-	return rootLayer.nodes[0]
+	return bytes.Clone(rootLayer.nodes[0])
 }
 
-// Path returns the authentication path for a given epoch
+// Path returns the authentication path for a given epoch. The co-path
+// nodes are copies, so they do not alias the tree.
 func (t *HashTree) Path(epoch uint32) HashTreeOpening {
 	leafIndex := int(epoch)
 	coPath := make([]th.Domain, 0, t.depth)
@@ -198,7 +449,7 @@ func (t *HashTree) Path(epoch uint32) HashTreeOpening {
 		siblingRelIndex := relIndex ^ 1
 		
 		if siblingRelIndex >= 0 && siblingRelIndex < len(layer.nodes) {
-			coPath = append(coPath, layer.nodes[siblingRelIndex])
+			coPath = append(coPath, bytes.Clone(layer.nodes[siblingRelIndex]))
 		} else {
 			// Should not happen with proper padding
 			coPath = append(coPath, t.th.RandDomain(rand.Reader))
@@ -211,13 +462,77 @@ func (t *HashTree) Path(epoch uint32) HashTreeOpening {
 	return HashTreeOpening{CoPath: coPath}
 }
 
-// VerifyPath verifies a Merkle authentication path
+// SelfCheck recomputes the root from the stored leaf node and Path of each
+// of sampleEpochs, and reports whether all of them match Root. It catches
+// padding and indexing bugs in tree construction before the first
+// signature does. Epochs outside the leaf layer fail the check.
+func (t *HashTree) SelfCheck(sampleEpochs []uint32) bool {
+	root := t.Root()
+	if root == nil || len(t.layers) != t.depth+1 {
+		return false
+	}
+	
+	leaves := &t.layers[0]
+	for _, epoch := range sampleEpochs {
+		relIndex := int(epoch) - leaves.startIndex
+		if relIndex < 0 || relIndex >= len(leaves.nodes) {
+			return false
+		}
+		if !bytes.Equal(nodePathRoot(t.th, t.params, epoch, leaves.nodes[relIndex], t.Path(epoch)), root) {
+			return false
+		}
+	}
+	return true
+}
+
+// VerifyPath verifies a Merkle authentication path.
+//
+// The path is bound to epoch: the leaf is hashed with tweak (0, epoch) and
+// the node at level l with tweak (l, epoch >> l), whose low bit also decides
+// the child order. A co-path taken from another leaf, or a valid
+// leaf/co-path pair presented under another epoch, therefore recomputes
+// different nodes and fails, whatever the tree's sparsity.
 func VerifyPath(thash th.TweakableHash, parameter th.Params, root th.Domain, 
 	epoch uint32, leaf []th.Domain, path HashTreeOpening) bool {
 	
-	// Hash the leaf first
-	leafTweak := thash.TreeTweak(0, epoch)
-	current := thash.Apply(parameter, leafTweak, leaf)
+	return bytes.Equal(PathRoot(thash, parameter, epoch, leaf, path), root)
+}
+
+// VerifyPathAtDepth is VerifyPath for a tree of the given depth: it also
+// rejects paths whose co-path does not have exactly depth nodes, and epochs
+// outside the tree. VerifyPath trusts the co-path length, so a truncated
+// path recomputes an inner node and verifies against a root taken from it.
+func VerifyPathAtDepth(thash th.TweakableHash, parameter th.Params, root th.Domain, 
+	depth int, epoch uint32, leaf []th.Domain, path HashTreeOpening) bool {
+	
+	if len(path.CoPath) != depth {
+		return false
+	}
+	if depth < 32 && uint64(epoch) >= uint64(1)<<depth {
+		return false
+	}
+	return VerifyPath(thash, parameter, root, epoch, leaf, path)
+}
+
+// HashLeaf hashes the chain ends of an epoch into its Merkle leaf node,
+// under the level-0 tree tweak at the epoch's position. Signers building
+// the tree and verifiers recomputing the root both hash leaves this way.
+func HashLeaf(thash th.TweakableHash, parameter th.Params, epoch uint32, chainEnds []th.Domain) th.Domain {
+	return thash.Apply(parameter, thash.TreeTweak(0, epoch), chainEnds)
+}
+
+// PathRoot returns the root that leaf and path recompute to for epoch,
+// hashing as VerifyPath does, without comparing it to anything
+func PathRoot(thash th.TweakableHash, parameter th.Params, 
+	epoch uint32, leaf []th.Domain, path HashTreeOpening) th.Domain {
+	
+	return nodePathRoot(thash, parameter, epoch, HashLeaf(thash, parameter, epoch, leaf), path)
+}
+
+// nodePathRoot returns the root that the hashed leaf node at epoch and path
+// recompute to
+func nodePathRoot(thash th.TweakableHash, parameter th.Params, 
+	epoch uint32, current th.Domain, path HashTreeOpening) th.Domain {
 	
 	// Walk up the tree
 	index := epoch
@@ -237,15 +552,116 @@ func VerifyPath(thash th.TweakableHash, parameter th.Params, root th.Domain,
 		
 		index = parentIndex
 	}
+	return current
+}
+
+// PathItem is one opening to check with VerifyPaths
+type PathItem struct {
+	Epoch   uint32
+	Leaf    []th.Domain
+	Opening HashTreeOpening
+}
+
+// VerifyPaths runs VerifyPath on every item against the same root, spreading
+// the items over up to GOMAXPROCS goroutines. result[i] is the verdict for items[i].
+func VerifyPaths(thash th.TweakableHash, parameter th.Params, root th.Domain, items []PathItem) []bool {
+	result := make([]bool, len(items))
+	thash = th.WithPreparedParams(thash, parameter)
+	
+	workers := min(runtime.GOMAXPROCS(0), len(items))
+	if workers <= 1 {
+		for i := range items {
+			result[i] = VerifyPath(thash, parameter, root, items[i].Epoch, items[i].Leaf, items[i].Opening)
+		}
+		return result
+	}
+	
+	next := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				result[i] = VerifyPath(thash, parameter, root, items[i].Epoch, items[i].Leaf, items[i].Opening)
+			}
+		}()
+	}
+	for i := range items {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return result
+}
+
+// VerifyMultiProof verifies several leaves of one tree against root in a
+// single bottom-up pass. leaves and openings must hold the same epochs, and
+// all openings must have the same depth. Co-path nodes shared between
+// openings must agree, and a co-path node must equal the node computed from
+// another leaf when their positions coincide; any conflict is rejected.
+func VerifyMultiProof(thash th.TweakableHash, parameter th.Params, root th.Domain,
+	leaves map[uint32][]th.Domain, openings map[uint32]HashTreeOpening) bool {
 	
-	// Compare with root
-	if len(current) != len(root) {
+	if len(leaves) == 0 || len(leaves) != len(openings) {
 		return false
 	}
-	for i := range current {
-		if current[i] != root[i] {
+	
+	// Hash the leaves, keyed by their position in the current level
+	depth := -1
+	current := make(map[uint32]th.Domain, len(leaves))
+	for epoch, leaf := range leaves {
+		opening, ok := openings[epoch]
+		if !ok {
 			return false
 		}
+		if depth < 0 {
+			depth = len(opening.CoPath)
+		} else if len(opening.CoPath) != depth {
+			return false
+		}
+		current[epoch] = HashLeaf(thash, parameter, epoch, leaf)
 	}
-	return true
-}
\ No newline at end of file
+	
+	for level := 0; level < depth; level++ {
+		// Collect the co-path nodes of this level, checking for conflicts
+		siblings := make(map[uint32]th.Domain)
+		for epoch, opening := range openings {
+			pos := (epoch >> level) ^ 1
+			node := opening.CoPath[level]
+			if computed, ok := current[pos]; ok {
+				if !bytes.Equal(computed, node) {
+					return false
+				}
+				continue
+			}
+			if seen, ok := siblings[pos]; ok && !bytes.Equal(seen, node) {
+				return false
+			}
+			siblings[pos] = node
+		}
+		
+		node := func(pos uint32) th.Domain {
+			if n, ok := current[pos]; ok {
+				return n
+			}
+			return siblings[pos]
+		}
+		
+		// Hash each distinct parent once
+		parents := make(map[uint32]th.Domain, len(current))
+		for pos := range current {
+			parentPos := pos >> 1
+			if _, done := parents[parentPos]; done {
+				continue
+			}
+			tweak := thash.TreeTweak(uint8(level+1), parentPos)
+			parents[parentPos] = thash.Apply(parameter, tweak, []th.Domain{node(parentPos << 1), node(parentPos<<1 | 1)})
+		}
+		current = parents
+	}
+	
+	// Every path must have converged on the root at position 0
+	top, ok := current[0]
+	return ok && len(current) == 1 && bytes.Equal(top, root)
+}
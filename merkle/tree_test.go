@@ -4,12 +4,26 @@ import (
 	"bytes"
 	"crypto/rand"
 	"fmt"
+	"io"
 	"testing"
 	
+	"golang.org/x/crypto/sha3"
 	"github.com/aerius-labs/hash-sig-go/th"
 	"github.com/aerius-labs/hash-sig-go/th/tweak_hash"
 )
 
+// plainHash hides any optional interfaces (such as batching) of the wrapped hash
+type plainHash struct {
+	th.TweakableHash
+}
+
+// seededReader returns a deterministic RNG for reproducible padding
+func seededReader(seed byte) io.Reader {
+	shake := sha3.NewShake128()
+	shake.Write([]byte{seed})
+	return shake
+}
+
 // Test basic Merkle tree construction
 func TestMerkleTreeConstruction(t *testing.T) {
 	thash := tweak_hash.NewSHA3TweakableHash(24, 24)
@@ -186,6 +200,71 @@ func TestTreeUniqueness(t *testing.T) {
 	}
 }
 
+// Test that the parallel threshold changes how layers are hashed, not the tree
+func TestSetParallelThreshold(t *testing.T) {
+	if got := ParallelThreshold(); got != DefaultParallelThreshold {
+		t.Fatalf("Expected default threshold %d, got %d", DefaultParallelThreshold, got)
+	}
+	defer SetParallelThreshold(DefaultParallelThreshold)
+	
+	thash := plainHash{tweak_hash.NewSHA3TweakableHash(24, 24)}
+	param := thash.RandParameter(rand.Reader)
+	leafHashes := make([]th.Domain, 37)
+	for i := range leafHashes {
+		leafHashes[i] = thash.RandDomain(rand.Reader)
+	}
+	
+	var want th.Domain
+	for _, threshold := range []int{-1, 0, 3, DefaultParallelThreshold} {
+		SetParallelThreshold(threshold)
+		if got := ParallelThreshold(); got != threshold {
+			t.Fatalf("Set threshold %d, got %d", threshold, got)
+		}
+		root := NewHashTree(seededReader(7), thash, 7, 5, param, leafHashes).Root()
+		if want == nil {
+			want = root
+		} else if !bytes.Equal(root, want) {
+			t.Fatalf("Threshold %d changed the root", threshold)
+		}
+	}
+	
+	if previous := SetParallelThreshold(50); previous != DefaultParallelThreshold {
+		t.Fatalf("Expected previous threshold %d, got %d", DefaultParallelThreshold, previous)
+	}
+}
+
+// Test that SelfCheck accepts a fresh tree and notices a corrupted node
+func TestHashTreeSelfCheck(t *testing.T) {
+	thash := tweak_hash.NewSHA3TweakableHash(24, 24)
+	param := thash.RandParameter(rand.Reader)
+	
+	const start = 5
+	leafHashes := make([]th.Domain, 20)
+	for i := range leafHashes {
+		leafHashes[i] = thash.RandDomain(rand.Reader)
+	}
+	tree := NewHashTree(rand.Reader, thash, 6, start, param, leafHashes)
+	
+	samples := []uint32{start, start + 9, start + 19}
+	if !tree.SelfCheck(samples) {
+		t.Fatal("Fresh tree failed self-check")
+	}
+	if tree.SelfCheck([]uint32{start + 40}) {
+		t.Fatal("Self-check accepted an epoch outside the leaf layer")
+	}
+	
+	// Corrupt the level-1 parent of epoch start+7, which is on the co-path
+	// of epoch start+9
+	node := tree.layers[1].nodes[(start+7)/2-tree.layers[1].startIndex]
+	node[0] ^= 1
+	if !tree.SelfCheck([]uint32{start + 19}) {
+		t.Fatal("Corruption elsewhere failed an unaffected path")
+	}
+	if tree.SelfCheck(samples) {
+		t.Fatal("Self-check missed a corrupted node")
+	}
+}
+
 // Test incorrect path verification fails
 func TestIncorrectPathFails(t *testing.T) {
 	thash := tweak_hash.NewSHA3TweakableHash(24, 24)
@@ -227,6 +306,289 @@ func TestIncorrectPathFails(t *testing.T) {
 	}
 }
 
+// Test that VerifyPathAtDepth rejects a truncated co-path checked against
+// the inner node it recomputes, which VerifyPath accepts
+func TestVerifyPathAtDepthRejectsTruncatedPath(t *testing.T) {
+	thash := tweak_hash.NewSHA3TweakableHash(24, 24)
+	param := thash.RandParameter(rand.Reader)
+	
+	const depth = 4
+	leafData := make([][]th.Domain, 1<<depth)
+	leafHashes := make([]th.Domain, 1<<depth)
+	for i := range leafData {
+		leafData[i] = []th.Domain{thash.RandDomain(rand.Reader)}
+		leafHashes[i] = HashLeaf(thash, param, uint32(i), leafData[i])
+	}
+	tree := NewHashTree(rand.Reader, thash, depth, 0, param, leafHashes)
+	
+	const epoch = 5
+	path := tree.Path(epoch)
+	if !VerifyPathAtDepth(thash, param, tree.Root(), depth, epoch, leafData[epoch], path) {
+		t.Fatal("Rejected a valid path")
+	}
+	
+	// An attacker who controls the root can present the level-2 node
+	truncated := HashTreeOpening{CoPath: path.CoPath[:2]}
+	innerRoot := PathRoot(thash, param, epoch, leafData[epoch], truncated)
+	if !VerifyPath(thash, param, innerRoot, epoch, leafData[epoch], truncated) {
+		t.Fatal("VerifyPath should accept the truncated path against its inner node")
+	}
+	if VerifyPathAtDepth(thash, param, innerRoot, depth, epoch, leafData[epoch], truncated) {
+		t.Fatal("Accepted a truncated co-path")
+	}
+	
+	// So can an over-long path, and an epoch past the tree
+	long := HashTreeOpening{CoPath: append(append([]th.Domain(nil), path.CoPath...), tree.Root())}
+	longRoot := PathRoot(thash, param, epoch, leafData[epoch], long)
+	if VerifyPathAtDepth(thash, param, longRoot, depth, epoch, leafData[epoch], long) {
+		t.Fatal("Accepted an over-long co-path")
+	}
+	if VerifyPathAtDepth(thash, param, tree.Root(), depth, epoch+1<<depth, leafData[epoch], path) {
+		t.Fatal("Accepted an epoch outside the tree")
+	}
+}
+
+// Test verifying several epochs at once, with shared upper co-path nodes
+func TestVerifyMultiProof(t *testing.T) {
+	thash := tweak_hash.NewSHA3TweakableHash(24, 24)
+	param := thash.RandParameter(rand.Reader)
+	
+	leafData := make([][]th.Domain, 8)
+	leafHashes := make([]th.Domain, 8)
+	for i := range leafData {
+		leafData[i] = []th.Domain{thash.RandDomain(rand.Reader)}
+		leafHashes[i] = thash.Apply(param, thash.TreeTweak(0, uint32(i)), leafData[i])
+	}
+	tree := NewHashTree(rand.Reader, thash, 3, 0, param, leafHashes)
+	root := tree.Root()
+	
+	// Epochs 0, 1 and 2 share the level-2 co-path node covering leaves 4..7,
+	// and epoch 2's level-1 ancestor is the level-1 co-path node of 0 and 1
+	epochs := []uint32{0, 1, 2}
+	build := func() (map[uint32][]th.Domain, map[uint32]HashTreeOpening) {
+		leaves := make(map[uint32][]th.Domain)
+		openings := make(map[uint32]HashTreeOpening)
+		for _, e := range epochs {
+			leaves[e] = leafData[e]
+			path := tree.Path(e)
+			coPath := make([]th.Domain, len(path.CoPath))
+			for i := range coPath {
+				coPath[i] = append(th.Domain(nil), path.CoPath[i]...)
+			}
+			openings[e] = HashTreeOpening{CoPath: coPath}
+		}
+		return leaves, openings
+	}
+	
+	leaves, openings := build()
+	if !VerifyMultiProof(thash, param, root, leaves, openings) {
+		t.Fatal("Valid multiproof rejected")
+	}
+	
+	// Tampering the shared node in one opening is a conflict
+	leaves, openings = build()
+	openings[1].CoPath[2][0] ^= 1
+	if VerifyMultiProof(thash, param, root, leaves, openings) {
+		t.Fatal("Conflicting shared co-path node accepted")
+	}
+	
+	// Tampering it consistently in all openings changes the root
+	leaves, openings = build()
+	for _, e := range epochs {
+		openings[e].CoPath[2][0] ^= 1
+	}
+	if VerifyMultiProof(thash, param, root, leaves, openings) {
+		t.Fatal("Consistently tampered shared node accepted")
+	}
+	
+	// A co-path node that disagrees with a computed ancestor is rejected
+	leaves, openings = build()
+	openings[0].CoPath[1][0] ^= 1
+	if VerifyMultiProof(thash, param, root, leaves, openings) {
+		t.Fatal("Co-path node conflicting with computed node accepted")
+	}
+	
+	// A wrong leaf, or mismatched leaf and opening sets
+	leaves, openings = build()
+	leaves[2] = leafData[3]
+	if VerifyMultiProof(thash, param, root, leaves, openings) {
+		t.Fatal("Wrong leaf accepted")
+	}
+	leaves, openings = build()
+	delete(openings, 2)
+	if VerifyMultiProof(thash, param, root, leaves, openings) {
+		t.Fatal("Leaf without opening accepted")
+	}
+}
+
+// Test growing a sparse tree with AppendLeaves
+func TestAppendLeaves(t *testing.T) {
+	thash := tweak_hash.NewSHA3TweakableHash(24, 24)
+	param := thash.RandParameter(rand.Reader)
+	
+	const (
+		depth = 5
+		start = 3
+	)
+	leafData := make([][]th.Domain, 20)
+	leafHashes := make([]th.Domain, len(leafData))
+	for i := range leafData {
+		leafData[i] = []th.Domain{thash.RandDomain(rand.Reader)}
+		leafHashes[i] = thash.Apply(param, thash.TreeTweak(0, uint32(start+i)), leafData[i])
+	}
+	
+	verifyAll := func(tree *HashTree, n int) {
+		t.Helper()
+		root := tree.Root()
+		for i := 0; i < n; i++ {
+			if !VerifyPath(thash, param, root, uint32(start+i), leafData[i], tree.Path(uint32(start+i))) {
+				t.Fatalf("Path for leaf %d fails after growing to %d leaves", start+i, n)
+			}
+		}
+		layers := tree.GetLayers()
+		for level, want := range LayerNodeCounts(depth, start, n) {
+			if got := len(layers[level].GetNodes()); got != want {
+				t.Fatalf("Level %d has %d nodes, expected %d", level, got, want)
+			}
+		}
+	}
+	
+	// Grow in steps that end on both even and odd positions, so trailing
+	// padding is replaced as well as extended
+	tree := NewHashTree(rand.Reader, thash, depth, start, param, leafHashes[:5])
+	frontPad := bytes.Clone(tree.GetLayers()[0].GetNodes()[0])
+	n := 5
+	for _, step := range []int{1, 3, 4} {
+		if err := tree.AppendLeaves(rand.Reader, leafHashes[n:n+step]); err != nil {
+			t.Fatalf("AppendLeaves failed: %v", err)
+		}
+		n += step
+		verifyAll(tree, n)
+	}
+	if !bytes.Equal(tree.GetLayers()[0].GetNodes()[0], frontPad) {
+		t.Fatal("Front padding changed")
+	}
+	
+	// A tree rebuilt from layers needs its leaf end first
+	rebuilt := NewHashTreeFromLayers(depth, tree.GetLayers(), param, thash)
+	if err := rebuilt.AppendLeaves(rand.Reader, leafHashes[n:]); err == nil {
+		t.Fatal("Expected error for unknown leaf end")
+	}
+	if err := rebuilt.SetLeafEnd(start + n + 5); err == nil {
+		t.Fatal("Expected error for leaf end outside the leaf layer")
+	}
+	if err := rebuilt.SetLeafEnd(start + n); err != nil {
+		t.Fatalf("SetLeafEnd failed: %v", err)
+	}
+	if err := rebuilt.AppendLeaves(rand.Reader, leafHashes[n:]); err != nil {
+		t.Fatalf("AppendLeaves failed: %v", err)
+	}
+	verifyAll(rebuilt, len(leafHashes))
+	
+	// The tree has room for 32 leaves, and start+20 = 23 are used
+	if err := rebuilt.AppendLeaves(rand.Reader, make([]th.Domain, 10)); err == nil {
+		t.Fatal("Expected error when leaves exceed the tree")
+	}
+}
+
+// Test that ComputeRoot matches the root of the full tree for the same padding
+func TestComputeRoot(t *testing.T) {
+	thash := tweak_hash.NewSHA3TweakableHash(24, 24)
+	param := thash.RandParameter(rand.Reader)
+	
+	for _, r := range [][2]int{{0, 64}, {5, 40}, {1, 2}, {63, 64}} {
+		start, end := r[0], r[1]
+		leafHashes := make([]th.Domain, end-start)
+		for i := range leafHashes {
+			leafHashes[i] = thash.RandDomain(rand.Reader)
+		}
+		
+		want := NewHashTree(seededReader(7), thash, 6, start, param, leafHashes).Root()
+		got := ComputeRoot(seededReader(7), thash, 6, start, param, leafHashes)
+		if !bytes.Equal(got, want) {
+			t.Fatalf("Roots differ for leaves [%d, %d)", start, end)
+		}
+		
+		// Padding comes from rng, so another stream changes a sparse root
+		other := ComputeRoot(seededReader(8), thash, 6, start, param, leafHashes)
+		if full := start == 0 && end == 64; bytes.Equal(other, want) != full {
+			t.Fatalf("Leaves [%d, %d): root with another padding stream equal = %v", start, end, !full)
+		}
+	}
+}
+
+// Test that batched level hashing builds the same tree as per-node Apply
+func TestBatchTreeMatchesPerNode(t *testing.T) {
+	thash := tweak_hash.NewSHA3TweakableHash(24, 24)
+	if _, ok := interface{}(thash).(th.BatchTweakableHash); !ok {
+		t.Fatal("SHA3 tweakable hash should implement BatchTweakableHash")
+	}
+	param := thash.RandParameter(rand.Reader)
+	
+	// Enough leaves to exercise the parallel paths of both builders
+	startIndex := 3
+	numLeaves := 500
+	leafData := make([][]th.Domain, numLeaves)
+	leafHashes := make([]th.Domain, numLeaves)
+	for i := 0; i < numLeaves; i++ {
+		leafData[i] = []th.Domain{thash.RandDomain(rand.Reader)}
+		leafHashes[i] = thash.Apply(param, thash.TreeTweak(0, uint32(startIndex+i)), leafData[i])
+	}
+	
+	batched := NewHashTree(seededReader(1), thash, 10, startIndex, param, leafHashes)
+	perNode := NewHashTree(seededReader(1), plainHash{thash}, 10, startIndex, param, leafHashes)
+	
+	if !bytes.Equal(batched.Root(), perNode.Root()) {
+		t.Fatal("Batched and per-node trees have different roots")
+	}
+	
+	for _, i := range []int{0, 1, 250, numLeaves - 1} {
+		epoch := uint32(startIndex + i)
+		if !VerifyPath(thash, param, batched.Root(), epoch, leafData[i], batched.Path(epoch)) {
+			t.Fatalf("Path verification failed for epoch %d in batched tree", epoch)
+		}
+	}
+}
+
+// Test that LayerNodeCounts matches the layers of a built tree
+func TestLayerNodeCounts(t *testing.T) {
+	thash := tweak_hash.NewSHA3TweakableHash(16, 24)
+	param := thash.RandParameter(rand.Reader)
+	
+	testCases := []struct {
+		depth, startIndex, numLeaves int
+	}{
+		{3, 0, 8},
+		{3, 0, 5},
+		{5, 10, 5},
+		{5, 7, 1},
+		{6, 13, 30},
+		{8, 1, 254},
+	}
+	
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("d%d_s%d_n%d", tc.depth, tc.startIndex, tc.numLeaves), func(t *testing.T) {
+			leafHashes := make([]th.Domain, tc.numLeaves)
+			for i := range leafHashes {
+				leafHashes[i] = thash.RandDomain(rand.Reader)
+			}
+			tree := NewHashTree(rand.Reader, thash, tc.depth, tc.startIndex, param, leafHashes)
+			
+			counts := LayerNodeCounts(tc.depth, tc.startIndex, tc.numLeaves)
+			layers := tree.GetLayers()
+			if len(counts) != len(layers) {
+				t.Fatalf("Expected %d levels, got %d", len(layers), len(counts))
+			}
+			for level, layer := range layers {
+				if counts[level] != len(layer.GetNodes()) {
+					t.Errorf("Level %d: LayerNodeCounts = %d, tree has %d nodes",
+						level, counts[level], len(layer.GetNodes()))
+				}
+			}
+		})
+	}
+}
+
 // Benchmark tree construction
 func BenchmarkTreeConstruction(b *testing.B) {
 	thash := tweak_hash.NewSHA3TweakableHash(24, 24)
@@ -278,4 +640,90 @@ func BenchmarkPathVerification(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		VerifyPath(thash, param, root, 128, leafData[128], path)
 	}
-}
\ No newline at end of file
+}
+
+// Test that VerifyPaths returns verdicts aligned with its inputs
+func TestVerifyPaths(t *testing.T) {
+	thash := tweak_hash.NewSHA3TweakableHash(24, 24)
+	param := thash.RandParameter(rand.Reader)
+	
+	const numLeaves = 32
+	leafData := make([][]th.Domain, numLeaves)
+	leafHashes := make([]th.Domain, numLeaves)
+	for i := range leafData {
+		leafData[i] = []th.Domain{thash.RandDomain(rand.Reader)}
+		leafHashes[i] = thash.Apply(param, thash.TreeTweak(0, uint32(i)), leafData[i])
+	}
+	tree := NewHashTree(rand.Reader, thash, 5, 0, param, leafHashes)
+	
+	items := make([]PathItem, numLeaves)
+	want := make([]bool, numLeaves)
+	for i := range items {
+		items[i] = PathItem{Epoch: uint32(i), Leaf: leafData[i], Opening: tree.Path(uint32(i))}
+		want[i] = true
+		switch i % 3 {
+		case 1:
+			// Wrong epoch for this leaf
+			items[i].Epoch ^= 1
+			want[i] = false
+		case 2:
+			items[i].Leaf = []th.Domain{thash.RandDomain(rand.Reader)}
+			want[i] = false
+		}
+	}
+	
+	got := VerifyPaths(thash, param, tree.Root(), items)
+	if len(got) != len(items) {
+		t.Fatalf("Got %d verdicts for %d items", len(got), len(items))
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("Item %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+	
+	if len(VerifyPaths(thash, param, tree.Root(), nil)) != 0 {
+		t.Fatal("Expected no verdicts for no items")
+	}
+}
+
+// Test that folding the co-path by Directions reproduces the root
+func TestOpeningDirections(t *testing.T) {
+	thash := tweak_hash.NewSHA3TweakableHash(24, 24)
+	param := thash.RandParameter(rand.Reader)
+	
+	leafData := make([][]th.Domain, 12)
+	leafHashes := make([]th.Domain, len(leafData))
+	for i := range leafData {
+		leafData[i] = []th.Domain{thash.RandDomain(rand.Reader)}
+		leafHashes[i] = thash.Apply(param, thash.TreeTweak(0, uint32(i+3)), leafData[i])
+	}
+	tree := NewHashTree(rand.Reader, thash, 4, 3, param, leafHashes)
+	
+	for i := range leafData {
+		epoch := uint32(i + 3)
+		opening := tree.Path(epoch)
+		dirs := opening.Directions(epoch)
+		if len(dirs) != len(opening.CoPath) {
+			t.Fatalf("Got %d directions for %d levels", len(dirs), len(opening.CoPath))
+		}
+		
+		current := leafHashes[i]
+		pos := epoch
+		for level, isLeft := range dirs {
+			children := []th.Domain{opening.CoPath[level], current}
+			if isLeft {
+				children = []th.Domain{current, opening.CoPath[level]}
+			}
+			pos >>= 1
+			current = thash.Apply(param, thash.TreeTweak(uint8(level+1), pos), children)
+		}
+		
+		if !bytes.Equal(current, tree.Root()) {
+			t.Fatalf("Epoch %d: root from directions differs", epoch)
+		}
+		if !VerifyPath(thash, param, tree.Root(), epoch, leafData[i], opening) {
+			t.Fatalf("Epoch %d: VerifyPath rejected the opening", epoch)
+		}
+	}
+}
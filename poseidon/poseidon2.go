@@ -65,4 +65,35 @@ func (p *Poseidon2) PermuteNew(state []Element) []Element {
 // Width returns the permutation width
 func (p *Poseidon2) Width() int {
 	return p.width
-}
\ No newline at end of file
+}
+
+// Sponge absorbs input into a state whose last len(capacity) elements are
+// initialized to capacity, adding rate = Width()-len(capacity) elements at a
+// time and permuting after each block (a short final block is added as is,
+// without padding). It returns the first outLen elements of the final state;
+// outLen may be up to Width() to obtain the whole state.
+func (p *Poseidon2) Sponge(capacity, input []Element, outLen int) []Element {
+	rate := p.width - len(capacity)
+	if rate <= 0 {
+		panic("capacity must be smaller than the permutation width")
+	}
+	if outLen > p.width {
+		panic("sponge output longer than the permutation width")
+	}
+	
+	// Initialize state
+	state := make([]Element, p.width)
+	copy(state[rate:], capacity)
+	
+	// Absorb phase
+	for i := 0; i < len(input); i += rate {
+		end := min(i+rate, len(input))
+		for j := 0; j < end-i; j++ {
+			state[j].Add(&state[j], &input[i+j])
+		}
+		p.Permute(state)
+	}
+	
+	// Squeeze phase
+	return state[:outLen:outLen]
+}
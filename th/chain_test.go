@@ -188,4 +188,89 @@ func TestChainVariousLengths(t *testing.T) {
 			t.Fatalf("Chain with %d steps should modify input", length)
 		}
 	}
-}
\ No newline at end of file
+}
+
+// positionRecorder records the chain positions it is asked to tweak
+type positionRecorder struct {
+	mockTweakableHash
+	positions []int
+}
+
+func (r *positionRecorder) ChainTweak(epoch uint32, chainIndex uint8, posInChain uint8) Tweak {
+	r.positions = append(r.positions, int(posInChain))
+	return r.mockTweakableHash.ChainTweak(epoch, chainIndex, posInChain)
+}
+
+// Test that walking a base-256 chain its full length tweaks every position
+// 1..255 exactly once, and that walking past the end panics instead of
+// wrapping the position
+func TestChainBase256FullLength(t *testing.T) {
+	th := &positionRecorder{mockTweakableHash: mockTweakableHash{paramLen: 16, hashLen: 24}}
+	parameter := th.RandParameter(rand.Reader)
+	start := th.RandDomain(rand.Reader)
+	
+	end := Chain(th, parameter, 3, 1, 0, 255, start)
+	if len(th.positions) != 255 {
+		t.Fatalf("Expected 255 steps, got %d", len(th.positions))
+	}
+	for i, pos := range th.positions {
+		if pos != i+1 {
+			t.Fatalf("Step %d used position %d, expected %d", i, pos, i+1)
+		}
+	}
+	
+	// The last step of the walk is the step from 254 to 255
+	mid := Chain(th, parameter, 3, 1, 0, 254, start)
+	if !bytes.Equal(Chain(th, parameter, 3, 1, 254, 1, mid), end) {
+		t.Fatal("Split walk differs from full walk")
+	}
+	
+	for _, walk := range [][2]int{{0, 256}, {255, 1}, {200, 56}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("Walk of %d steps from %d did not panic", walk[1], walk[0])
+				}
+			}()
+			Chain(th, parameter, 3, 1, uint8(walk[0]), walk[1], start)
+		}()
+	}
+}
+
+// Test the ApplyInto fallback for hashes without IntoApplier and its
+// length check
+func TestApplyIntoFallback(t *testing.T) {
+	mock := &mockTweakableHash{paramLen: 16, hashLen: 24}
+	param := mock.RandParameter(rand.Reader)
+	tweak := mock.TreeTweak(1, 2)
+	message := []Domain{mock.RandDomain(rand.Reader)}
+	
+	dst := make([]byte, 24)
+	ApplyInto(mock, dst, param, tweak, message)
+	if !bytes.Equal(dst, mock.Apply(param, tweak, message)) {
+		t.Fatal("Fallback ApplyInto differs from Apply")
+	}
+	
+	defer func() {
+		if recover() == nil {
+			t.Fatal("ApplyInto accepted a short buffer")
+		}
+	}()
+	ApplyInto(mock, make([]byte, 23), param, tweak, message)
+}
+
+// Test that ChainInto writes what Chain returns, for odd, even and zero
+// step counts, into a reused buffer
+func TestChainIntoMatchesChain(t *testing.T) {
+	th := &mockTweakableHash{paramLen: 16, hashLen: 24}
+	parameter := th.RandParameter(rand.Reader)
+	start := th.RandDomain(rand.Reader)
+	dst := make(Domain, th.OutputLen())
+	
+	for _, steps := range []int{0, 1, 2, 7, 16, 255} {
+		ChainInto(dst, th, parameter, 3, 1, 0, steps, start)
+		if !bytes.Equal(dst, Chain(th, parameter, 3, 1, 0, steps, start)) {
+			t.Fatalf("ChainInto differs from Chain for %d steps", steps)
+		}
+	}
+}
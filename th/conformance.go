@@ -0,0 +1,27 @@
+package th
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// domainLengthSamples is the number of RandDomain outputs CheckDomainLength inspects
+const domainLengthSamples = 8
+
+// ErrDomainLength indicates a RandDomain output whose length is not OutputLen
+var ErrDomainLength = errors.New("RandDomain length does not match OutputLen")
+
+// CheckDomainLength checks that h.RandDomain returns OutputLen bytes over
+// several samples. Merkle trees pad layers with RandDomain, so a mismatch
+// would silently produce wrong-length padding nodes. Custom TweakableHash
+// implementations should pass this check.
+func CheckDomainLength(h TweakableHash, rng io.Reader) error {
+	want := h.OutputLen()
+	for i := 0; i < domainLengthSamples; i++ {
+		if got := len(h.RandDomain(rng)); got != want {
+			return fmt.Errorf("%w: sample %d has %d bytes, OutputLen is %d", ErrDomainLength, i, got, want)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package th
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+// shortDomainHash returns padding one byte shorter than its OutputLen
+type shortDomainHash struct {
+	mockTweakableHash
+}
+
+func (m *shortDomainHash) RandDomain(rng io.Reader) Domain {
+	return m.mockTweakableHash.RandDomain(rng)[1:]
+}
+
+// Test that CheckDomainLength accepts a conforming hash and rejects a mismatched one
+func TestCheckDomainLength(t *testing.T) {
+	if err := CheckDomainLength(&mockTweakableHash{paramLen: 16, hashLen: 24}, rand.Reader); err != nil {
+		t.Fatalf("Conforming hash rejected: %v", err)
+	}
+	
+	bad := &shortDomainHash{mockTweakableHash{paramLen: 16, hashLen: 24}}
+	if err := CheckDomainLength(bad, rand.Reader); !errors.Is(err, ErrDomainLength) {
+		t.Fatalf("Expected ErrDomainLength, got %v", err)
+	}
+}
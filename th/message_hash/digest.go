@@ -0,0 +1,65 @@
+package message_hash
+
+import (
+	"errors"
+	"fmt"
+	
+	"github.com/aerius-labs/hash-sig-go/internal/bitutil"
+	"github.com/aerius-labs/hash-sig-go/th"
+	"github.com/aerius-labs/hash-sig-go/tweak"
+)
+
+// digest256Len is the length in bytes of the 256-bit digests used by the
+// byte-oriented message hashes (SHA3-256, SHA-256)
+const digest256Len = 32
+
+// validateDigestChunking panics unless dimension chunks of chunkSize bits
+// can be cut from a 256-bit digest of the named hash
+func validateDigestChunking(dimension, chunkSize int, hashName string) {
+	if err := checkDigestChunking(dimension, chunkSize, hashName); err != nil {
+		panic(err.Error())
+	}
+}
+
+// checkDigestChunking checks that dimension chunks of chunkSize bits can be
+// cut from a 256-bit digest of the named hash
+func checkDigestChunking(dimension, chunkSize int, hashName string) error {
+	if chunkSize < 1 || chunkSize > 8 {
+		return errors.New("chunk size must be between 1 and 8")
+	}
+	if dimension > 256 {
+		return errors.New("dimension must be <= 256")
+	}
+	if dimension*chunkSize > 8*digest256Len {
+		return fmt.Errorf("dimension * chunk size exceeds the 256 bits of %s output", hashName)
+	}
+	return nil
+}
+
+// digestToChunks splits the first dimension * chunkSize bits of a digest
+// into w-bit chunks. Bits are read LSB-first, which matches BytesToChunks
+// when w divides 8 and packs chunks across byte boundaries otherwise.
+func digestToChunks(digest []byte, dimension, chunkSize int) []uint8 {
+	wide, err := bitutil.ExtractWBitChunks(digest, chunkSize, dimension)
+	if err != nil {
+		panic("failed to split into chunks: " + err.Error())
+	}
+	
+	chunks := make([]uint8, dimension)
+	for i, c := range wide {
+		chunks[i] = uint8(c)
+	}
+	
+	return chunks
+}
+
+// messageSuffix serialises P||T||M, the randomness-independent part of the
+// R||P||T||M input hashed by the byte-oriented message hashes
+func messageSuffix(params th.Params, msg []byte, epoch uint32) []byte {
+	msgTweak := tweak.MessageTweak(epoch)
+	suffix := make([]byte, 0, len(params)+len(msgTweak)+len(msg))
+	suffix = append(suffix, params...)
+	suffix = append(suffix, msgTweak...)
+	suffix = append(suffix, msg...)
+	return suffix
+}
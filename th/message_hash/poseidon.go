@@ -1,9 +1,12 @@
 package message_hash
 
 import (
+	"fmt"
 	"math/big"
 	
 	"github.com/consensys/gnark-crypto/field/babybear"
+	"github.com/aerius-labs/hash-sig-go/field"
+	"github.com/aerius-labs/hash-sig-go/encoding"
 	"github.com/aerius-labs/hash-sig-go/poseidon"
 	"github.com/aerius-labs/hash-sig-go/th"
 )
@@ -17,11 +20,29 @@ type PoseidonMessageHash struct {
 	base         int
 	tweakLenFE   int
 	msgLenFE     int // Message length in field elements
+	msgLen       int // Message length in bytes
+	maxMsgLen    int // Longest message msgLenFE field elements hold
+	
+	// maxResidual bounds what is left of the output integer after
+	// decodeToChunks takes numChunks chunks from it
+	maxResidual *big.Int
 }
 
-// NewPoseidonMessageHash creates a new Poseidon message hash
+// NewPoseidonMessageHash creates a new Poseidon message hash for
+// th.MessageLength-byte messages
 func NewPoseidonMessageHash(
 	parameterLen, randLen, msgHashLenFE, numChunks, base, tweakLenFE, msgLenFE int,
+) *PoseidonMessageHash {
+	return NewPoseidonMessageHashWithMessageLen(
+		parameterLen, randLen, msgHashLenFE, numChunks, base, tweakLenFE, msgLenFE, th.MessageLength,
+	)
+}
+
+// NewPoseidonMessageHashWithMessageLen creates a Poseidon message hash for
+// msgLen-byte messages, which must fit in msgLenFE field elements (see
+// MaxMessageLen and Validate)
+func NewPoseidonMessageHashWithMessageLen(
+	parameterLen, randLen, msgHashLenFE, numChunks, base, tweakLenFE, msgLenFE, msgLen int,
 ) *PoseidonMessageHash {
 	return &PoseidonMessageHash{
 		parameterLen: parameterLen,
@@ -31,35 +52,71 @@ func NewPoseidonMessageHash(
 		base:         base,
 		tweakLenFE:   tweakLenFE,
 		msgLenFE:     msgLenFE,
+		msgLen:       msgLen,
+		maxMsgLen:    maxMessageLen(msgLenFE),
+		maxResidual:  maxDecodeResidual(msgHashLenFE, numChunks, base),
 	}
 }
 
+// maxDecodeResidual returns floor((p^msgHashLenFE - 1) / base^numChunks),
+// the largest residual decodeToChunks can leave for canonical field
+// elements. It is zero exactly when the chunks use all of the output.
+func maxDecodeResidual(msgHashLenFE, numChunks, base int) *big.Int {
+	if base < 2 || numChunks < 0 || msgHashLenFE < 0 {
+		return new(big.Int)
+	}
+	maxOutput := new(big.Int).Exp(field.PBigInt(), big.NewInt(int64(msgHashLenFE)), nil)
+	maxOutput.Sub(maxOutput, big.NewInt(1))
+	chunkSpace := new(big.Int).Exp(big.NewInt(int64(base)), big.NewInt(int64(numChunks)), nil)
+	return maxOutput.Div(maxOutput, chunkSpace)
+}
+
 // Hash hashes a message with parameters, randomness, and epoch
 func (h *PoseidonMessageHash) Hash(params th.Params, msg []byte, rand []byte, epoch uint32) []byte {
-	// Convert message to field elements (32 bytes -> 8 field elements of 4 bytes each)
-	msgFields := bytesToFieldElements(msg, h.msgLenFE)
-	
-	// Convert randomness to field elements
-	randFields := bytesToFieldElements(rand, h.randLen)
-	
-	// Convert parameters to field elements
-	paramFields := bytesToFieldElements(params, h.parameterLen)
+	return h.HashPrepared(h.PrepareMessage(params, msg, epoch), rand)
+}
+
+// poseidonPreparedMessage is the prepared form of Poseidon message-hash inputs
+type poseidonPreparedMessage struct {
+	perm      *poseidon.Poseidon2
+	capacity  []babybear.Element
+	msgFields []babybear.Element
+}
+
+// PrepareMessage converts the parameter, epoch and message to field
+// elements and instantiates the permutation once, for reuse across retries.
+// It panics if msg is longer than MaxMessageLen, rather than dropping the
+// bytes that do not fit.
+func (h *PoseidonMessageHash) PrepareMessage(params th.Params, msg []byte, epoch uint32) encoding.PreparedMessage {
+	checkMessageLen(msg, h.maxMsgLen)
 	
-	// Create epoch tweak as field elements
-	epochFields := h.epochToFieldElements(epoch)
+	// Compute capacity value for sponge: parameters || epoch tweak
+	capacity := make([]babybear.Element, 0, h.parameterLen+h.tweakLenFE)
+	capacity = append(capacity, field.BytesToElementsBaseP(params, h.parameterLen)...)
+	capacity = append(capacity, h.epochToFieldElements(epoch)...)
 	
-	// Compute capacity value for sponge
-	capacity := make([]babybear.Element, 0)
-	capacity = append(capacity, paramFields...)
-	capacity = append(capacity, epochFields...)
+	return &poseidonPreparedMessage{
+		perm:     poseidon.NewPoseidon2_24(),
+		capacity: capacity,
+		// Convert message to field elements, as base-p digits
+		msgFields: field.BytesToElementsBaseP(msg, h.msgLenFE),
+	}
+}
+
+// HashPrepared computes the message hash for prepared inputs
+func (h *PoseidonMessageHash) HashPrepared(prepared encoding.PreparedMessage, rand []byte) []byte {
+	pp, ok := prepared.(*poseidonPreparedMessage)
+	if !ok {
+		panic("prepared message was not produced by PoseidonMessageHash")
+	}
 	
 	// Input is randomness || message
-	input := make([]babybear.Element, 0)
-	input = append(input, randFields...)
-	input = append(input, msgFields...)
+	input := make([]babybear.Element, 0, h.randLen+len(pp.msgFields))
+	input = append(input, field.BytesToElementsBaseP(rand, h.randLen)...)
+	input = append(input, pp.msgFields...)
 	
 	// Apply Poseidon sponge
-	result := h.poseidonSponge(capacity, input)
+	result := pp.perm.Sponge(pp.capacity, input, h.msgHashLenFE)
 	
 	// Decode field elements to chunks
 	return h.decodeToChunks(result[:h.msgHashLenFE])
@@ -98,125 +155,106 @@ func (h *PoseidonMessageHash) ChunkSize() int {
 	return chunkSize
 }
 
-// epochToFieldElements converts epoch to field elements with message hash separator
-func (h *PoseidonMessageHash) epochToFieldElements(epoch uint32) []babybear.Element {
-	// Pack as: (epoch << 8) | separator
-	val := uint64(epoch)<<8 | 0x02 // MESSAGE_HASH separator
-	
-	// Decompose in base p
-	result := make([]babybear.Element, h.tweakLenFE)
-	for i := 0; i < h.tweakLenFE; i++ {
-		var e babybear.Element
-		e.SetUint64(val % 2013265921)
-		result[i] = e
-		val /= 2013265921
-	}
-	
-	return result
+// MessageLen returns the configured message length in bytes. Messages
+// must have exactly this length: shorter ones would pack like longer ones
+// with trailing zero bytes.
+func (h *PoseidonMessageHash) MessageLen() int {
+	return h.msgLen
 }
 
-// poseidonSponge applies the sponge construction
-func (h *PoseidonMessageHash) poseidonSponge(capacity []babybear.Element, input []babybear.Element) []babybear.Element {
-	perm := poseidon.NewPoseidon2_24()
-	width := 24
-	rate := width - len(capacity)
-	
-	// Initialize state
-	state := make([]babybear.Element, width)
-	copy(state[rate:], capacity)
-	
-	// Absorb phase
-	for i := 0; i < len(input); i += rate {
-		end := i + rate
-		if end > len(input) {
-			end = len(input)
-		}
-		
-		// Add input to state
-		for j := 0; j < end-i; j++ {
-			var sum babybear.Element
-			sum.Add(&state[j], &input[i+j])
-			state[j] = sum
-		}
-		
-		// Apply permutation
-		perm.Permute(state)
+// MaxMessageLen returns the longest message, in bytes, that msgLenFE field
+// elements hold without loss
+func (h *PoseidonMessageHash) MaxMessageLen() int {
+	return h.maxMsgLen
+}
+
+// Validate checks that the msgHashLenFE output field elements carry enough
+// entropy for numChunks base-BASE chunks, BASE^numChunks <= p^msgHashLenFE,
+// so every chunk decoded from the output covers its full range
+func (h *PoseidonMessageHash) Validate() error {
+	if h.base < 2 || h.base > 256 {
+		return fmt.Errorf("base %d not in [2, 256]", h.base)
 	}
-	
-	// Squeeze phase
-	output := make([]babybear.Element, h.msgHashLenFE)
-	copy(output, state[:h.msgHashLenFE])
-	
-	return output
+	if h.numChunks < 1 {
+		return fmt.Errorf("number of chunks %d must be positive", h.numChunks)
+	}
+	if err := checkMessageFits(h.msgLen, h.msgLenFE); err != nil {
+		return err
+	}
+	chunkSpace := new(big.Int).Exp(big.NewInt(int64(h.base)), big.NewInt(int64(h.numChunks)), nil)
+	outputSpace := new(big.Int).Exp(field.PBigInt(), big.NewInt(int64(h.msgHashLenFE)), nil)
+	if chunkSpace.Cmp(outputSpace) > 0 {
+		return fmt.Errorf("%d base-%d chunks take 2^%d values, but %d field elements reach only about 2^%d",
+			h.numChunks, h.base, chunkSpace.BitLen()-1, h.msgHashLenFE, outputSpace.BitLen()-1)
+	}
+	return nil
 }
 
-// bytesToFieldElements converts bytes to field elements using base-p decomposition
-func bytesToFieldElements(data []byte, numElements int) []babybear.Element {
-	// Interpret data as a little-endian integer
-	acc := new(big.Int).SetBytes(reverseBytes(data))
-	
-	// Perform base-p decomposition
-	p := big.NewInt(2013265921) // BabyBear prime
-	result := make([]babybear.Element, numElements)
-	
-	for i := 0; i < numElements; i++ {
-		digit := new(big.Int).Mod(acc, p)
-		var e babybear.Element
-		e.SetBigInt(digit)
-		result[i] = e
-		acc.Div(acc, p)
+// maxMessageLen returns the largest n with 256^n <= p^msgLenFE, the
+// longest message msgLenFE field elements hold without loss
+func maxMessageLen(msgLenFE int) int {
+	if msgLenFE <= 0 {
+		return 0
 	}
-	
-	return result
+	fieldSpace := new(big.Int).Exp(field.PBigInt(), big.NewInt(int64(msgLenFE)), nil)
+	return (fieldSpace.BitLen() - 1) / 8
 }
 
-// fieldElementsToBytes converts field elements back to bytes
-func fieldElementsToBytes(elements []babybear.Element) []byte {
-	// Reconstruct the big integer from base-p digits
-	acc := new(big.Int)
-	p := big.NewInt(2013265921)
-	
-	// Process in reverse order to reconstruct correctly
-	for i := len(elements) - 1; i >= 0; i-- {
-		digit := elements[i].BigInt(new(big.Int))
-		acc.Mul(acc, p)
-		acc.Add(acc, digit)
+// checkMessageFits checks that msgLenFE field elements hold every
+// msgLen-byte message
+func checkMessageFits(msgLen, msgLenFE int) error {
+	if msgLen < 0 {
+		return fmt.Errorf("negative message length %d", msgLen)
 	}
-	
-	// Convert to bytes (big-endian) then reverse for little-endian
-	bytes := acc.Bytes()
-	
-	// Calculate expected byte length based on field elements
-	// Each field element can store roughly 31 bits, so we estimate
-	expectedLen := (len(elements) * 31) / 8
-	if expectedLen < 32 {
-		expectedLen = 32 // Minimum 32 bytes for message
+	if msgLen > maxMessageLen(msgLenFE) {
+		return fmt.Errorf("%d field elements cannot hold a %d-byte message", msgLenFE, msgLen)
 	}
-	
-	// Pad with zeros if needed
-	if len(bytes) < expectedLen {
-		padded := make([]byte, expectedLen)
-		copy(padded[expectedLen-len(bytes):], bytes)
-		bytes = padded
+	return nil
+}
+
+// checkMessageLen panics if msg is longer than maxMsgLen bytes, which its
+// packing into field elements would silently truncate
+func checkMessageLen(msg []byte, maxMsgLen int) {
+	if len(msg) > maxMsgLen {
+		panic(fmt.Sprintf("message of %d bytes exceeds the %d bytes the message field elements hold", len(msg), maxMsgLen))
 	}
-	
-	return reverseBytes(bytes)
 }
 
-// reverseBytes reverses a byte slice
-func reverseBytes(b []byte) []byte {
-	result := make([]byte, len(b))
-	for i := range b {
-		result[i] = b[len(b)-1-i]
+// epochToFieldElements converts epoch to field elements with message hash separator
+func (h *PoseidonMessageHash) epochToFieldElements(epoch uint32) []babybear.Element {
+	// Pack as: (epoch << 8) | separator
+	val := uint64(epoch)<<8 | 0x02 // MESSAGE_HASH separator
+	
+	// Decompose in base p
+	result := make([]babybear.Element, h.tweakLenFE)
+	for i := 0; i < h.tweakLenFE; i++ {
+		var e babybear.Element
+		e.SetUint64(val % field.P)
+		result[i] = e
+		val /= field.P
 	}
+	
 	return result
 }
 
-// decodeToChunks decodes field elements to chunks in base-BASE
+// decodeToChunks decodes field elements to chunks in base-BASE. It panics
+// if the integer left after the last chunk exceeds maxResidual, which
+// would mean the input was not msgHashLenFE canonical field elements.
 func (h *PoseidonMessageHash) decodeToChunks(fieldElements []babybear.Element) []byte {
+	chunks, residual := h.decodeToChunksResidual(fieldElements)
+	if residual.Cmp(h.maxResidual) > 0 {
+		panic("message hash output exceeds the configured output length")
+	}
+	return chunks
+}
+
+// decodeToChunksResidual decodes field elements to chunks in base-BASE and
+// returns the chunks and the integer left over after taking them. The
+// residual is the output entropy the chunks do not use.
+func (h *PoseidonMessageHash) decodeToChunksResidual(fieldElements []babybear.Element) ([]byte, *big.Int) {
 	// Combine field elements into one big integer
 	acc := new(big.Int)
-	p := big.NewInt(2013265921) // BabyBear prime
+	p := field.PBigInt()
 	
 	for _, fe := range fieldElements {
 		feBig := fe.BigInt(new(big.Int))
@@ -227,13 +265,12 @@ func (h *PoseidonMessageHash) decodeToChunks(fieldElements []babybear.Element) [
 	// Convert to base-BASE chunks
 	base := big.NewInt(int64(h.base))
 	chunks := make([]byte, h.numChunks)
+	chunk := new(big.Int)
 	
 	for i := 0; i < h.numChunks; i++ {
-		chunk := new(big.Int).Mod(acc, base)
+		acc.DivMod(acc, base, chunk)
 		chunks[i] = byte(chunk.Int64())
-		acc.Div(acc, base)
 	}
 	
-	return chunks
+	return chunks, acc
 }
-
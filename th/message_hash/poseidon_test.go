@@ -5,51 +5,53 @@ import (
 	"crypto/rand"
 	"math/big"
 	"testing"
-	
-	"github.com/consensys/gnark-crypto/field/babybear"
+
+	"github.com/aerius-labs/hash-sig-go/encoding"
+	"github.com/aerius-labs/hash-sig-go/field"
 	"github.com/aerius-labs/hash-sig-go/th"
+	"github.com/consensys/gnark-crypto/field/babybear"
 )
 
 // Test basic Poseidon message hash functionality
 func TestPoseidonMessageHashApply(t *testing.T) {
 	// Test configuration matching Rust's PoseidonMessageHash445
 	mh := NewPoseidonMessageHash(
-		4, // parameterLen
-		4, // randLen
-		5, // msgHashLenFE
+		4,  // parameterLen
+		4,  // randLen
+		5,  // msgHashLenFE
 		32, // numChunks
 		16, // base
-		2, // tweakLenFE
-		9, // msgLenFE
+		2,  // tweakLenFE
+		9,  // msgLenFE
 	)
-	
+
 	// Generate random inputs
 	params := make(th.Params, 16) // 4 field elements * 4 bytes
 	rand.Read(params)
-	
+
 	message := make([]byte, 32)
 	rand.Read(message)
-	
+
 	randomness := make([]byte, 16) // 4 field elements * 4 bytes
 	rand.Read(randomness)
-	
+
 	epoch := uint32(13)
-	
+
 	// Hash the message
 	result := mh.Hash(params, message, randomness, epoch)
-	
+
 	// Verify output length
 	expectedLen := mh.OutputLen()
 	if len(result) != expectedLen {
 		t.Errorf("Expected output length %d, got %d", expectedLen, len(result))
 	}
-	
+
 	// Test consistency - same inputs should give same output
 	result2 := mh.Hash(params, message, randomness, epoch)
 	if !bytes.Equal(result, result2) {
 		t.Error("Same inputs produced different results")
 	}
-	
+
 	// Different epoch should give different result
 	result3 := mh.Hash(params, message, randomness, epoch+1)
 	if bytes.Equal(result, result3) {
@@ -60,7 +62,7 @@ func TestPoseidonMessageHashApply(t *testing.T) {
 // Test epoch encoding
 func TestEncodeEpoch(t *testing.T) {
 	mh := NewPoseidonMessageHash(4, 4, 5, 32, 16, 2, 9)
-	
+
 	testCases := []struct {
 		name  string
 		epoch uint32
@@ -71,17 +73,17 @@ func TestEncodeEpoch(t *testing.T) {
 		{"Large", 0x12345678},
 		{"Max", 0xFFFFFFFF},
 	}
-	
+
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			// Compute expected encoding
 			sep := uint64(0x02) // MESSAGE_HASH separator
 			epochBigint := new(big.Int).SetUint64(uint64(tc.epoch)<<8 | sep)
-			
+
 			// Convert to field elements (2 elements for tweakLenFE=2)
-			p := new(big.Int).SetUint64(2013265921)
+			p := field.PBigInt()
 			expected := make([]babybear.Element, 2)
-			
+
 			remainder := new(big.Int).Set(epochBigint)
 			for i := 0; i < 2; i++ {
 				var e babybear.Element
@@ -90,10 +92,10 @@ func TestEncodeEpoch(t *testing.T) {
 				expected[i] = e
 				remainder.Div(remainder, p)
 			}
-			
+
 			// Get actual encoding
 			actual := mh.epochToFieldElements(tc.epoch)
-			
+
 			// Compare
 			for i := 0; i < len(expected); i++ {
 				if !actual[i].Equal(&expected[i]) {
@@ -107,23 +109,23 @@ func TestEncodeEpoch(t *testing.T) {
 // Test epoch encoding injectivity
 func TestEpochEncodingInjective(t *testing.T) {
 	mh := NewPoseidonMessageHash(4, 4, 5, 32, 16, 2, 9)
-	
+
 	seen := make(map[string]struct{})
-	
+
 	// Test many random epochs
 	for i := 0; i < 10000; i++ {
 		b := make([]byte, 4)
 		rand.Read(b)
 		epoch := uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
-		
+
 		fields := mh.epochToFieldElements(epoch)
-		
+
 		// Convert to string for map key
 		key := ""
 		for _, f := range fields {
 			key += f.String() + ","
 		}
-		
+
 		if _, exists := seen[key]; exists {
 			// Check if it's actually the same epoch (ok) or a collision (bad)
 			if key != "" { // Only fail on actual collision
@@ -154,28 +156,21 @@ func TestEncodeMessage(t *testing.T) {
 			return msg
 		}()},
 	}
-	
+
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			// Convert message to field elements
-			fields := bytesToFieldElements(tc.message, 9) // 9 field elements for 32 bytes
-			
+			fields := field.BytesToElementsBaseP(tc.message, 9) // 9 field elements for 32 bytes
+
 			// Verify we got the right number of field elements
 			if len(fields) != 9 {
 				t.Errorf("Expected 9 field elements, got %d", len(fields))
 			}
-			
-			// Convert back and check if we can recover (approximately) the original
-			recovered := fieldElementsToBytes(fields)
-			
-			// We should have at least 32 bytes
-			if len(recovered) < 32 {
-				t.Errorf("Recovered message too short: %d bytes", len(recovered))
-			}
-			
-			// First 32 bytes should match
-			if !bytes.Equal(tc.message, recovered[:32]) {
-				t.Error("Message encoding/decoding mismatch")
+
+			// Convert back; the encoding is exact, including leading zeros
+			recovered := field.ElementsToBytesBaseP(fields, len(tc.message))
+			if !bytes.Equal(tc.message, recovered) {
+				t.Errorf("Message encoding/decoding mismatch: %x", recovered)
 			}
 		})
 	}
@@ -184,14 +179,14 @@ func TestEncodeMessage(t *testing.T) {
 // Test randomness generation
 func TestRandNotAllSame(t *testing.T) {
 	mh := NewPoseidonMessageHash(4, 4, 5, 32, 16, 2, 9)
-	
+
 	allSameCount := 0
 	trials := 10
-	
+
 	for i := 0; i < trials; i++ {
 		randBytes := make([]byte, mh.RandLen())
 		rand.Read(randBytes)
-		
+
 		// Check if all bytes are identical
 		if len(randBytes) > 0 {
 			first := randBytes[0]
@@ -207,7 +202,7 @@ func TestRandNotAllSame(t *testing.T) {
 			}
 		}
 	}
-	
+
 	if allSameCount == trials {
 		t.Error("All random values had identical bytes")
 	}
@@ -217,36 +212,207 @@ func TestRandNotAllSame(t *testing.T) {
 func TestPoseidonMessageHashW1(t *testing.T) {
 	// Configuration for w=1
 	mh := NewPoseidonMessageHash(
-		5, // parameterLen
-		5, // randLen
-		5, // msgHashLenFE
+		5,   // parameterLen
+		5,   // randLen
+		5,   // msgHashLenFE
 		155, // numChunks for w=1
-		2, // base for w=1
-		2, // tweakLenFE
-		9, // msgLenFE
+		2,   // base for w=1
+		2,   // tweakLenFE
+		9,   // msgLenFE
 	)
-	
+
 	// Generate random inputs
 	params := make(th.Params, 20) // 5 field elements * 4 bytes
 	rand.Read(params)
-	
+
 	message := make([]byte, 32)
 	rand.Read(message)
-	
+
 	randomness := make([]byte, 20) // 5 field elements * 4 bytes
 	rand.Read(randomness)
-	
+
 	epoch := uint32(13)
-	
+
 	// Hash the message
 	result := mh.Hash(params, message, randomness, epoch)
-	
+
 	// Verify output length
 	expectedLen := mh.OutputLen()
 	if len(result) != expectedLen {
 		t.Errorf("Expected output length %d, got %d", expectedLen, len(result))
 	}
-	
+
 	// Should produce 155 chunks for w=1
 	// This would be verified when decoding for actual encoding use
-}
\ No newline at end of file
+}
+
+// Test that one prepared message can be reused across randomness values
+func TestPoseidonHashPreparedMatchesHash(t *testing.T) {
+	mh := NewPoseidonMessageHash(4, 4, 5, 32, 16, 2, 9)
+	topLevel := NewTopLevelPoseidonMessageHash(8, 6, 48, 40, 12, 175, 3, 9, 4, 4)
+
+	params := make(th.Params, 16)
+	rand.Read(params)
+	message := make([]byte, 32)
+	rand.Read(message)
+
+	prepared := mh.PrepareMessage(params, message, 11)
+	preparedTopLevel := topLevel.PrepareMessage(params, message, 11)
+	for i := 0; i < 3; i++ {
+		randomness := make([]byte, 16)
+		rand.Read(randomness)
+
+		if !bytes.Equal(mh.HashPrepared(prepared, randomness), mh.Hash(params, message, randomness, 11)) {
+			t.Fatalf("PoseidonMessageHash.HashPrepared differs from Hash on attempt %d", i)
+		}
+		if !bytes.Equal(topLevel.HashPrepared(preparedTopLevel, randomness), topLevel.Hash(params, message, randomness, 11)) {
+			t.Fatalf("TopLevelPoseidonMessageHash.HashPrepared differs from Hash on attempt %d", i)
+		}
+	}
+}
+
+// Test that Validate accepts the standard configurations and rejects
+// message hashes too short for their chunks
+func TestPoseidonMessageHashValidate(t *testing.T) {
+	configs := []struct {
+		name         string
+		msgHashLenFE int
+		numChunks    int
+		base         int
+		valid        bool
+	}{
+		{"W1", 5, 154, 2, true},
+		{"W2", 5, 77, 4, true},
+		{"W4", 5, 38, 16, true},
+		{"W8", 5, 19, 256, true},
+		{"W256", 9, 32, 256, true},
+		{"W256Short", 5, 32, 256, false},
+		{"W1OneChunkShort", 5, 155, 2, false},
+		{"W4OneChunkShort", 5, 39, 16, false},
+		{"W1TooManyChunks", 5, 160, 2, false},
+		{"BadBase", 5, 10, 1, false},
+	}
+
+	for _, cfg := range configs {
+		t.Run(cfg.name, func(t *testing.T) {
+			mh := NewPoseidonMessageHash(5, 5, cfg.msgHashLenFE, cfg.numChunks, cfg.base, 2, 9)
+			if err := mh.Validate(); (err == nil) != cfg.valid {
+				t.Fatalf("valid=%v, got error %v", cfg.valid, err)
+			}
+		})
+	}
+
+	// 8 field elements hold about 248 bits, less than a 32-byte message
+	if err := NewPoseidonMessageHash(5, 5, 5, 38, 16, 2, 8).Validate(); err == nil {
+		t.Fatal("Accepted a message length of 8 field elements")
+	}
+}
+
+// Test that decoding leaves a residual within the bound for the output
+// length, and none for configurations whose chunks cover the whole output
+func TestDecodeToChunksResidual(t *testing.T) {
+	configs := []struct {
+		name         string
+		msgHashLenFE int
+		numChunks    int
+		base         int
+		lossless     bool
+	}{
+		{"W1", 5, 154, 2, false},
+		{"W4", 5, 38, 16, false},
+		{"W256", 9, 32, 256, false},
+		{"W1Full", 5, 155, 2, true},
+		{"W4Full", 5, 39, 16, true},
+	}
+
+	var maxElement babybear.Element
+	maxElement.SetUint64(field.P - 1)
+
+	for _, cfg := range configs {
+		t.Run(cfg.name, func(t *testing.T) {
+			mh := NewPoseidonMessageHash(5, 5, cfg.msgHashLenFE, cfg.numChunks, cfg.base, 2, 9)
+			if (mh.maxResidual.Sign() == 0) != cfg.lossless {
+				t.Fatalf("lossless=%v, got residual bound %v", cfg.lossless, mh.maxResidual)
+			}
+
+			inputs := make([][]babybear.Element, 0, 9)
+			largest := make([]babybear.Element, cfg.msgHashLenFE)
+			for i := range largest {
+				largest[i] = maxElement
+			}
+			inputs = append(inputs, largest)
+			for i := 0; i < 8; i++ {
+				random := make([]babybear.Element, cfg.msgHashLenFE)
+				for j := range random {
+					random[j].MustSetRandom()
+				}
+				inputs = append(inputs, random)
+			}
+
+			for _, input := range inputs {
+				chunks, residual := mh.decodeToChunksResidual(input)
+				if residual.Cmp(mh.maxResidual) > 0 {
+					t.Fatalf("Residual %v exceeds bound %v", residual, mh.maxResidual)
+				}
+				for i, c := range chunks {
+					if int(c) >= cfg.base {
+						t.Fatalf("Chunk %d is %d, not below base %d", i, c, cfg.base)
+					}
+				}
+			}
+		})
+	}
+
+	// Feeding more elements than configured overflows the residual bound
+	mh := NewPoseidonMessageHash(5, 5, 5, 38, 16, 2, 9)
+	long := make([]babybear.Element, 6)
+	for i := range long {
+		long[i] = maxElement
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("decodeToChunks accepted 6 field elements for a 5-element output")
+		}
+	}()
+	mh.decodeToChunks(long)
+}
+
+// Test configurable message lengths: MaxMessageLen, validation against it,
+// and rejection of messages that would be truncated
+func TestPoseidonMessageHashMessageLen(t *testing.T) {
+	// p^8 is about 2^247.3 and p^9 about 2^278.2
+	for msgLenFE, want := range map[int]int{8: 30, 9: 34} {
+		mh := NewPoseidonMessageHash(5, 5, 5, 38, 16, 2, msgLenFE)
+		if got := mh.MaxMessageLen(); got != want {
+			t.Fatalf("MaxMessageLen with %d field elements = %d, expected %d", msgLenFE, got, want)
+		}
+	}
+
+	mh := NewPoseidonMessageHashWithMessageLen(5, 5, 5, 38, 16, 2, 9, 34)
+	if err := mh.Validate(); err != nil {
+		t.Fatalf("Rejected a 34-byte message length: %v", err)
+	}
+	if mh.MessageLen() != 34 || encoding.MessageLen(mh) != 34 {
+		t.Fatalf("MessageLen = %d, expected 34", mh.MessageLen())
+	}
+	if err := NewPoseidonMessageHashWithMessageLen(5, 5, 5, 38, 16, 2, 9, 35).Validate(); err == nil {
+		t.Fatal("Accepted a 35-byte message length with 9 field elements")
+	}
+
+	// Every byte of a maximal message reaches the hash
+	params := make([]byte, 20)
+	rho := make([]byte, mh.RandLen())
+	message := bytes.Repeat([]byte{0xFF}, 34)
+	digest := mh.Hash(params, message, rho, 1)
+	message[33] = 0xFE
+	if bytes.Equal(mh.Hash(params, message, rho, 1), digest) {
+		t.Fatal("Changing the last message byte did not change the hash")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Hashed a 35-byte message that 9 field elements cannot hold")
+		}
+	}()
+	mh.Hash(params, append(message, 1), rho, 1)
+}
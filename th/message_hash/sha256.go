@@ -0,0 +1,110 @@
+package message_hash
+
+import (
+	"crypto/sha256"
+	"io"
+	
+	"github.com/aerius-labs/hash-sig-go/encoding"
+	"github.com/aerius-labs/hash-sig-go/th"
+	"github.com/aerius-labs/hash-sig-go/tweak"
+)
+
+// SHA256MessageHash implements message hashing using SHA-256, for interop
+// with systems standardised on SHA-2. It mirrors SHA3MessageHash.
+type SHA256MessageHash struct {
+	parameterLen  int
+	randomnessLen int
+	dimension     int // number of chunks (v or n₀)
+	chunkSize     int // w in bits
+}
+
+// NewSHA256MessageHash creates a new SHA-256-based message hash
+func NewSHA256MessageHash(parameterLen, randomnessLen, dimension, chunkSize int) *SHA256MessageHash {
+	validateDigestChunking(dimension, chunkSize, "SHA-256")
+	return &SHA256MessageHash{
+		parameterLen:  parameterLen,
+		randomnessLen: randomnessLen,
+		dimension:     dimension,
+		chunkSize:     chunkSize,
+	}
+}
+
+// RandRandomness generates randomness for message encoding
+func (s *SHA256MessageHash) RandRandomness(rng io.Reader) []byte {
+	r := make([]byte, s.randomnessLen)
+	if _, err := io.ReadFull(rng, r); err != nil {
+		panic("failed to generate randomness: " + err.Error())
+	}
+	return r
+}
+
+// Apply implements message hashing
+// Returns chunks as uint8 values (each chunk is w bits, stored in a uint8)
+func (s *SHA256MessageHash) Apply(parameter th.Params, epoch uint32, randomness []byte, message []byte) []uint8 {
+	// Compute Thmsg: Truncate_(ℓ·w)_bits(SHA256(R||P||T||M))
+	h := sha256.New()
+	h.Write(randomness)
+	h.Write(parameter)
+	h.Write(tweak.MessageTweak(epoch))
+	h.Write(message)
+	
+	return digestToChunks(h.Sum(nil), s.dimension, s.chunkSize)
+}
+
+// sha256PreparedMessage is the prepared form of SHA-256 message-hash inputs
+type sha256PreparedMessage struct {
+	// suffix is P||T||M, which follows R in the hash input
+	suffix []byte
+}
+
+// PrepareMessage serialises P||T||M once
+func (s *SHA256MessageHash) PrepareMessage(params th.Params, msg []byte, epoch uint32) encoding.PreparedMessage {
+	return &sha256PreparedMessage{suffix: messageSuffix(params, msg, epoch)}
+}
+
+// HashPrepared computes the message hash for prepared inputs
+func (s *SHA256MessageHash) HashPrepared(prepared encoding.PreparedMessage, rand []byte) []byte {
+	sp, ok := prepared.(*sha256PreparedMessage)
+	if !ok {
+		panic("prepared message was not produced by SHA256MessageHash")
+	}
+	h := sha256.New()
+	h.Write(rand)
+	h.Write(sp.suffix)
+	return digestToChunks(h.Sum(nil), s.dimension, s.chunkSize)
+}
+
+// Dimension returns the number of chunks
+func (s *SHA256MessageHash) Dimension() int {
+	return s.dimension
+}
+
+// Base returns 2^w
+func (s *SHA256MessageHash) Base() int {
+	return 1 << s.chunkSize
+}
+
+// ChunkSize returns w
+func (s *SHA256MessageHash) ChunkSize() int {
+	return s.chunkSize
+}
+
+// Validate checks that the chunks fit into the 256-bit SHA-256 digest
+func (s *SHA256MessageHash) Validate() error {
+	return checkDigestChunking(s.dimension, s.chunkSize, "SHA-256")
+}
+
+// Hash implements the MessageHash interface
+func (s *SHA256MessageHash) Hash(params th.Params, msg []byte, rand []byte, epoch uint32) []byte {
+	return s.Apply(params, epoch, rand, msg)
+}
+
+// OutputLen returns the output length in bytes
+func (s *SHA256MessageHash) OutputLen() int {
+	return s.dimension
+}
+
+// RandLen returns the randomness length in bytes
+func (s *SHA256MessageHash) RandLen() int {
+	return s.randomnessLen
+}
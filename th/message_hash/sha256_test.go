@@ -0,0 +1,60 @@
+package message_hash
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"reflect"
+	"testing"
+	
+	"github.com/aerius-labs/hash-sig-go/internal/bitutil"
+	"github.com/aerius-labs/hash-sig-go/tweak"
+)
+
+// Test that SHA-256 message hash chunks SHA256(R||P||T||M) like the SHA3 variant
+func TestSHA256MessageHash(t *testing.T) {
+	mh := NewSHA256MessageHash(24, 24, 48, 4)
+	sha3mh := NewSHA3MessageHash(24, 24, 48, 4)
+	
+	param := make([]byte, 24)
+	rand.Read(param)
+	randomness := mh.RandRandomness(rand.Reader)
+	message := make([]byte, 32)
+	rand.Read(message)
+	
+	chunks := mh.Hash(param, message, randomness, 5)
+	if len(chunks) != 48 {
+		t.Fatalf("Expected 48 chunks, got %d", len(chunks))
+	}
+	
+	digest := sha256.Sum256(append(append(append(append([]byte{}, randomness...), param...), tweak.MessageTweak(5)...), message...))
+	expected, err := bitutil.BytesToChunks(digest[:24], 4)
+	if err != nil {
+		t.Fatalf("BytesToChunks failed: %v", err)
+	}
+	if !reflect.DeepEqual(chunks, expected) {
+		t.Fatal("Chunks do not match the truncated SHA-256 digest")
+	}
+	
+	if reflect.DeepEqual(chunks, sha3mh.Hash(param, message, randomness, 5)) {
+		t.Fatal("SHA-256 and SHA3 message hashes should differ")
+	}
+	
+	prepared := mh.PrepareMessage(param, message, 5)
+	if !reflect.DeepEqual(mh.HashPrepared(prepared, randomness), chunks) {
+		t.Fatal("HashPrepared differs from Hash")
+	}
+}
+
+// Test that SHA-256 shares the SHA3 chunk-range validation
+func TestSHA256MessageHashValidation(t *testing.T) {
+	for _, tc := range []struct{ dimension, chunkSize int }{{32, 9}, {10, 0}, {86, 3}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected panic for dimension %d, chunk size %d", tc.dimension, tc.chunkSize)
+				}
+			}()
+			NewSHA256MessageHash(24, 24, tc.dimension, tc.chunkSize)
+		}()
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"io"
 	
 	"golang.org/x/crypto/sha3"
-	"github.com/aerius-labs/hash-sig-go/internal/bitutil"
+	"github.com/aerius-labs/hash-sig-go/encoding"
 	"github.com/aerius-labs/hash-sig-go/th"
 	"github.com/aerius-labs/hash-sig-go/tweak"
 )
@@ -20,12 +20,7 @@ type SHA3MessageHash struct {
 
 // NewSHA3MessageHash creates a new SHA3-based message hash
 func NewSHA3MessageHash(parameterLen, randomnessLen, dimension, chunkSize int) *SHA3MessageHash {
-	if chunkSize != 1 && chunkSize != 2 && chunkSize != 4 && chunkSize != 8 {
-		panic("chunk size must be 1, 2, 4, or 8")
-	}
-	if dimension > 256 {
-		panic("dimension must be <= 256")
-	}
+	validateDigestChunking(dimension, chunkSize, "SHA3-256")
 	return &SHA3MessageHash{
 		parameterLen:  parameterLen,
 		randomnessLen: randomnessLen,
@@ -62,24 +57,31 @@ func (s *SHA3MessageHash) Apply(parameter th.Params, epoch uint32, randomness []
 	h.Write(msgTweak)
 	h.Write(message)
 	
-	fullHash := h.Sum(nil)
-	
-	// Truncate to exactly dimension * chunkSize bits
-	numBits := s.dimension * s.chunkSize
-	truncated := bitutil.TruncateBits(fullHash, numBits)
-	
-	// Split into w-bit chunks
-	chunks, err := bitutil.BytesToChunks(truncated, s.chunkSize)
-	if err != nil {
-		panic("failed to split into chunks: " + err.Error())
-	}
-	
-	// Ensure we have exactly dimension chunks
-	if len(chunks) > s.dimension {
-		chunks = chunks[:s.dimension]
+	return digestToChunks(h.Sum(nil), s.dimension, s.chunkSize)
+}
+
+// sha3PreparedMessage is the prepared form of SHA3 message-hash inputs
+type sha3PreparedMessage struct {
+	// suffix is P||T||M, which follows R in the hash input
+	suffix []byte
+}
+
+// PrepareMessage serialises P||T||M once. R is absorbed first, so the
+// sponge state itself cannot be precomputed.
+func (s *SHA3MessageHash) PrepareMessage(params th.Params, msg []byte, epoch uint32) encoding.PreparedMessage {
+	return &sha3PreparedMessage{suffix: messageSuffix(params, msg, epoch)}
+}
+
+// HashPrepared computes the message hash for prepared inputs
+func (s *SHA3MessageHash) HashPrepared(prepared encoding.PreparedMessage, rand []byte) []byte {
+	sp, ok := prepared.(*sha3PreparedMessage)
+	if !ok {
+		panic("prepared message was not produced by SHA3MessageHash")
 	}
-	
-	return chunks
+	h := sha3.New256()
+	h.Write(rand)
+	h.Write(sp.suffix)
+	return digestToChunks(h.Sum(nil), s.dimension, s.chunkSize)
 }
 
 // Dimension returns the number of chunks
@@ -97,6 +99,11 @@ func (s *SHA3MessageHash) ChunkSize() int {
 	return s.chunkSize
 }
 
+// Validate checks that the chunks fit into the 256-bit SHA3-256 digest
+func (s *SHA3MessageHash) Validate() error {
+	return checkDigestChunking(s.dimension, s.chunkSize, "SHA3-256")
+}
+
 // Hash implements the MessageHash interface
 func (s *SHA3MessageHash) Hash(params th.Params, msg []byte, rand []byte, epoch uint32) []byte {
 	return s.Apply(params, epoch, rand, msg)
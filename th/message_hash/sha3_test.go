@@ -5,6 +5,10 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	
+	"golang.org/x/crypto/sha3"
+	"github.com/aerius-labs/hash-sig-go/internal/bitutil"
+	"github.com/aerius-labs/hash-sig-go/tweak"
 )
 
 // Test message hash functionality
@@ -63,6 +67,10 @@ func TestSHA3MessageHashChunkSizes(t *testing.T) {
 		{2, 128, 3},  // 128 2-bit chunks
 		{4, 64, 15},  // 64 4-bit chunks
 		{8, 32, 255}, // 32 8-bit chunks
+		{3, 85, 7},   // chunks straddle byte boundaries
+		{5, 51, 31},
+		{6, 42, 63},
+		{7, 36, 127},
 	}
 	
 	for _, tc := range testCases {
@@ -81,6 +89,9 @@ func TestSHA3MessageHashChunkSizes(t *testing.T) {
 			if len(chunks) != tc.dimension {
 				t.Fatalf("Expected %d chunks, got %d", tc.dimension, len(chunks))
 			}
+			if mh.Base() != 1<<tc.chunkSize {
+				t.Fatalf("Expected base %d, got %d", 1<<tc.chunkSize, mh.Base())
+			}
 			
 			for i, chunk := range chunks {
 				if chunk > tc.maxValue {
@@ -91,6 +102,62 @@ func TestSHA3MessageHashChunkSizes(t *testing.T) {
 	}
 }
 
+// Test that byte-aligned chunk sizes keep the BytesToChunks layout
+func TestSHA3MessageHashMatchesBytesToChunks(t *testing.T) {
+	for _, chunkSize := range []int{1, 2, 4, 8} {
+		dimension := 256 / chunkSize
+		mh := NewSHA3MessageHash(24, 24, dimension, chunkSize)
+		
+		param := make([]byte, 24)
+		rand.Read(param)
+		randomness := mh.RandRandomness(rand.Reader)
+		message := make([]byte, 32)
+		rand.Read(message)
+		
+		h := sha3.New256()
+		h.Write(randomness)
+		h.Write(param)
+		h.Write(tweak.MessageTweak(3))
+		h.Write(message)
+		expected, err := bitutil.BytesToChunks(h.Sum(nil), chunkSize)
+		if err != nil {
+			t.Fatalf("BytesToChunks failed: %v", err)
+		}
+		
+		if !reflect.DeepEqual(mh.Apply(param, 3, randomness, message), expected) {
+			t.Fatalf("Chunk layout changed for chunk size %d", chunkSize)
+		}
+	}
+}
+
+// Test that a prepared message matches Hash for several randomness values
+func TestSHA3HashPreparedMatchesHash(t *testing.T) {
+	mh := NewSHA3MessageHash(24, 24, 48, 4)
+	
+	param := make([]byte, 24)
+	rand.Read(param)
+	message := make([]byte, 32)
+	rand.Read(message)
+	
+	prepared := mh.PrepareMessage(param, message, 7)
+	for i := 0; i < 5; i++ {
+		randomness := mh.RandRandomness(rand.Reader)
+		if !reflect.DeepEqual(mh.HashPrepared(prepared, randomness), mh.Hash(param, message, randomness, 7)) {
+			t.Fatalf("HashPrepared differs from Hash on attempt %d", i)
+		}
+	}
+}
+
+// Test that configurations needing more than 256 hash bits are rejected
+func TestSHA3MessageHashRejectsOversizedOutput(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected panic for 3 * 86 > 256 bits")
+		}
+	}()
+	NewSHA3MessageHash(24, 24, 86, 3)
+}
+
 // Benchmark message hash
 func BenchmarkSHA3MessageHash(b *testing.B) {
 	mh := NewSHA3MessageHash(24, 24, 48, 4)
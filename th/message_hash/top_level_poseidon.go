@@ -1,9 +1,12 @@
 package message_hash
 
 import (
+	"fmt"
 	"math/big"
 	
 	"github.com/consensys/gnark-crypto/field/babybear"
+	"github.com/aerius-labs/hash-sig-go/field"
+	"github.com/aerius-labs/hash-sig-go/encoding"
 	"github.com/aerius-labs/hash-sig-go/hypercube"
 	"github.com/aerius-labs/hash-sig-go/poseidon"
 	"github.com/aerius-labs/hash-sig-go/th"
@@ -19,8 +22,10 @@ type TopLevelPoseidonMessageHash struct {
 	finalLayer           int
 	tweakLenFE           int
 	msgLenFE             int
+	maxMsgLen            int // Longest message msgLenFE field elements hold
 	parameterLen         int
 	randLen              int
+	width                int // Poseidon2 permutation width, 16 or 24
 }
 
 // NewTopLevelPoseidonMessageHash creates a new top-level Poseidon message hash
@@ -28,6 +33,37 @@ func NewTopLevelPoseidonMessageHash(
 	posOutputLenPerInvFE, posInvocations, posOutputLenFE,
 	dimension, base, finalLayer,
 	tweakLenFE, msgLenFE, parameterLen, randLen int,
+) *TopLevelPoseidonMessageHash {
+	return newTopLevelPoseidonMessageHash(
+		posOutputLenPerInvFE, posInvocations, posOutputLenFE,
+		dimension, base, finalLayer,
+		tweakLenFE, msgLenFE, parameterLen, randLen, 24,
+	)
+}
+
+// NewTopLevelPoseidonMessageHashWidth16 creates a top-level Poseidon message
+// hash whose invocations use the cheaper width-16 permutation. Each
+// invocation's input (counter, parameter, epoch, randomness, message) and
+// output must fit into 16 field elements. The hypercube mapping of the
+// resulting field elements is the same as for the width-24 variant.
+func NewTopLevelPoseidonMessageHashWidth16(
+	posOutputLenPerInvFE, posInvocations, posOutputLenFE,
+	dimension, base, finalLayer,
+	tweakLenFE, msgLenFE, parameterLen, randLen int,
+) *TopLevelPoseidonMessageHash {
+	return newTopLevelPoseidonMessageHash(
+		posOutputLenPerInvFE, posInvocations, posOutputLenFE,
+		dimension, base, finalLayer,
+		tweakLenFE, msgLenFE, parameterLen, randLen, 16,
+	)
+}
+
+// newTopLevelPoseidonMessageHash validates the configuration, including
+// that each invocation fits the permutation width, and builds the hash
+func newTopLevelPoseidonMessageHash(
+	posOutputLenPerInvFE, posInvocations, posOutputLenFE,
+	dimension, base, finalLayer,
+	tweakLenFE, msgLenFE, parameterLen, randLen, width int,
 ) *TopLevelPoseidonMessageHash {
 	// Validate constraints
 	if posOutputLenFE != posInvocations*posOutputLenPerInvFE {
@@ -43,6 +79,14 @@ func NewTopLevelPoseidonMessageHash(
 		panic("BASE must be at most 256")
 	}
 	
+	// Invocation input: counter || parameter || epoch || randomness || message
+	if inputLen := 1 + parameterLen + tweakLenFE + randLen + msgLenFE; inputLen > width {
+		panic(fmt.Sprintf("invocation input of %d field elements exceeds permutation width %d", inputLen, width))
+	}
+	if posOutputLenPerInvFE > width {
+		panic(fmt.Sprintf("POS_OUTPUT_LEN_PER_INV_FE exceeds permutation width %d", width))
+	}
+	
 	return &TopLevelPoseidonMessageHash{
 		posOutputLenPerInvFE: posOutputLenPerInvFE,
 		posInvocations:       posInvocations,
@@ -52,20 +96,48 @@ func NewTopLevelPoseidonMessageHash(
 		finalLayer:           finalLayer,
 		tweakLenFE:           tweakLenFE,
 		msgLenFE:             msgLenFE,
+		maxMsgLen:            maxMessageLen(msgLenFE),
 		parameterLen:         parameterLen,
 		randLen:              randLen,
+		width:                width,
 	}
 }
 
 // Hash hashes a message and maps it into hypercube layers
 func (h *TopLevelPoseidonMessageHash) Hash(params th.Params, msg []byte, rand []byte, epoch uint32) []byte {
-	// Convert inputs to field elements
-	paramFields := bytesToFieldElements(params, h.parameterLen)
-	msgFields := bytesToFieldElements(msg, h.msgLenFE)
-	randFields := bytesToFieldElements(rand, h.randLen)
+	return h.HashPrepared(h.PrepareMessage(params, msg, epoch), rand)
+}
+
+// topLevelPreparedMessage is the prepared form of top-level Poseidon message-hash inputs
+type topLevelPreparedMessage struct {
+	perm        *poseidon.Poseidon2
+	paramFields []babybear.Element
+	epochFields []babybear.Element
+	msgFields   []babybear.Element
+}
+
+// PrepareMessage converts the parameter, epoch and message to field
+// elements and instantiates the permutation once, for reuse across retries.
+// It panics if msg is longer than MaxMessageLen.
+func (h *TopLevelPoseidonMessageHash) PrepareMessage(params th.Params, msg []byte, epoch uint32) encoding.PreparedMessage {
+	checkMessageLen(msg, h.maxMsgLen)
 	
-	// Encode epoch
-	epochFields := h.encodeEpoch(epoch)
+	return &topLevelPreparedMessage{
+		perm:        h.newPermutation(),
+		paramFields: field.BytesToElementsBaseP(params, h.parameterLen),
+		epochFields: h.encodeEpoch(epoch),
+		msgFields:   field.BytesToElementsBaseP(msg, h.msgLenFE),
+	}
+}
+
+// HashPrepared computes the message hash for prepared inputs
+func (h *TopLevelPoseidonMessageHash) HashPrepared(prepared encoding.PreparedMessage, rand []byte) []byte {
+	tp, ok := prepared.(*topLevelPreparedMessage)
+	if !ok {
+		panic("prepared message was not produced by TopLevelPoseidonMessageHash")
+	}
+	paramFields, epochFields, msgFields := tp.paramFields, tp.epochFields, tp.msgFields
+	randFields := field.BytesToElementsBaseP(rand, h.randLen)
 	
 	// Collect all field elements from Poseidon invocations
 	allOutputs := make([]babybear.Element, 0, h.posOutputLenFE)
@@ -92,8 +164,7 @@ func (h *TopLevelPoseidonMessageHash) Hash(params th.Params, msg []byte, rand []
 		input = append(input, msgFields...)
 		
 		// Apply Poseidon compression
-		perm := poseidon.NewPoseidon2_24()
-		output := h.poseidonCompress(perm, input, h.posOutputLenPerInvFE)
+		output := h.poseidonCompress(tp.perm, input, h.posOutputLenPerInvFE)
 		
 		allOutputs = append(allOutputs, output...)
 	}
@@ -136,6 +207,40 @@ func (h *TopLevelPoseidonMessageHash) ChunkSize() int {
 	return chunkSize
 }
 
+// MessageLen returns th.MessageLength: messages are packed into msgLenFE
+// field elements, which hold exactly that many bytes without loss
+func (h *TopLevelPoseidonMessageHash) MessageLen() int {
+	return th.MessageLength
+}
+
+// MaxMessageLen returns the longest message, in bytes, that msgLenFE field
+// elements hold without loss
+func (h *TopLevelPoseidonMessageHash) MaxMessageLen() int {
+	return h.maxMsgLen
+}
+
+// Validate checks that the posOutputLenFE output field elements carry
+// enough entropy to reach every vertex in layers 0..finalLayer of the
+// hypercube, i.e. that the part's size is at most p^posOutputLenFE
+func (h *TopLevelPoseidonMessageHash) Validate() error {
+	if h.base < 2 || h.base > 256 {
+		return fmt.Errorf("base %d not in [2, 256]", h.base)
+	}
+	if h.finalLayer < 0 || h.finalLayer > h.dimension*(h.base-1) {
+		return fmt.Errorf("final layer %d not in [0, %d]", h.finalLayer, h.dimension*(h.base-1))
+	}
+	if err := checkMessageFits(th.MessageLength, h.msgLenFE); err != nil {
+		return err
+	}
+	partSize := hypercube.HypercubePartSize(h.base, h.dimension, h.finalLayer)
+	outputSpace := new(big.Int).Exp(field.PBigInt(), big.NewInt(int64(h.posOutputLenFE)), nil)
+	if partSize.Cmp(outputSpace) > 0 {
+		return fmt.Errorf("layers 0..%d hold about 2^%d vertices, but %d field elements reach only about 2^%d",
+			h.finalLayer, partSize.BitLen()-1, h.posOutputLenFE, outputSpace.BitLen()-1)
+	}
+	return nil
+}
+
 // encodeEpoch encodes the epoch as field elements
 func (h *TopLevelPoseidonMessageHash) encodeEpoch(epoch uint32) []babybear.Element {
 	// Pack as: (epoch << 8) | separator
@@ -145,17 +250,30 @@ func (h *TopLevelPoseidonMessageHash) encodeEpoch(epoch uint32) []babybear.Eleme
 	result := make([]babybear.Element, h.tweakLenFE)
 	for i := 0; i < h.tweakLenFE; i++ {
 		var e babybear.Element
-		e.SetUint64(val % 2013265921)
+		e.SetUint64(val % field.P)
 		result[i] = e
-		val /= 2013265921
+		val /= field.P
 	}
 	
 	return result
 }
 
+// newPermutation instantiates the Poseidon2 permutation of the configured width
+func (h *TopLevelPoseidonMessageHash) newPermutation() *poseidon.Poseidon2 {
+	if h.width == 16 {
+		return poseidon.NewPoseidon2_16()
+	}
+	return poseidon.NewPoseidon2_24()
+}
+
+// Width returns the Poseidon2 permutation width used per invocation
+func (h *TopLevelPoseidonMessageHash) Width() int {
+	return h.width
+}
+
 // poseidonCompress applies Poseidon compression
 func (h *TopLevelPoseidonMessageHash) poseidonCompress(perm *poseidon.Poseidon2, input []babybear.Element, outputLen int) []babybear.Element {
-	width := 24
+	width := perm.Width()
 	
 	// Pad input to width
 	padded := make([]babybear.Element, width)
@@ -183,7 +301,7 @@ func (h *TopLevelPoseidonMessageHash) poseidonCompress(perm *poseidon.Poseidon2,
 func (h *TopLevelPoseidonMessageHash) mapIntoHypercubePart(fieldElements []babybear.Element) []byte {
 	// Combine field elements into one big integer
 	acc := new(big.Int)
-	orderU64 := new(big.Int).SetUint64(2013265921) // BabyBear field order
+	orderU64 := field.PBigInt() // BabyBear field order
 	
 	for _, fe := range fieldElements {
 		acc.Mul(acc, orderU64)
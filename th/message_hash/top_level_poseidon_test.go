@@ -164,4 +164,93 @@ func TestTopLevelPoseidonProperties(t *testing.T) {
 			}
 		}
 	}
-}
\ No newline at end of file
+}
+
+// Test the width-16 variant against the width-24 variant with the same configuration
+func TestTopLevelPoseidonWidth16(t *testing.T) {
+	const (
+		BASE        = 12
+		DIMENSION   = 40
+		FINAL_LAYER = 175
+	)
+	
+	// 1 + 2 + 2 + 2 + 9 = 16 input field elements per invocation
+	mh16 := NewTopLevelPoseidonMessageHashWidth16(8, 6, 48, DIMENSION, BASE, FINAL_LAYER, 2, 9, 2, 2)
+	mh24 := NewTopLevelPoseidonMessageHash(8, 6, 48, DIMENSION, BASE, FINAL_LAYER, 2, 9, 2, 2)
+	if mh16.Width() != 16 || mh24.Width() != 24 {
+		t.Fatalf("Unexpected widths %d and %d", mh16.Width(), mh24.Width())
+	}
+	
+	params := make(th.Params, 8)
+	rand.Read(params)
+	message := make([]byte, 32)
+	rand.Read(message)
+	randomness := make([]byte, 8)
+	rand.Read(randomness)
+	
+	result := mh16.Hash(params, message, randomness, 7)
+	if len(result) != DIMENSION {
+		t.Fatalf("Expected output length %d, got %d", DIMENSION, len(result))
+	}
+	
+	// The output must be a vertex in layers 0..FINAL_LAYER
+	sum := 0
+	for i, val := range result {
+		if int(val) >= BASE {
+			t.Fatalf("Output[%d] = %d exceeds base %d", i, val, BASE)
+		}
+		sum += int(val)
+	}
+	if minSum := (BASE-1)*DIMENSION - FINAL_LAYER; sum < minSum {
+		t.Errorf("Vertex sum %d is below %d, outside layers 0..%d", sum, minSum, FINAL_LAYER)
+	}
+	
+	// The permutation differs, so the outputs should too
+	same := true
+	for i, val := range mh24.Hash(params, message, randomness, 7) {
+		if val != result[i] {
+			same = false
+			break
+		}
+	}
+	if same {
+		t.Error("Width-16 and width-24 variants produced the same output")
+	}
+	
+	// The hypercube mapping itself does not depend on the width
+	fieldElems := make([]babybear.Element, 48)
+	for i := range fieldElems {
+		fieldElems[i].SetUint64(uint64(i) * 104729)
+	}
+	v16 := mh16.mapIntoHypercubePart(fieldElems)
+	v24 := mh24.mapIntoHypercubePart(fieldElems)
+	for i := range v16 {
+		if v16[i] != v24[i] {
+			t.Fatalf("Mapping differs at coordinate %d: %d vs %d", i, v16[i], v24[i])
+		}
+	}
+}
+
+// Test that an invocation input wider than 16 field elements is rejected
+func TestTopLevelPoseidonWidth16RejectsWideInput(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for 1 + 4 + 3 + 4 + 9 = 21 input field elements")
+		}
+	}()
+	NewTopLevelPoseidonMessageHashWidth16(8, 6, 48, 40, 12, 175, 3, 9, 4, 4)
+}
+
+// Test that Validate accepts the Rust test configuration and rejects an
+// output too short to reach every vertex of the top layers
+func TestTopLevelPoseidonValidate(t *testing.T) {
+	if err := NewTopLevelPoseidonMessageHash(8, 6, 48, 40, 12, 175, 3, 9, 4, 4).Validate(); err != nil {
+		t.Fatalf("Valid configuration rejected: %v", err)
+	}
+	if err := NewTopLevelPoseidonMessageHash(2, 1, 2, 40, 12, 175, 3, 9, 4, 4).Validate(); err == nil {
+		t.Fatal("Accepted 2 field elements for a 40-dimensional part")
+	}
+	if err := NewTopLevelPoseidonMessageHash(8, 6, 48, 40, 12, 441, 3, 9, 4, 4).Validate(); err == nil {
+		t.Fatal("Accepted a final layer beyond the hypercube")
+	}
+}
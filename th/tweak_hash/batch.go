@@ -0,0 +1,42 @@
+package tweak_hash
+
+import (
+	"runtime"
+	"sync"
+)
+
+// batchParallelThreshold is the batch size above which ApplyBatch
+// fans out across goroutines
+const batchParallelThreshold = 64
+
+// runBatch evaluates a worker function over [0, n), splitting the range
+// across up to GOMAXPROCS goroutines for large batches. newWorker is called
+// once per goroutine so each worker can own its scratch state.
+func runBatch(n int, newWorker func() func(i int)) {
+	workers := runtime.GOMAXPROCS(0)
+	if n < batchParallelThreshold || workers == 1 {
+		work := newWorker()
+		for i := 0; i < n; i++ {
+			work(i)
+		}
+		return
+	}
+
+	chunk := (n + workers - 1) / workers
+	var wg sync.WaitGroup
+	for start := 0; start < n; start += chunk {
+		end := start + chunk
+		if end > n {
+			end = n
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			work := newWorker()
+			for i := start; i < end; i++ {
+				work(i)
+			}
+		}(start, end)
+	}
+	wg.Wait()
+}
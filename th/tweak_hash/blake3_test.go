@@ -0,0 +1,87 @@
+package tweak_hash
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/aerius-labs/hash-sig-go/th"
+)
+
+// Test all standard configurations, mirroring the SHA3 variant
+func TestBlake3Configurations(t *testing.T) {
+	configs := []struct {
+		name      string
+		paramLen  int
+		hashLen   int
+	}{
+		{"128_128", 16, 16},
+		{"128_192", 16, 24},
+		{"192_192", 24, 24},
+		{"Nonstandard", 10, 17},
+		{"LongOutput", 24, 48},
+	}
+
+	for _, cfg := range configs {
+		t.Run(cfg.name, func(t *testing.T) {
+			thash := NewBlake3TweakableHash(cfg.paramLen, cfg.hashLen)
+
+			param := thash.RandParameter(rand.Reader)
+			if len(param) != cfg.paramLen {
+				t.Fatalf("Parameter length mismatch: got %d, want %d", len(param), cfg.paramLen)
+			}
+
+			msg1 := thash.RandDomain(rand.Reader)
+			msg2 := thash.RandDomain(rand.Reader)
+			if len(msg1) != cfg.hashLen {
+				t.Fatalf("Domain length mismatch: got %d, want %d", len(msg1), cfg.hashLen)
+			}
+
+			treeTweak := thash.TreeTweak(0, 3)
+			result := thash.Apply(param, treeTweak, []th.Domain{msg1, msg2})
+			if len(result) != cfg.hashLen {
+				t.Fatalf("Expected %d bytes, got %d", cfg.hashLen, len(result))
+			}
+
+			chainTweak := thash.ChainTweak(2, 3, 4)
+			result2 := thash.Apply(param, chainTweak, []th.Domain{msg1, msg2})
+			if bytes.Equal(result, result2) {
+				t.Fatal("Different tweaks produced same result")
+			}
+
+			// Determinism
+			result3 := thash.Apply(param, treeTweak, []th.Domain{msg1, msg2})
+			if !bytes.Equal(result, result3) {
+				t.Fatal("Blake3 tweakable hash is not deterministic")
+			}
+		})
+	}
+}
+
+// Test that Blake3 and SHA3 produce unrelated outputs for identical inputs
+func TestBlake3DiffersFromSHA3(t *testing.T) {
+	b3 := NewBlake3_192_192()
+	s3 := NewSHA3_192_192()
+
+	param := b3.RandParameter(rand.Reader)
+	msg := b3.RandDomain(rand.Reader)
+	tweak := b3.ChainTweak(1, 2, 3)
+
+	if bytes.Equal(b3.Apply(param, tweak, []th.Domain{msg}), s3.Apply(param, tweak, []th.Domain{msg})) {
+		t.Fatal("Blake3 and SHA3 outputs should differ")
+	}
+}
+
+// Benchmark Blake3 tweakable hash, for comparison with BenchmarkSHA3Apply
+func BenchmarkBlake3Apply(b *testing.B) {
+	thash := NewBlake3TweakableHash(24, 24)
+	param := thash.RandParameter(rand.Reader)
+	msg1 := thash.RandDomain(rand.Reader)
+	msg2 := thash.RandDomain(rand.Reader)
+	tweak := thash.ChainTweak(0, 0, 0)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		thash.Apply(param, tweak, []th.Domain{msg1, msg2})
+	}
+}
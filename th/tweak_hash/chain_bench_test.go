@@ -0,0 +1,51 @@
+package tweak_hash
+
+import (
+	"crypto/rand"
+	"testing"
+	
+	"github.com/aerius-labs/hash-sig-go/th"
+)
+
+// Benchmark th.Chain over 16 steps for every backend, to compare them for
+// key generation and verification, where chain walking dominates. Run with
+// go test -bench ChainBackends ./th/tweak_hash to get ns/op and allocs/op.
+// The Into variants walk with th.ChainInto into a reused buffer.
+func BenchmarkChainBackends(b *testing.B) {
+	poseidon := NewPoseidonTweakHash(5, 7, 2, 9, 32)
+	
+	backends := []struct {
+		name  string
+		thash th.TweakableHash
+		// prepare wraps the hash with its parameter, as KeyGen and Sign do
+		prepare bool
+	}{
+		{"SHA3_192_192", NewSHA3_192_192(), false},
+		{"Blake3_192_192", NewBlake3_192_192(), false},
+		{"Poseidon24", poseidon, false},
+		{"Poseidon24Prepared", poseidon, true},
+	}
+	
+	for _, backend := range backends {
+		parameter := backend.thash.RandParameter(rand.Reader)
+		start := backend.thash.RandDomain(rand.Reader)
+		thash := backend.thash
+		if backend.prepare {
+			thash = th.WithPreparedParams(thash, parameter)
+		}
+		
+		b.Run(backend.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				th.Chain(thash, parameter, uint32(i), 0, 0, 16, start)
+			}
+		})
+		b.Run(backend.name+"Into", func(b *testing.B) {
+			dst := make(th.Domain, thash.OutputLen())
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				th.ChainInto(dst, thash, parameter, uint32(i), 0, 0, 16, start)
+			}
+		})
+	}
+}
@@ -3,9 +3,11 @@ package tweak_hash
 
 import (
 	"encoding/binary"
+	"fmt"
 	"io"
 	
 	"github.com/consensys/gnark-crypto/field/babybear"
+	"github.com/aerius-labs/hash-sig-go/field"
 	"github.com/aerius-labs/hash-sig-go/poseidon"
 	"github.com/aerius-labs/hash-sig-go/th"
 )
@@ -24,7 +26,7 @@ const (
 	DomainParametersLength = 4
 	
 	// BabyBear prime
-	P = 2013265921
+	P = field.P
 )
 
 // PoseidonTweakHash implements tweakable hash using Poseidon2
@@ -36,8 +38,18 @@ type PoseidonTweakHash struct {
 	numChunks    int
 }
 
-// NewPoseidonTweakHash creates a new Poseidon tweakable hash
+// NewPoseidonTweakHash creates a new Poseidon tweakable hash. The parameter
+// and tweak form the sponge capacity, so together they must leave a
+// positive rate in the width-24 permutation, and the output is squeezed
+// from a single state, so hashLen must be at most 24 (lengths in field
+// elements).
 func NewPoseidonTweakHash(parameterLen, hashLen, tweakLen, capacity, numChunks int) *PoseidonTweakHash {
+	if parameterLen+tweakLen >= MergeCompressionWidth {
+		panic(fmt.Sprintf("parameter length %d plus tweak length %d leaves no sponge rate in width %d", parameterLen, tweakLen, MergeCompressionWidth))
+	}
+	if hashLen > MergeCompressionWidth {
+		panic(fmt.Sprintf("hash length %d exceeds permutation width %d", hashLen, MergeCompressionWidth))
+	}
 	return &PoseidonTweakHash{
 		parameterLen: parameterLen,
 		hashLen:      hashLen,
@@ -47,37 +59,130 @@ func NewPoseidonTweakHash(parameterLen, hashLen, tweakLen, capacity, numChunks i
 	}
 }
 
-// RandParameter generates random parameters
+// RandParameter generates random parameters, canonically encoded: every
+// 4-byte word is reduced modulo p, which Apply would do anyway
 func (p *PoseidonTweakHash) RandParameter(rng io.Reader) th.Params {
 	params := make([]byte, p.parameterLen*4) // 4 bytes per field element
 	if _, err := io.ReadFull(rng, params); err != nil {
 		panic("failed to generate parameters")
 	}
-	return params
+	return field.ElementsToBytes(field.BytesToElements(params, p.parameterLen))
+}
+
+// ParameterFromSeed deterministically expands seed into a canonically
+// encoded parameter, like SHA3TweakableHash.ParameterFromSeed
+func (p *PoseidonTweakHash) ParameterFromSeed(seed []byte) th.Params {
+	return p.RandParameter(seedReader(seed))
 }
 
 // Apply computes the tweakable hash
 func (p *PoseidonTweakHash) Apply(params th.Params, tweak th.Tweak, data []th.Domain) th.Domain {
 	// Convert parameters to field elements
-	paramFields := bytesToFieldElements(params, p.parameterLen)
+	paramFields := field.BytesToElements(params, p.parameterLen)
+	
+	return p.applyFields(poseidon.NewPoseidon2_24(), paramFields, tweak, data)
+}
+
+// ApplyInto computes Apply into dst
+func (p *PoseidonTweakHash) ApplyInto(dst []byte, params th.Params, tweak th.Tweak, data []th.Domain) {
+	paramFields := field.BytesToElements(params, p.parameterLen)
+	state := p.applyFieldsState(poseidon.NewPoseidon2_24(), paramFields, tweak, data)
+	field.PutElements(dst, state[:p.hashLen])
+}
+
+// Clone returns a copy of the hash
+func (p *PoseidonTweakHash) Clone() th.TweakableHash {
+	clone := *p
+	return &clone
+}
+
+// poseidonPrepared is the prepared form of a parameter for PoseidonTweakHash
+type poseidonPrepared struct {
+	perm        *poseidon.Poseidon2
+	paramFields []babybear.Element
+}
+
+// Prepare converts the parameter to field elements and instantiates the
+// permutation once, for reuse across many ApplyPrepared calls
+func (p *PoseidonTweakHash) Prepare(params th.Params) th.PreparedParams {
+	return &poseidonPrepared{
+		perm:        poseidon.NewPoseidon2_24(),
+		paramFields: field.BytesToElements(params, p.parameterLen),
+	}
+}
+
+// ApplyPrepared computes the tweakable hash for a prepared parameter
+func (p *PoseidonTweakHash) ApplyPrepared(prepared th.PreparedParams, tweak th.Tweak, data []th.Domain) th.Domain {
+	pp, ok := prepared.(*poseidonPrepared)
+	if !ok {
+		panic("prepared parameter was not produced by PoseidonTweakHash")
+	}
+	return p.applyFields(pp.perm, pp.paramFields, tweak, data)
+}
+
+// ApplyPreparedInto computes ApplyPrepared into dst
+func (p *PoseidonTweakHash) ApplyPreparedInto(dst []byte, prepared th.PreparedParams, tweak th.Tweak, data []th.Domain) {
+	pp, ok := prepared.(*poseidonPrepared)
+	if !ok {
+		panic("prepared parameter was not produced by PoseidonTweakHash")
+	}
+	field.PutElements(dst, p.applyFieldsState(pp.perm, pp.paramFields, tweak, data)[:p.hashLen])
+}
+
+// ApplyBatch computes Apply for every (tweak, data) pair, converting the
+// parameter and instantiating the permutation once for the whole batch
+func (p *PoseidonTweakHash) ApplyBatch(params th.Params, tweaks []th.Tweak, data [][]th.Domain) []th.Domain {
+	if len(tweaks) != len(data) {
+		panic("tweaks and data must have the same length")
+	}
+	
+	// The permutation holds only read-only round constants, so workers share it
+	perm := poseidon.NewPoseidon2_24()
+	paramFields := field.BytesToElements(params, p.parameterLen)
+	
+	out := make([]th.Domain, len(tweaks))
+	runBatch(len(tweaks), func() func(i int) {
+		return func(i int) {
+			out[i] = p.applyFields(perm, paramFields, tweaks[i], data[i])
+		}
+	})
+	return out
+}
+
+// ApplyWithTrace computes the tweakable hash like Apply and additionally
+// returns the full 24-element sponge state after the last permutation.
+// This is a debugging aid for comparing against in-circuit witnesses; the
+// first hashLen elements of the state are the output.
+func (p *PoseidonTweakHash) ApplyWithTrace(params th.Params, tweak th.Tweak, data []th.Domain) (th.Domain, []babybear.Element) {
+	paramFields := field.BytesToElements(params, p.parameterLen)
+	state := p.applyFieldsState(poseidon.NewPoseidon2_24(), paramFields, tweak, data)
+	return field.ElementsToBytes(state[:p.hashLen]), state
+}
+
+// applyFields computes the tweakable hash given the parameter as field elements
+func (p *PoseidonTweakHash) applyFields(perm *poseidon.Poseidon2, paramFields []babybear.Element, tweak th.Tweak, data []th.Domain) th.Domain {
+	state := p.applyFieldsState(perm, paramFields, tweak, data)
 	
+	// Squeeze phase - extract hashLen elements and convert back to bytes
+	return field.ElementsToBytes(state[:p.hashLen])
+}
+
+// applyFieldsState runs the sponge and returns its final state
+func (p *PoseidonTweakHash) applyFieldsState(perm *poseidon.Poseidon2, paramFields []babybear.Element, tweak th.Tweak, data []th.Domain) []babybear.Element {
 	// Convert tweak to field elements
 	tweakFields := p.tweakToFieldElements(tweak)
 	
 	// Convert data to field elements
 	var dataFields []babybear.Element
 	for _, d := range data {
-		dataFields = append(dataFields, bytesToFieldElements(d, p.hashLen)...)
+		dataFields = append(dataFields, field.BytesToElements(d, p.hashLen)...)
 	}
 	
 	// Compute capacity value as hash of params and tweak
 	capacityValue := p.computeCapacityValue(paramFields, tweakFields)
 	
 	// Apply sponge construction
-	result := p.poseidonSponge(capacityValue, dataFields)
-	
-	// Convert back to bytes
-	return fieldElementsToBytes(result)
+	return perm.Sponge(capacityValue, dataFields, perm.Width())
 }
 
 // TreeTweak creates a tree tweak
@@ -107,6 +212,37 @@ func (p *PoseidonTweakHash) MessageTweak(epoch uint32) th.Tweak {
 	return tweak
 }
 
+// CanonicalDomain re-encodes d with every 4-byte word reduced modulo p.
+// Apply reads words modulo p, so d and its canonical form hash identically.
+func (p *PoseidonTweakHash) CanonicalDomain(d th.Domain) th.Domain {
+	if len(d) != p.OutputLen() {
+		return d
+	}
+	return field.ElementsToBytes(field.BytesToElements(d, p.hashLen))
+}
+
+// CheckParameterCanonical checks that every big-endian 4-byte word of
+// params is below p
+func (p *PoseidonTweakHash) CheckParameterCanonical(params th.Params) error {
+	for i := 0; i+4 <= len(params); i += 4 {
+		if word := binary.BigEndian.Uint32(params[i:]); uint64(word) >= P {
+			return fmt.Errorf("%w: element %d is %d, not below p", th.ErrNonCanonicalParameter, i/4, word)
+		}
+	}
+	return nil
+}
+
+// ValidateParameter rejects parameters whose field elements are all zero,
+// including non-canonical encodings of zero
+func (p *PoseidonTweakHash) ValidateParameter(params th.Params) error {
+	for _, e := range field.BytesToElements(params, len(params)/4) {
+		if !e.IsZero() {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: all %d field elements are zero", th.ErrDegenerateParameter, len(params)/4)
+}
+
 // OutputLen returns the output length in bytes
 func (p *PoseidonTweakHash) OutputLen() int {
 	return p.hashLen * 4 // 4 bytes per field element
@@ -129,7 +265,6 @@ func (p *PoseidonTweakHash) RandDomain(rng io.Reader) th.Domain {
 // tweakToFieldElements converts tweak bytes to field elements
 func (p *PoseidonTweakHash) tweakToFieldElements(tweak th.Tweak) []babybear.Element {
 	// Convert tweak to a big integer, then decompose in base p
-	// This matches the Rust implementation's approach
 	
 	// First byte is separator
 	separator := tweak[0]
@@ -162,7 +297,9 @@ func (p *PoseidonTweakHash) tweakToFieldElements(tweak th.Tweak) []babybear.Elem
 	return result
 }
 
-// computeCapacityValue computes the capacity for sponge construction
+// computeCapacityValue computes the capacity for sponge construction:
+// params || tweak, which Sponge places in the last state elements. Whether
+// this is Rust's layout is unverified.
 func (p *PoseidonTweakHash) computeCapacityValue(params []babybear.Element, tweak []babybear.Element) []babybear.Element {
 	// Combine params and tweak for domain separation
 	capacity := make([]babybear.Element, 0, len(params)+len(tweak))
@@ -170,69 +307,3 @@ func (p *PoseidonTweakHash) computeCapacityValue(params []babybear.Element, twea
 	capacity = append(capacity, tweak...)
 	return capacity
 }
-
-// poseidonSponge applies the sponge construction
-func (p *PoseidonTweakHash) poseidonSponge(capacity []babybear.Element, input []babybear.Element) []babybear.Element {
-	perm := poseidon.NewPoseidon2_24()
-	width := 24
-	rate := width - len(capacity)
-	
-	// Initialize state
-	state := make([]babybear.Element, width)
-	copy(state[rate:], capacity)
-	
-	// Absorb phase
-	for i := 0; i < len(input); i += rate {
-		end := i + rate
-		if end > len(input) {
-			end = len(input)
-		}
-		
-		// Add input to state
-		for j := 0; j < end-i; j++ {
-			var sum babybear.Element
-			sum.Add(&state[j], &input[i+j])
-			state[j] = sum
-		}
-		
-		// Apply permutation
-		perm.Permute(state)
-	}
-	
-	// Squeeze phase - extract hashLen elements
-	output := make([]babybear.Element, p.hashLen)
-	copy(output, state[:p.hashLen])
-	
-	return output
-}
-
-// bytesToFieldElements converts bytes to field elements
-func bytesToFieldElements(data []byte, numElements int) []babybear.Element {
-	result := make([]babybear.Element, numElements)
-	for i := 0; i < numElements; i++ {
-		offset := i * 4
-		if offset+4 <= len(data) {
-			var e babybear.Element
-			e.SetBytes(data[offset : offset+4])
-			result[i] = e
-		} else if offset < len(data) {
-			// Partial element
-			partial := make([]byte, 4)
-			copy(partial, data[offset:])
-			var e babybear.Element
-			e.SetBytes(partial)
-			result[i] = e
-		}
-	}
-	return result
-}
-
-// fieldElementsToBytes converts field elements to bytes
-func fieldElementsToBytes(elements []babybear.Element) []byte {
-	result := make([]byte, 0, len(elements)*4)
-	for _, elem := range elements {
-		b := elem.Bytes()
-		result = append(result, b[:]...)
-	}
-	return result
-}
\ No newline at end of file
@@ -7,6 +7,7 @@ import (
 	"testing"
 	
 	"github.com/consensys/gnark-crypto/field/babybear"
+	"github.com/aerius-labs/hash-sig-go/field"
 	"github.com/aerius-labs/hash-sig-go/th"
 )
 
@@ -78,7 +79,7 @@ func TestTreeTweakFieldElements(t *testing.T) {
 	tweakBigint.Add(tweakBigint, new(big.Int).SetUint64(sep))
 	
 	// Convert to field elements
-	p := new(big.Int).SetUint64(2013265921)
+	p := field.PBigInt()
 	expected := make([]babybear.Element, 2)
 	
 	remainder := new(big.Int).Set(tweakBigint)
@@ -121,7 +122,7 @@ func TestChainTweakFieldElements(t *testing.T) {
 	tweakBigint.Add(tweakBigint, new(big.Int).SetUint64(sep))
 	
 	// Convert to field elements
-	p := new(big.Int).SetUint64(2013265921)
+	p := field.PBigInt()
 	expected := make([]babybear.Element, 2)
 	
 	remainder := new(big.Int).Set(tweakBigint)
@@ -302,4 +303,155 @@ func TestRandDomainNotAllSame(t *testing.T) {
 	if allSameCount == trials {
 		t.Error("All random domain elements had identical bytes")
 	}
-}
\ No newline at end of file
+}
+
+// Test that ApplyBatch matches per-call Apply exactly
+func TestPoseidonApplyBatchMatchesApply(t *testing.T) {
+	pth := NewPoseidonTweakHash(5, 7, 2, 9, 32)
+	params := pth.RandParameter(rand.Reader)
+	
+	// Cover both the sequential and the parallel batch paths
+	for _, n := range []int{1, 5, batchParallelThreshold + 3} {
+		tweaks := make([]th.Tweak, n)
+		data := make([][]th.Domain, n)
+		for i := 0; i < n; i++ {
+			tweaks[i] = pth.TreeTweak(2, uint32(i))
+			data[i] = []th.Domain{pth.RandDomain(rand.Reader), pth.RandDomain(rand.Reader)}
+		}
+		
+		results := pth.ApplyBatch(params, tweaks, data)
+		for i := 0; i < n; i++ {
+			if !bytes.Equal(results[i], pth.Apply(params, tweaks[i], data[i])) {
+				t.Fatalf("Batch result %d differs from Apply (batch size %d)", i, n)
+			}
+		}
+	}
+}
+
+// Test that the prepared-parameter path matches plain Apply
+func TestPoseidonApplyPreparedMatchesApply(t *testing.T) {
+	pth := NewPoseidonTweakHash(5, 7, 2, 9, 32)
+	params := pth.RandParameter(rand.Reader)
+	otherParams := pth.RandParameter(rand.Reader)
+	msg := pth.RandDomain(rand.Reader)
+	tweak := pth.ChainTweak(3, 1, 4)
+	
+	expected := pth.Apply(params, tweak, []th.Domain{msg})
+	
+	prepared := pth.Prepare(params)
+	if !bytes.Equal(pth.ApplyPrepared(prepared, tweak, []th.Domain{msg}), expected) {
+		t.Fatal("ApplyPrepared differs from Apply")
+	}
+	
+	// The bound wrapper takes the fast path for the prepared parameter
+	// and falls back to the plain path for any other parameter
+	bound := th.WithPreparedParams(pth, params)
+	if !bytes.Equal(bound.Apply(params, tweak, []th.Domain{msg}), expected) {
+		t.Fatal("Prepared wrapper differs from Apply")
+	}
+	if !bytes.Equal(bound.Apply(otherParams, tweak, []th.Domain{msg}), pth.Apply(otherParams, tweak, []th.Domain{msg})) {
+		t.Fatal("Prepared wrapper differs from Apply for a different parameter")
+	}
+	
+	// It keeps the batch path and unwraps to the plain hash
+	batch, ok := bound.(th.BatchTweakableHash)
+	if !ok {
+		t.Fatal("Prepared wrapper should forward ApplyBatch")
+	}
+	if got := batch.ApplyBatch(params, []th.Tweak{tweak}, [][]th.Domain{{msg}}); !bytes.Equal(got[0], expected) {
+		t.Fatal("Prepared wrapper's ApplyBatch differs from Apply")
+	}
+	if th.Unprepared(bound) != th.TweakableHash(pth) {
+		t.Fatal("Unprepared should return the wrapped hash")
+	}
+	
+	// Hashes without a prepared path are returned unchanged
+	sha := NewSHA3_192_192()
+	if th.WithPreparedParams(sha, params) != th.TweakableHash(sha) {
+		t.Fatal("WithPreparedParams should not wrap hashes without a prepared path")
+	}
+}
+
+// Test that the traced sponge state agrees with the returned output
+func TestPoseidonApplyWithTrace(t *testing.T) {
+	pth := NewPoseidonTweakHash(5, 7, 2, 9, 32)
+	params := pth.RandParameter(rand.Reader)
+	msg1 := pth.RandDomain(rand.Reader)
+	msg2 := pth.RandDomain(rand.Reader)
+	tweak := pth.TreeTweak(1, 6)
+	
+	output, state := pth.ApplyWithTrace(params, tweak, []th.Domain{msg1, msg2})
+	if len(state) != MergeCompressionWidth {
+		t.Fatalf("Expected %d state elements, got %d", MergeCompressionWidth, len(state))
+	}
+	if !bytes.Equal(output, pth.Apply(params, tweak, []th.Domain{msg1, msg2})) {
+		t.Fatal("ApplyWithTrace output differs from Apply")
+	}
+	if !bytes.Equal(field.ElementsToBytes(state[:7]), output) {
+		t.Fatal("First hashLen state elements do not decode to the output")
+	}
+}
+
+// Benchmark Poseidon tweakable hash
+func BenchmarkPoseidonApply(b *testing.B) {
+	pth := NewPoseidonTweakHash(5, 7, 2, 9, 32)
+	params := pth.RandParameter(rand.Reader)
+	msg := pth.RandDomain(rand.Reader)
+	tweak := pth.ChainTweak(0, 0, 0)
+	
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pth.Apply(params, tweak, []th.Domain{msg})
+	}
+}
+
+// Benchmark Poseidon tweakable hash with a prepared parameter
+func BenchmarkPoseidonApplyPrepared(b *testing.B) {
+	pth := NewPoseidonTweakHash(5, 7, 2, 9, 32)
+	params := pth.RandParameter(rand.Reader)
+	msg := pth.RandDomain(rand.Reader)
+	tweak := pth.ChainTweak(0, 0, 0)
+	prepared := pth.Prepare(params)
+	
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pth.ApplyPrepared(prepared, tweak, []th.Domain{msg})
+	}
+}
+
+// Test that the constructor rejects configurations the width-24 sponge
+// cannot run, and accepts the largest ones it can
+func TestNewPoseidonTweakHashValidatesWidth(t *testing.T) {
+	configs := []struct {
+		name     string
+		paramLen int
+		hashLen  int
+		tweakLen int
+		valid    bool
+	}{
+		{"Standard", 5, 7, 2, true},
+		{"RateOne", 20, 7, 3, true},
+		{"MaxHash", 5, 24, 2, true},
+		{"NoRate", 22, 7, 2, false},
+		{"Oversized", 30, 7, 2, false},
+		{"HashTooLong", 5, 25, 2, false},
+	}
+	
+	for _, cfg := range configs {
+		t.Run(cfg.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); (r == nil) != cfg.valid {
+					t.Fatalf("valid=%v, panic: %v", cfg.valid, r)
+				}
+			}()
+			pth := NewPoseidonTweakHash(cfg.paramLen, cfg.hashLen, cfg.tweakLen, 9, 32)
+			
+			// A valid configuration hashes without panicking
+			params := pth.RandParameter(rand.Reader)
+			msg := pth.RandDomain(rand.Reader)
+			if out := pth.Apply(params, pth.ChainTweak(1, 2, 3), []th.Domain{msg}); len(out) != 4*cfg.hashLen {
+				t.Fatalf("Expected %d bytes, got %d", 4*cfg.hashLen, len(out))
+			}
+		})
+	}
+}
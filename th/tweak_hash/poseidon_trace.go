@@ -0,0 +1,126 @@
+package tweak_hash
+
+import (
+	"io"
+	"sync"
+	
+	"github.com/consensys/gnark-crypto/field/babybear"
+	"github.com/aerius-labs/hash-sig-go/field"
+	"github.com/aerius-labs/hash-sig-go/th"
+)
+
+// PoseidonInvocation records the field-element inputs of one Poseidon Apply
+type PoseidonInvocation struct {
+	Params []babybear.Element
+	Tweak  []babybear.Element
+	Data   []babybear.Element
+}
+
+// TracingPoseidonTweakHash wraps a PoseidonTweakHash and records the
+// field-element inputs of every Apply, so SNARK witnesses can be checked
+// against the exact values hashed during KeyGen, Sign or Verify.
+//
+// The wrapper deliberately does not expose the prepared or batched fast
+// paths, so every hash goes through Apply and is recorded. Concurrent
+// callers (e.g. parallel chain walks in Sign) are recorded in invocation
+// order, before hashing; each invocation carries its tweak to identify it.
+type TracingPoseidonTweakHash struct {
+	inner *PoseidonTweakHash
+	
+	mu    sync.Mutex
+	trace []PoseidonInvocation
+}
+
+// NewTracingPoseidonTweakHash creates a tracing wrapper around inner
+func NewTracingPoseidonTweakHash(inner *PoseidonTweakHash) *TracingPoseidonTweakHash {
+	return &TracingPoseidonTweakHash{inner: inner}
+}
+
+// Apply records the field-element inputs and computes the tweakable hash
+func (t *TracingPoseidonTweakHash) Apply(params th.Params, tweak th.Tweak, data []th.Domain) th.Domain {
+	var dataFields []babybear.Element
+	for _, d := range data {
+		dataFields = append(dataFields, field.BytesToElements(d, t.inner.hashLen)...)
+	}
+	invocation := PoseidonInvocation{
+		Params: field.BytesToElements(params, t.inner.parameterLen),
+		Tweak:  t.inner.tweakToFieldElements(tweak),
+		Data:   dataFields,
+	}
+	
+	t.mu.Lock()
+	t.trace = append(t.trace, invocation)
+	t.mu.Unlock()
+	
+	return t.inner.Apply(params, tweak, data)
+}
+
+// Clone returns a tracing wrapper around a clone of the inner hash, with a
+// trace of its own: invocations on the clone are not recorded here
+func (t *TracingPoseidonTweakHash) Clone() th.TweakableHash {
+	return NewTracingPoseidonTweakHash(t.inner.Clone().(*PoseidonTweakHash))
+}
+
+// Trace returns a copy of the invocations recorded so far
+func (t *TracingPoseidonTweakHash) Trace() []PoseidonInvocation {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]PoseidonInvocation(nil), t.trace...)
+}
+
+// Reset discards the recorded invocations
+func (t *TracingPoseidonTweakHash) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.trace = nil
+}
+
+// RandParameter generates random parameters
+func (t *TracingPoseidonTweakHash) RandParameter(rng io.Reader) th.Params {
+	return t.inner.RandParameter(rng)
+}
+
+// ParameterFromSeed deterministically expands seed into a parameter
+func (t *TracingPoseidonTweakHash) ParameterFromSeed(seed []byte) th.Params {
+	return t.inner.ParameterFromSeed(seed)
+}
+
+// RandDomain generates a random domain element
+func (t *TracingPoseidonTweakHash) RandDomain(rng io.Reader) th.Domain {
+	return t.inner.RandDomain(rng)
+}
+
+// TreeTweak creates a tree tweak
+func (t *TracingPoseidonTweakHash) TreeTweak(level uint8, posInLevel uint32) th.Tweak {
+	return t.inner.TreeTweak(level, posInLevel)
+}
+
+// ChainTweak creates a chain tweak
+func (t *TracingPoseidonTweakHash) ChainTweak(epoch uint32, chainIndex uint8, posInChain uint8) th.Tweak {
+	return t.inner.ChainTweak(epoch, chainIndex, posInChain)
+}
+
+// CanonicalDomain delegates to the inner hash
+func (t *TracingPoseidonTweakHash) CanonicalDomain(d th.Domain) th.Domain {
+	return t.inner.CanonicalDomain(d)
+}
+
+// CheckParameterCanonical delegates to the inner hash
+func (t *TracingPoseidonTweakHash) CheckParameterCanonical(params th.Params) error {
+	return t.inner.CheckParameterCanonical(params)
+}
+
+// ValidateParameter delegates to the inner hash
+func (t *TracingPoseidonTweakHash) ValidateParameter(params th.Params) error {
+	return t.inner.ValidateParameter(params)
+}
+
+// OutputLen returns the output length in bytes
+func (t *TracingPoseidonTweakHash) OutputLen() int {
+	return t.inner.OutputLen()
+}
+
+// ParameterLen returns the parameter length in bytes
+func (t *TracingPoseidonTweakHash) ParameterLen() int {
+	return t.inner.ParameterLen()
+}
@@ -1,6 +1,7 @@
 package tweak_hash
 
 import (
+	"fmt"
 	"io"
 	
 	"golang.org/x/crypto/sha3"
@@ -8,6 +9,10 @@ import (
 	"github.com/aerius-labs/hash-sig-go/tweak"
 )
 
+// parameterSeedSep domain-separates ParameterFromSeed from other uses of
+// the same seed
+var parameterSeedSep = []byte("hash-sig-go 2025 parameter from seed")
+
 // SHA3TweakableHash implements tweakable hash using SHA3
 // Following Section 7.2 of the paper
 type SHA3TweakableHash struct {
@@ -15,10 +20,14 @@ type SHA3TweakableHash struct {
 	hashLen      int
 }
 
-// NewSHA3TweakableHash creates a new SHA3-based tweakable hash
+// NewSHA3TweakableHash creates a new SHA3-based tweakable hash. Outputs are
+// truncated SHA3-256 digests, so hashLen must be at most 32 bytes.
 func NewSHA3TweakableHash(parameterLen, hashLen int) *SHA3TweakableHash {
-	if parameterLen > 255 || hashLen > 255 {
-		panic("parameter and hash lengths must be <= 255 bytes")
+	if parameterLen > 255 {
+		panic("parameter length must be <= 255 bytes")
+	}
+	if hashLen > 32 {
+		panic(fmt.Sprintf("hash length %d exceeds the 32-byte SHA3-256 output", hashLen))
 	}
 	return &SHA3TweakableHash{
 		parameterLen: parameterLen,
@@ -39,6 +48,37 @@ func (s *SHA3TweakableHash) RandParameter(rng io.Reader) th.Params {
 	return p
 }
 
+// ParameterFromSeed deterministically expands seed into a parameter, as
+// RandParameter reading from seedReader(seed). It is meant for golden tests
+// and cross-implementation vectors; real keys should use RandParameter.
+func (s *SHA3TweakableHash) ParameterFromSeed(seed []byte) th.Params {
+	return s.RandParameter(seedReader(seed))
+}
+
+// seedReader returns SHAKE256(parameterSeedSep || seed) as a reader
+func seedReader(seed []byte) io.Reader {
+	h := sha3.NewShake256()
+	h.Write(parameterSeedSep)
+	h.Write(seed)
+	return h
+}
+
+// ValidateParameter rejects the all-zero parameter
+func (s *SHA3TweakableHash) ValidateParameter(p th.Params) error {
+	return validateNonZero(p)
+}
+
+// validateNonZero returns an error wrapping th.ErrDegenerateParameter if
+// every byte of p is zero
+func validateNonZero(p th.Params) error {
+	for _, b := range p {
+		if b != 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: all %d bytes are zero", th.ErrDegenerateParameter, len(p))
+}
+
 // RandDomain generates a random domain element
 func (s *SHA3TweakableHash) RandDomain(rng io.Reader) th.Domain {
 	d := make([]byte, s.hashLen)
@@ -60,6 +100,13 @@ func (s *SHA3TweakableHash) ChainTweak(epoch uint32, chainIndex uint8, posInChai
 
 // Apply computes Th: Truncate_n_bits(SHA3(P||T||M))
 func (s *SHA3TweakableHash) Apply(parameter th.Params, tweak th.Tweak, message []th.Domain) th.Domain {
+	out := make(th.Domain, s.hashLen)
+	s.ApplyInto(out, parameter, tweak, message)
+	return out
+}
+
+// ApplyInto computes Apply into dst
+func (s *SHA3TweakableHash) ApplyInto(dst []byte, parameter th.Params, tweak th.Tweak, message []th.Domain) {
 	h := sha3.New256()
 	
 	// Write P || T || M
@@ -69,9 +116,38 @@ func (s *SHA3TweakableHash) Apply(parameter th.Params, tweak th.Tweak, message [
 		h.Write(m)
 	}
 	
-	// Get full hash and truncate to hashLen bytes
-	fullHash := h.Sum(nil)
-	return truncateBytes(fullHash, s.hashLen)
+	// Copy the first len(dst) = hashLen bytes of the digest
+	var fullHash [32]byte
+	copy(dst, h.Sum(fullHash[:0]))
+}
+
+// Clone returns a copy of the hash
+func (s *SHA3TweakableHash) Clone() th.TweakableHash {
+	clone := *s
+	return &clone
+}
+
+// ApplyBatch computes Apply for every (tweak, message) pair, reusing
+// one SHA3 state per worker instead of allocating one per call
+func (s *SHA3TweakableHash) ApplyBatch(parameter th.Params, tweaks []th.Tweak, messages [][]th.Domain) []th.Domain {
+	if len(tweaks) != len(messages) {
+		panic("tweaks and messages must have the same length")
+	}
+	
+	out := make([]th.Domain, len(tweaks))
+	runBatch(len(tweaks), func() func(i int) {
+		h := sha3.New256()
+		return func(i int) {
+			h.Reset()
+			h.Write(parameter)
+			h.Write(tweaks[i])
+			for _, m := range messages[i] {
+				h.Write(m)
+			}
+			out[i] = truncateBytes(h.Sum(nil), s.hashLen)
+		}
+	})
+	return out
 }
 
 // OutputLen returns the output length in bytes
@@ -3,6 +3,9 @@ package tweak_hash
 import (
 	"bytes"
 	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"testing"
 	
 	"github.com/aerius-labs/hash-sig-go/th"
@@ -134,3 +137,209 @@ func BenchmarkSHA3Apply(b *testing.B) {
 		thash.Apply(param, tweak, []th.Domain{msg1, msg2})
 	}
 }
+
+// Test that hash lengths beyond the SHA3-256 digest are rejected
+func TestSHA3RejectsLongHashLen(t *testing.T) {
+	NewSHA3TweakableHash(16, 32)
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected a panic for a 33-byte hash length")
+		}
+	}()
+	NewSHA3TweakableHash(16, 33)
+}
+
+// Test that ApplyBatch matches per-call Apply exactly
+func TestSHA3ApplyBatchMatchesApply(t *testing.T) {
+	thash := NewSHA3TweakableHash(16, 24)
+	param := thash.RandParameter(rand.Reader)
+	
+	// Cover both the sequential and the parallel batch paths
+	for _, n := range []int{0, 1, 7, batchParallelThreshold + 33} {
+		tweaks := make([]th.Tweak, n)
+		messages := make([][]th.Domain, n)
+		for i := 0; i < n; i++ {
+			tweaks[i] = thash.TreeTweak(1, uint32(i))
+			messages[i] = []th.Domain{thash.RandDomain(rand.Reader), thash.RandDomain(rand.Reader)}
+		}
+		
+		results := thash.ApplyBatch(param, tweaks, messages)
+		if len(results) != n {
+			t.Fatalf("Expected %d results, got %d", n, len(results))
+		}
+		for i := 0; i < n; i++ {
+			if !bytes.Equal(results[i], thash.Apply(param, tweaks[i], messages[i])) {
+				t.Fatalf("Batch result %d differs from Apply (batch size %d)", i, n)
+			}
+		}
+	}
+}
+
+// Test that ApplyInto writes exactly what Apply returns, for every backend
+// and for Poseidon with a prepared parameter, into a reused buffer
+func TestApplyIntoMatchesApply(t *testing.T) {
+	hashes := map[string]th.TweakableHash{
+		"SHA3":     NewSHA3TweakableHash(16, 24),
+		"SHA3Full": NewSHA3TweakableHash(16, 32),
+		"Blake3":   NewBlake3TweakableHash(16, 24),
+		"Poseidon": NewPoseidonTweakHash(5, 7, 2, 9, 39),
+	}
+	
+	for name, thash := range hashes {
+		t.Run(name, func(t *testing.T) {
+			if _, ok := thash.(th.IntoApplier); !ok {
+				t.Fatal("Does not implement IntoApplier")
+			}
+			param := thash.RandParameter(rand.Reader)
+			prepared := th.WithPreparedParams(thash, param)
+			dst := make([]byte, thash.OutputLen())
+			
+			for i := 0; i < 4; i++ {
+				tweak := thash.TreeTweak(1, uint32(i))
+				message := []th.Domain{thash.RandDomain(rand.Reader), thash.RandDomain(rand.Reader)}
+				want := thash.Apply(param, tweak, message)
+				
+				th.ApplyInto(thash, dst, param, tweak, message)
+				if !bytes.Equal(dst, want) {
+					t.Fatalf("ApplyInto differs from Apply at call %d", i)
+				}
+				th.ApplyInto(prepared, dst, param, tweak, message)
+				if !bytes.Equal(dst, want) {
+					t.Fatalf("Prepared ApplyInto differs from Apply at call %d", i)
+				}
+			}
+		})
+	}
+}
+
+// Test that clones are distinct instances hashing exactly like their
+// originals, and that a tracing clone keeps its own trace
+func TestClone(t *testing.T) {
+	poseidon := NewPoseidonTweakHash(5, 7, 2, 9, 39)
+	tracing := NewTracingPoseidonTweakHash(poseidon)
+	hashes := map[string]th.TweakableHash{
+		"SHA3":     NewSHA3TweakableHash(16, 24),
+		"Blake3":   NewBlake3TweakableHash(16, 24),
+		"Poseidon": poseidon,
+		"Tracing":  tracing,
+	}
+	
+	for name, thash := range hashes {
+		t.Run(name, func(t *testing.T) {
+			param := thash.RandParameter(rand.Reader)
+			tweak := thash.ChainTweak(4, 2, 1)
+			message := []th.Domain{thash.RandDomain(rand.Reader)}
+			want := thash.Apply(param, tweak, message)
+			
+			clone := th.Clone(thash)
+			if clone == thash {
+				t.Fatal("Clone returned the original instance")
+			}
+			if !bytes.Equal(clone.Apply(param, tweak, message), want) {
+				t.Fatal("Clone hashes differently")
+			}
+			prepared := th.Clone(th.WithPreparedParams(thash, param))
+			if !bytes.Equal(prepared.Apply(param, tweak, message), want) {
+				t.Fatal("Clone of a prepared hash hashes differently")
+			}
+		})
+	}
+	
+	tracing.Reset()
+	clone := tracing.Clone().(*TracingPoseidonTweakHash)
+	clone.Apply(clone.RandParameter(rand.Reader), clone.TreeTweak(1, 0), []th.Domain{clone.RandDomain(rand.Reader)})
+	if len(tracing.Trace()) != 0 || len(clone.Trace()) != 1 {
+		t.Fatalf("Traces not independent: original %d, clone %d", len(tracing.Trace()), len(clone.Trace()))
+	}
+}
+
+// Test that ParameterFromSeed is deterministic, seed-dependent and pinned
+func TestParameterFromSeed(t *testing.T) {
+	thash := NewSHA3_128_192()
+	param := thash.ParameterFromSeed([]byte("seed"))
+	if want, _ := hex.DecodeString("caa1422b5d65c930c22b118e0f39b601"); !bytes.Equal(param, want) {
+		t.Fatalf("Parameter changed: got %x, want %x", param, want)
+	}
+	if !bytes.Equal(thash.ParameterFromSeed([]byte("seed")), param) {
+		t.Fatal("ParameterFromSeed is not deterministic")
+	}
+	if bytes.Equal(thash.ParameterFromSeed([]byte("seed2")), param) {
+		t.Fatal("Different seeds gave the same parameter")
+	}
+	
+	// Other backends expand the same stream to their own lengths
+	if b3 := NewBlake3_192_192().ParameterFromSeed([]byte("seed")); len(b3) != 24 || !bytes.Equal(b3[:16], param) {
+		t.Fatalf("Blake3 parameter %x is not the 24-byte expansion of the seed", b3)
+	}
+	
+	// Poseidon parameters come out canonical
+	poseidon := NewPoseidonTweakHash(5, 7, 2, 9, 32)
+	pp := poseidon.ParameterFromSeed([]byte("seed"))
+	if err := th.ValidateParameterCanonical(poseidon, pp); err != nil {
+		t.Fatalf("Poseidon parameter not canonical: %v", err)
+	}
+	if !bytes.Equal(poseidon.ParameterFromSeed([]byte("seed")), pp) {
+		t.Fatal("Poseidon ParameterFromSeed is not deterministic")
+	}
+}
+
+// Test that ValidateParameter rejects exactly the degenerate parameters
+func TestValidateParameter(t *testing.T) {
+	poseidon := NewPoseidonTweakHash(5, 7, 2, 9, 39)
+	hashes := map[string]th.TweakableHash{
+		"SHA3":     NewSHA3TweakableHash(16, 24),
+		"Blake3":   NewBlake3TweakableHash(16, 24),
+		"Poseidon": poseidon,
+		"Tracing":  NewTracingPoseidonTweakHash(poseidon),
+	}
+	
+	for name, thash := range hashes {
+		t.Run(name, func(t *testing.T) {
+			if err := th.ValidateParameter(thash, thash.RandParameter(rand.Reader)); err != nil {
+				t.Fatalf("Random parameter rejected: %v", err)
+			}
+			zero := make(th.Params, thash.ParameterLen())
+			if err := th.ValidateParameter(thash, zero); !errors.Is(err, th.ErrDegenerateParameter) {
+				t.Fatalf("Expected ErrDegenerateParameter for zeros, got %v", err)
+			}
+			zero[len(zero)-1] = 1
+			if err := th.ValidateParameter(thash, zero); err != nil {
+				t.Fatalf("Parameter with a non-zero byte rejected: %v", err)
+			}
+		})
+	}
+	
+	// p is a non-canonical encoding of zero
+	param := make(th.Params, poseidon.ParameterLen())
+	for i := 0; i < len(param); i += 4 {
+		binary.BigEndian.PutUint32(param[i:], uint32(P))
+	}
+	if err := poseidon.ValidateParameter(param); !errors.Is(err, th.ErrDegenerateParameter) {
+		t.Fatalf("Expected ErrDegenerateParameter for encodings of zero, got %v", err)
+	}
+}
+
+// Test that Chain and ChainInto equal repeated Apply for SHA3 hash lengths
+// up to the full 32-byte digest, with no stale bytes left in dst
+func TestSHA3ChainMatchesApply(t *testing.T) {
+	for _, hashLen := range []int{17, 24, 32} {
+		thash := NewSHA3TweakableHash(16, hashLen)
+		param := thash.RandParameter(rand.Reader)
+		start := thash.RandDomain(rand.Reader)
+		
+		const epoch, chainIndex, startPos, steps = 7, 3, 2, 9
+		want := start
+		for i := 0; i < steps; i++ {
+			want = thash.Apply(param, thash.ChainTweak(epoch, chainIndex, uint8(startPos+i+1)), []th.Domain{want})
+		}
+		
+		if got := th.Chain(thash, param, epoch, chainIndex, startPos, steps, start); !bytes.Equal(got, want) {
+			t.Fatalf("hashLen %d: Chain = %x, repeated Apply = %x", hashLen, got, want)
+		}
+		dst := bytes.Repeat([]byte{0xff}, thash.OutputLen())
+		th.ChainInto(dst, thash, param, epoch, chainIndex, startPos, steps, start)
+		if !bytes.Equal(dst, want) {
+			t.Fatalf("hashLen %d: ChainInto = %x, repeated Apply = %x", hashLen, dst, want)
+		}
+	}
+}
@@ -1,8 +1,12 @@
 package th
 
 import (
+	"bytes"
 	"crypto/rand"
+	"errors"
+	"fmt"
 	"io"
+	"math"
 )
 
 // MessageLength is the fixed length of messages to sign (32 bytes)
@@ -51,6 +55,221 @@ type TweakableHash interface {
 	ParameterLen() int
 }
 
+// BatchTweakableHash is an optional extension of TweakableHash for
+// implementations that can amortize setup across many Apply calls.
+// Merkle tree construction detects it and hashes each level in one batch.
+type BatchTweakableHash interface {
+	TweakableHash
+
+	// ApplyBatch computes Apply(parameter, tweaks[i], messages[i]) for every i.
+	// Results must be identical to calling Apply individually.
+	ApplyBatch(parameter Params, tweaks []Tweak, messages [][]Domain) []Domain
+}
+
+// Cloner is an optional extension of TweakableHash for implementations
+// that can hand out independent copies of themselves.
+//
+// The hashes in this module keep no mutable state outside their
+// constructors, except for the trace of a tracing wrapper, so every method
+// of a shared instance, including those of the optional interfaces, is safe
+// to call from many goroutines at once. A clone additionally shares nothing
+// mutable with its original, so a worker may wrap, prepare or cache state
+// around its own clone without locking or affecting other workers.
+type Cloner interface {
+	// Clone returns an independent instance that hashes exactly like the receiver
+	Clone() TweakableHash
+}
+
+// Clone returns h.Clone() if h implements Cloner, and h otherwise
+func Clone(h TweakableHash) TweakableHash {
+	if cloner, ok := h.(Cloner); ok {
+		return cloner.Clone()
+	}
+	return h
+}
+
+// IntoApplier is an optional extension of TweakableHash for implementations
+// that can write their output into a caller-provided buffer, sparing hot
+// loops (chain walks, tree building) an allocation per hash
+type IntoApplier interface {
+	// ApplyInto computes Apply(parameter, tweak, message) into dst, which
+	// has length OutputLen() and does not overlap message
+	ApplyInto(dst []byte, parameter Params, tweak Tweak, message []Domain)
+}
+
+// ApplyInto computes h.Apply(parameter, tweak, message) into dst, using
+// IntoApplier if h implements it and copying Apply's result otherwise.
+// It panics if len(dst) is not h.OutputLen(). dst must not overlap message.
+func ApplyInto(h TweakableHash, dst []byte, parameter Params, tweak Tweak, message []Domain) {
+	if len(dst) != h.OutputLen() {
+		panic(fmt.Sprintf("output buffer has %d bytes, OutputLen is %d", len(dst), h.OutputLen()))
+	}
+	if applier, ok := h.(IntoApplier); ok {
+		applier.ApplyInto(dst, parameter, tweak, message)
+		return
+	}
+	copy(dst, h.Apply(parameter, tweak, message))
+}
+
+// PreparedParams is an opaque, implementation-specific precomputed form
+// of a public parameter, produced by ParamPreparer.Prepare
+type PreparedParams interface{}
+
+// ParamPreparer is an optional extension of TweakableHash for implementations
+// that can precompute a fixed parameter once (e.g. its field-element form)
+// and reuse it across many Apply calls
+type ParamPreparer interface {
+	// Prepare precomputes the given parameter
+	Prepare(parameter Params) PreparedParams
+	
+	// ApplyPrepared computes Apply(parameter, tweak, message) for the
+	// parameter the handle was prepared from
+	ApplyPrepared(prepared PreparedParams, tweak Tweak, message []Domain) Domain
+}
+
+// PreparedIntoApplier is IntoApplier for prepared parameters
+type PreparedIntoApplier interface {
+	// ApplyPreparedInto computes ApplyPrepared(prepared, tweak, message)
+	// into dst, under the same conditions as IntoApplier.ApplyInto
+	ApplyPreparedInto(dst []byte, prepared PreparedParams, tweak Tweak, message []Domain)
+}
+
+// DomainCanonicalizer is an optional extension of TweakableHash for
+// implementations whose domain elements have several byte encodings of the
+// same value (e.g. field elements read modulo p)
+type DomainCanonicalizer interface {
+	// CanonicalDomain returns the canonical encoding of d. Elements of
+	// the wrong length are returned unchanged.
+	CanonicalDomain(d Domain) Domain
+}
+
+// ParameterCanonicalChecker is an optional extension of TweakableHash for
+// implementations whose parameters have a canonical encoding
+type ParameterCanonicalChecker interface {
+	// CheckParameterCanonical returns an error if p is not canonically encoded
+	CheckParameterCanonical(p Params) error
+}
+
+// ErrNonCanonicalParameter indicates a parameter of the wrong length or
+// with a non-canonical encoding
+var ErrNonCanonicalParameter = errors.New("non-canonical parameter")
+
+// ValidateParameterCanonical checks that p has length h.ParameterLen() and,
+// if h implements ParameterCanonicalChecker, that it is canonically encoded.
+// A non-canonical parameter may hash like its canonical form in one
+// component and differently in another, so decoders of untrusted keys
+// should reject it.
+func ValidateParameterCanonical(h TweakableHash, p Params) error {
+	if len(p) != h.ParameterLen() {
+		return fmt.Errorf("%w: length %d, expected %d", ErrNonCanonicalParameter, len(p), h.ParameterLen())
+	}
+	if checker, ok := h.(ParameterCanonicalChecker); ok {
+		return checker.CheckParameterCanonical(p)
+	}
+	return nil
+}
+
+// ParameterValidator is an optional extension of TweakableHash for
+// implementations that can recognise degenerate parameters, such as the
+// all-zero parameter a broken RNG would produce
+type ParameterValidator interface {
+	// ValidateParameter returns an error wrapping ErrDegenerateParameter
+	// if p is degenerate
+	ValidateParameter(p Params) error
+}
+
+// ErrDegenerateParameter indicates a parameter no healthy RNG would
+// plausibly produce, e.g. all zeros
+var ErrDegenerateParameter = errors.New("degenerate parameter")
+
+// ValidateParameter checks p with h's ValidateParameter, if h implements
+// ParameterValidator
+func ValidateParameter(h TweakableHash, p Params) error {
+	if validator, ok := h.(ParameterValidator); ok {
+		return validator.ValidateParameter(p)
+	}
+	return nil
+}
+
+// preparedHash binds a prepared parameter to a tweakable hash
+type preparedHash struct {
+	TweakableHash
+	preparer  ParamPreparer
+	parameter Params
+	prepared  PreparedParams
+}
+
+// WithPreparedParams returns a TweakableHash that behaves exactly like h but
+// takes the prepared fast path whenever Apply is called with parameter.
+// If h does not implement ParamPreparer, h is returned unchanged.
+func WithPreparedParams(h TweakableHash, parameter Params) TweakableHash {
+	preparer, ok := h.(ParamPreparer)
+	if !ok {
+		return h
+	}
+	prepared := &preparedHash{
+		TweakableHash: h,
+		preparer:      preparer,
+		parameter:     parameter,
+		prepared:      preparer.Prepare(parameter),
+	}
+	if batch, ok := h.(BatchTweakableHash); ok {
+		return &preparedBatchHash{preparedHash: prepared, batch: batch}
+	}
+	return prepared
+}
+
+// Unprepared returns the hash WithPreparedParams wrapped, or h itself if it
+// is not such a wrapper
+func Unprepared(h TweakableHash) TweakableHash {
+	switch p := h.(type) {
+	case *preparedHash:
+		return p.TweakableHash
+	case *preparedBatchHash:
+		return p.TweakableHash
+	}
+	return h
+}
+
+// Apply uses the prepared parameter when it matches, and the plain path otherwise
+func (h *preparedHash) Apply(parameter Params, tweak Tweak, message []Domain) Domain {
+	if bytes.Equal(parameter, h.parameter) {
+		return h.preparer.ApplyPrepared(h.prepared, tweak, message)
+	}
+	return h.TweakableHash.Apply(parameter, tweak, message)
+}
+
+// Clone prepares the parameter again for a clone of the underlying hash
+func (h *preparedHash) Clone() TweakableHash {
+	return WithPreparedParams(Clone(h.TweakableHash), h.parameter)
+}
+
+// ApplyInto is Apply writing into dst
+func (h *preparedHash) ApplyInto(dst []byte, parameter Params, tweak Tweak, message []Domain) {
+	if bytes.Equal(parameter, h.parameter) {
+		if into, ok := h.preparer.(PreparedIntoApplier); ok {
+			into.ApplyPreparedInto(dst, h.prepared, tweak, message)
+		} else {
+			copy(dst, h.preparer.ApplyPrepared(h.prepared, tweak, message))
+		}
+		return
+	}
+	ApplyInto(h.TweakableHash, dst, parameter, tweak, message)
+}
+
+// preparedBatchHash is a preparedHash over a BatchTweakableHash, so Merkle
+// tree construction keeps hashing whole levels in one batch
+type preparedBatchHash struct {
+	*preparedHash
+	batch BatchTweakableHash
+}
+
+// ApplyBatch hands the batch to the underlying hash, which already sets up
+// the parameter once per batch
+func (h *preparedBatchHash) ApplyBatch(parameter Params, tweaks []Tweak, messages [][]Domain) []Domain {
+	return h.batch.ApplyBatch(parameter, tweaks, messages)
+}
+
 // MessageHasher extends TweakableHash for message hashing operations
 type MessageHasher interface {
 	// DigestChunks returns ℓ chunks, each w bits (packed), as required by the encoding
@@ -62,19 +281,57 @@ type MessageHasher interface {
 }
 
 // Chain implements hash chains (Construction 2 from the paper)
-// Walks a chain for 'steps' starting from 'start' at position 'startPosInChain'
+// Walks a chain for 'steps' starting from 'start' at position 'startPosInChain'.
+// Chain tweaks encode positions in one byte, so Chain panics if the walk
+// would go past position 255 instead of wrapping the position around.
 func Chain(th TweakableHash, parameter Params, epoch uint32, chainIndex uint8, 
 	startPosInChain uint8, steps int, start Domain) Domain {
 	
-	current := make(Domain, len(start))
-	copy(current, start)
+	if steps <= 0 {
+		checkChainWalk(startPosInChain, steps)
+		return bytes.Clone(start)
+	}
+	dst := make(Domain, th.OutputLen())
+	ChainInto(dst, th, parameter, epoch, chainIndex, startPosInChain, steps, start)
+	return dst
+}
+
+// ChainInto is Chain writing the chain value into dst, which has length
+// OutputLen() and does not overlap start. The walk alternates between dst
+// and one scratch buffer, so it allocates no output per step.
+func ChainInto(dst Domain, th TweakableHash, parameter Params, epoch uint32, chainIndex uint8, 
+	startPosInChain uint8, steps int, start Domain) {
+	
+	checkChainWalk(startPosInChain, steps)
+	if steps <= 0 {
+		copy(dst, start)
+		return
+	}
 	
-	for j := 0; j < steps; j++ {
-		tweak := th.ChainTweak(epoch, chainIndex, startPosInChain+uint8(j)+1)
-		current = th.Apply(parameter, tweak, []Domain{current})
+	// The value at each step is the message of the next. The last step
+	// must write dst, so with an even number of steps the first writes
+	// the scratch buffer.
+	out, spare := dst, make(Domain, len(dst))
+	if steps%2 == 0 {
+		out, spare = spare, out
 	}
+	message := []Domain{start}
 	
-	return current
+	// Positions are computed as int so that they cannot wrap
+	for pos := int(startPosInChain) + 1; pos <= int(startPosInChain)+steps; pos++ {
+		tweak := th.ChainTweak(epoch, chainIndex, uint8(pos))
+		ApplyInto(th, out, parameter, tweak, message)
+		message[0] = out
+		out, spare = spare, out
+	}
+}
+
+// checkChainWalk panics if a walk of steps from startPosInChain would pass
+// the last position a chain tweak can encode
+func checkChainWalk(startPosInChain uint8, steps int) {
+	if int(startPosInChain)+steps > math.MaxUint8 {
+		panic(fmt.Sprintf("chain walk of %d steps from position %d passes position %d", steps, startPosInChain, math.MaxUint8))
+	}
 }
 
 // Helper to generate random bytes
@@ -0,0 +1,322 @@
+package xmss
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/aerius-labs/hash-sig-go/merkle"
+	"github.com/aerius-labs/hash-sig-go/th"
+)
+
+// Binary signature wire-format versions. The first byte of every encoded
+// signature is its version, so decoders can keep accepting old signatures
+// after the format evolves.
+const (
+	SignatureFormatV1 byte = 1
+
+	// CurrentSignatureFormat is the version written by MarshalBinary
+	CurrentSignatureFormat = SignatureFormatV1
+)
+
+var (
+	// ErrUnsupportedVersion indicates an unknown wire-format version byte
+	ErrUnsupportedVersion = errors.New("unsupported wire-format version")
+
+	// ErrInvalidBinary indicates malformed binary input
+	ErrInvalidBinary = errors.New("invalid binary encoding")
+)
+
+// MarshalBinary encodes the signature in the current binary wire format.
+//
+// Version 1 layout (integers are little-endian uint32):
+//
+//	version || len(rho) || rho ||
+//	len(CoPath) || nodeLen || CoPath nodes ||
+//	len(Hashes) || hashLen || Hashes
+func (sig *Signature) MarshalBinary() ([]byte, error) {
+	return marshalSignatureV1(sig)
+}
+
+// UnmarshalBinary decodes a signature in any supported wire-format version
+func (sig *Signature) UnmarshalBinary(data []byte) error {
+	decoded, err := UnmarshalSignatureVersioned(data)
+	if err != nil {
+		return err
+	}
+	*sig = *decoded
+	return nil
+}
+
+// UnmarshalSignatureVersioned decodes a binary signature, dispatching on its
+// leading version byte
+func UnmarshalSignatureVersioned(data []byte) (*Signature, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: empty input", ErrInvalidBinary)
+	}
+
+	switch data[0] {
+	case SignatureFormatV1:
+		return unmarshalSignatureV1(data[1:])
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, data[0])
+	}
+}
+
+// marshalSignatureV1 encodes a signature in wire-format version 1
+func marshalSignatureV1(sig *Signature) ([]byte, error) {
+	out := []byte{SignatureFormatV1}
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(sig.Rho)))
+	out = append(out, sig.Rho...)
+
+	var err error
+	if out, err = appendDomainsV1(out, sig.Path.CoPath); err != nil {
+		return nil, fmt.Errorf("co-path: %w", err)
+	}
+	if out, err = appendDomainsV1(out, sig.Hashes); err != nil {
+		return nil, fmt.Errorf("hashes: %w", err)
+	}
+	return out, nil
+}
+
+// unmarshalSignatureV1 decodes the body of a version 1 signature
+func unmarshalSignatureV1(data []byte) (*Signature, error) {
+	r := &binaryReader{data: data}
+
+	rhoLen, err := r.readUint32()
+	if err != nil {
+		return nil, err
+	}
+	rho, err := r.readBytes(int(rhoLen))
+	if err != nil {
+		return nil, err
+	}
+
+	coPath, err := r.readDomainsV1()
+	if err != nil {
+		return nil, err
+	}
+	hashes, err := r.readDomainsV1()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.finish(); err != nil {
+		return nil, err
+	}
+
+	sig := &Signature{
+		Rho:    rho,
+		Hashes: hashes,
+	}
+	sig.Path.CoPath = coPath
+	return sig, nil
+}
+
+// SecretKeyFormatV1 is the first binary secret-key wire-format version
+const SecretKeyFormatV1 byte = 1
+
+// MarshalBinary encodes the secret key, including its full Merkle tree.
+//
+// Version 1 layout (integers are little-endian uint32):
+//
+//	version || len(PRFKey) || PRFKey || len(Parameter) || Parameter ||
+//	ActivationEpoch || NumActiveEpochs || depth || numLayers ||
+//	per layer: startIndex || len(nodes) || nodeLen || nodes
+func (sk *SecretKey) MarshalBinary() ([]byte, error) {
+	if sk.destroyed {
+		return nil, ErrKeyDestroyed
+	}
+	if sk.ActivationEpoch < 0 || sk.NumActiveEpochs < 0 {
+		return nil, fmt.Errorf("negative activation range [%d, +%d)", sk.ActivationEpoch, sk.NumActiveEpochs)
+	}
+	if sk.Tree == nil {
+		return nil, errors.New("secret key has no tree")
+	}
+	
+	out := make([]byte, 0, sk.SerializedSize())
+	out = append(out, SecretKeyFormatV1)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(sk.PRFKey)))
+	out = append(out, sk.PRFKey...)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(sk.Parameter)))
+	out = append(out, sk.Parameter...)
+	out = binary.LittleEndian.AppendUint32(out, uint32(sk.ActivationEpoch))
+	out = binary.LittleEndian.AppendUint32(out, uint32(sk.NumActiveEpochs))
+	
+	layers := sk.Tree.GetLayers()
+	out = binary.LittleEndian.AppendUint32(out, uint32(sk.Tree.GetDepth()))
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(layers)))
+	for i := range layers {
+		out = binary.LittleEndian.AppendUint32(out, uint32(layers[i].GetStartIndex()))
+		var err error
+		if out, err = appendDomainsV1(out, layers[i].GetNodes()); err != nil {
+			return nil, fmt.Errorf("layer %d: %w", i, err)
+		}
+	}
+	return out, nil
+}
+
+// SerializedSize returns len(sk.MarshalBinary()) without serializing
+func (sk *SecretKey) SerializedSize() int {
+	// Version, two length-prefixed byte strings, activation range, depth, layer count
+	size := 1 + 4 + len(sk.PRFKey) + 4 + len(sk.Parameter) + 4 + 4 + 4 + 4
+	if sk.Tree == nil {
+		return size
+	}
+	
+	layers := sk.Tree.GetLayers()
+	for i := range layers {
+		nodes := layers[i].GetNodes()
+		// Start index, node count, node length
+		size += 4 + 4 + 4
+		if len(nodes) > 0 {
+			size += len(nodes) * len(nodes[0])
+		}
+	}
+	return size
+}
+
+// UnmarshalSecretKeyBinary decodes a secret key produced by MarshalBinary.
+// Like UnmarshalSecretKey, it needs the tweakable hash to rebuild the tree.
+func UnmarshalSecretKeyBinary(data []byte, thash th.TweakableHash) (*SecretKey, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: empty input", ErrInvalidBinary)
+	}
+	if data[0] != SecretKeyFormatV1 {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, data[0])
+	}
+	r := &binaryReader{data: data[1:]}
+	
+	prfKeyLen, err := r.readUint32()
+	if err != nil {
+		return nil, err
+	}
+	prfKey, err := r.readBytes(int(prfKeyLen))
+	if err != nil {
+		return nil, err
+	}
+	paramLen, err := r.readUint32()
+	if err != nil {
+		return nil, err
+	}
+	param, err := r.readBytes(int(paramLen))
+	if err != nil {
+		return nil, err
+	}
+	
+	var header [4]uint32 // activation epoch, active epochs, depth, layer count
+	for i := range header {
+		if header[i], err = r.readUint32(); err != nil {
+			return nil, err
+		}
+	}
+	// Every layer costs at least its 12-byte header, which bounds the allocation
+	if uint64(header[3])*12 > uint64(len(r.data)-r.pos) {
+		return nil, fmt.Errorf("%w: %d layers exceed input", ErrInvalidBinary, header[3])
+	}
+	
+	layers := make([]merkle.HashTreeLayer, header[3])
+	for i := range layers {
+		startIndex, err := r.readUint32()
+		if err != nil {
+			return nil, err
+		}
+		nodes, err := r.readDomainsV1()
+		if err != nil {
+			return nil, err
+		}
+		layers[i] = merkle.NewHashTreeLayer(int(startIndex), nodes)
+	}
+	
+	if err := r.finish(); err != nil {
+		return nil, err
+	}
+	
+	return &SecretKey{
+		PRFKey:          prfKey,
+		Tree:            merkle.NewHashTreeFromLayers(int(header[2]), layers, param, thash),
+		Parameter:       param,
+		ActivationEpoch: int(header[0]),
+		NumActiveEpochs: int(header[1]),
+	}, nil
+}
+
+// appendDomainsV1 appends count || elementLen || elements. All elements
+// must have the same length.
+func appendDomainsV1(out []byte, domains []th.Domain) ([]byte, error) {
+	elemLen := 0
+	if len(domains) > 0 {
+		elemLen = len(domains[0])
+	}
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(domains)))
+	out = binary.LittleEndian.AppendUint32(out, uint32(elemLen))
+	for i, d := range domains {
+		if len(d) != elemLen {
+			return nil, fmt.Errorf("element %d has length %d, expected %d", i, len(d), elemLen)
+		}
+		out = append(out, d...)
+	}
+	return out, nil
+}
+
+// binaryReader is a bounds-checked cursor over binary input
+type binaryReader struct {
+	data []byte
+	pos  int
+}
+
+// readUint32 reads a little-endian uint32
+func (r *binaryReader) readUint32() (uint32, error) {
+	if len(r.data)-r.pos < 4 {
+		return 0, fmt.Errorf("%w: unexpected end of input", ErrInvalidBinary)
+	}
+	v := binary.LittleEndian.Uint32(r.data[r.pos:])
+	r.pos += 4
+	return v, nil
+}
+
+// readBytes reads n bytes into a fresh slice
+func (r *binaryReader) readBytes(n int) ([]byte, error) {
+	if n < 0 || n > len(r.data)-r.pos {
+		return nil, fmt.Errorf("%w: length %d exceeds input", ErrInvalidBinary, n)
+	}
+	b := make([]byte, n)
+	copy(b, r.data[r.pos:])
+	r.pos += n
+	return b, nil
+}
+
+// readDomainsV1 reads count || elementLen || elements
+func (r *binaryReader) readDomainsV1() ([]th.Domain, error) {
+	count, err := r.readUint32()
+	if err != nil {
+		return nil, err
+	}
+	elemLen, err := r.readUint32()
+	if err != nil {
+		return nil, err
+	}
+	// Empty elements are never valid, so every element costs at least one byte
+	if elemLen == 0 && count > 0 {
+		return nil, fmt.Errorf("%w: zero-length elements", ErrInvalidBinary)
+	}
+	if uint64(count)*uint64(elemLen) > uint64(len(r.data)-r.pos) {
+		return nil, fmt.Errorf("%w: %d elements of %d bytes exceed input", ErrInvalidBinary, count, elemLen)
+	}
+
+	domains := make([]th.Domain, count)
+	for i := range domains {
+		if domains[i], err = r.readBytes(int(elemLen)); err != nil {
+			return nil, err
+		}
+	}
+	return domains, nil
+}
+
+// finish checks that the whole input has been consumed
+func (r *binaryReader) finish() error {
+	if r.pos != len(r.data) {
+		return fmt.Errorf("%w: %d trailing bytes", ErrInvalidBinary, len(r.data)-r.pos)
+	}
+	return nil
+}
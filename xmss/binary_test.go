@@ -0,0 +1,113 @@
+package xmss
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/aerius-labs/hash-sig-go/encoding/winternitz"
+	"github.com/aerius-labs/hash-sig-go/internal/prf"
+	"github.com/aerius-labs/hash-sig-go/th/message_hash"
+	"github.com/aerius-labs/hash-sig-go/th/tweak_hash"
+)
+
+func TestSignatureBinaryVersioned(t *testing.T) {
+	prfInstance := prf.NewSHA3PRF(24, 24)
+	thInstance := tweak_hash.NewSHA3TweakableHash(24, 24)
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	encInstance := winternitz.NewWinternitzEncoding(mhInstance, 4, 3)
+
+	xmss := NewGeneralizedXMSS(prfInstance, encInstance, thInstance, 5)
+	pk, sk := xmss.KeyGen(rand.Reader, 0, 32)
+
+	message := make([]byte, 32)
+	rand.Read(message)
+
+	sig, err := xmss.Sign(rand.Reader, sk, 3, message)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	encoded, err := sig.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	if encoded[0] != SignatureFormatV1 {
+		t.Fatalf("Expected version byte %d, got %d", SignatureFormatV1, encoded[0])
+	}
+
+	// A version-1 signature decodes with the versioned decoder and still verifies
+	decoded, err := UnmarshalSignatureVersioned(encoded)
+	if err != nil {
+		t.Fatalf("UnmarshalSignatureVersioned failed: %v", err)
+	}
+	if !bytes.Equal(decoded.Rho, sig.Rho) || len(decoded.Hashes) != len(sig.Hashes) ||
+		len(decoded.Path.CoPath) != len(sig.Path.CoPath) {
+		t.Fatal("Decoded signature does not match the original")
+	}
+	if !xmss.Verify(pk, 3, message, decoded) {
+		t.Fatal("Decoded signature failed to verify")
+	}
+
+	// Unknown version bytes are rejected
+	unknown := append([]byte{}, encoded...)
+	unknown[0] = 0xee
+	if _, err := UnmarshalSignatureVersioned(unknown); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("Expected ErrUnsupportedVersion, got %v", err)
+	}
+
+	// Truncated and padded inputs are rejected
+	if _, err := UnmarshalSignatureVersioned(encoded[:len(encoded)-1]); !errors.Is(err, ErrInvalidBinary) {
+		t.Fatalf("Expected ErrInvalidBinary for truncated input, got %v", err)
+	}
+	if _, err := UnmarshalSignatureVersioned(append(encoded, 0)); !errors.Is(err, ErrInvalidBinary) {
+		t.Fatalf("Expected ErrInvalidBinary for trailing data, got %v", err)
+	}
+	if _, err := UnmarshalSignatureVersioned(nil); !errors.Is(err, ErrInvalidBinary) {
+		t.Fatalf("Expected ErrInvalidBinary for empty input, got %v", err)
+	}
+}
+
+func TestSecretKeySerializedSize(t *testing.T) {
+	prfInstance := prf.NewSHA3PRF(24, 24)
+	thInstance := tweak_hash.NewSHA3TweakableHash(24, 24)
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	encInstance := winternitz.NewWinternitzEncoding(mhInstance, 4, 3)
+
+	xmss := NewGeneralizedXMSS(prfInstance, encInstance, thInstance, 5)
+
+	// Cover a full tree and a sparse, padded one
+	for _, activation := range [][2]int{{0, 32}, {3, 10}} {
+		pk, sk := xmss.KeyGen(rand.Reader, activation[0], activation[1])
+
+		encoded, err := sk.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary failed: %v", err)
+		}
+		if sk.SerializedSize() != len(encoded) {
+			t.Fatalf("SerializedSize %d differs from encoded length %d", sk.SerializedSize(), len(encoded))
+		}
+
+		decoded, err := UnmarshalSecretKeyBinary(encoded, thInstance)
+		if err != nil {
+			t.Fatalf("UnmarshalSecretKeyBinary failed: %v", err)
+		}
+
+		// The decoded key still produces valid signatures
+		message := make([]byte, 32)
+		rand.Read(message)
+		epoch := uint32(activation[0] + 1)
+		sig, err := xmss.Sign(rand.Reader, decoded, epoch, message)
+		if err != nil {
+			t.Fatalf("Failed to sign with decoded key: %v", err)
+		}
+		if !xmss.Verify(pk, epoch, message, sig) {
+			t.Fatal("Signature from decoded key failed to verify")
+		}
+
+		if _, err := UnmarshalSecretKeyBinary(encoded[:len(encoded)-1], thInstance); !errors.Is(err, ErrInvalidBinary) {
+			t.Fatalf("Expected ErrInvalidBinary for truncated key, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package xmss
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/aerius-labs/hash-sig-go/th"
+)
+
+// This file hand-rolls the small subset of CBOR (RFC 8949) needed to encode
+// signatures and public keys: definite-length maps with text-string keys,
+// byte strings, and arrays of byte strings. Keys are emitted in canonical
+// (length-first) order so the encoding is deterministic.
+
+// CBOR major types used by the encoder
+const (
+	cborMajorBytes = 2
+	cborMajorText  = 3
+	cborMajorArray = 4
+	cborMajorMap   = 5
+)
+
+// CBOR map keys
+const (
+	cborKeyRho       = "rho"
+	cborKeyPath      = "path"
+	cborKeyHashes    = "hashes"
+	cborKeyRoot      = "root"
+	cborKeyParameter = "parameter"
+)
+
+// ErrInvalidCBOR indicates malformed or unsupported CBOR input
+var ErrInvalidCBOR = errors.New("invalid CBOR encoding")
+
+// MarshalCBOR encodes the signature as a CBOR map
+// {"rho": bstr, "path": [bstr...], "hashes": [bstr...]}
+func (sig *Signature) MarshalCBOR() ([]byte, error) {
+	var out []byte
+	out = cborAppendHead(out, cborMajorMap, 3)
+
+	out = cborAppendText(out, cborKeyRho)
+	out = cborAppendBytes(out, sig.Rho)
+
+	out = cborAppendText(out, cborKeyPath)
+	out = cborAppendDomains(out, sig.Path.CoPath)
+
+	out = cborAppendText(out, cborKeyHashes)
+	out = cborAppendDomains(out, sig.Hashes)
+
+	return out, nil
+}
+
+// UnmarshalCBOR decodes a signature produced by MarshalCBOR
+func (sig *Signature) UnmarshalCBOR(data []byte) error {
+	d := &cborDecoder{data: data}
+	n, err := d.expectHead(cborMajorMap)
+	if err != nil {
+		return err
+	}
+
+	var decoded Signature
+	seen := make(map[string]bool)
+	for i := uint64(0); i < n; i++ {
+		key, err := d.readMapKey(seen)
+		if err != nil {
+			return err
+		}
+		switch key {
+		case cborKeyRho:
+			decoded.Rho, err = d.readBytes()
+		case cborKeyPath:
+			decoded.Path.CoPath, err = d.readDomains()
+		case cborKeyHashes:
+			decoded.Hashes, err = d.readDomains()
+		default:
+			return fmt.Errorf("%w: unknown signature field %q", ErrInvalidCBOR, key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := d.finish(); err != nil {
+		return err
+	}
+	if decoded.Rho == nil || decoded.Path.CoPath == nil || decoded.Hashes == nil {
+		return fmt.Errorf("%w: missing signature field", ErrInvalidCBOR)
+	}
+
+	*sig = decoded
+	return nil
+}
+
+// MarshalCBOR encodes the public key as a CBOR map
+// {"root": bstr, "parameter": bstr}
+func (pk *PublicKey) MarshalCBOR() ([]byte, error) {
+	var out []byte
+	out = cborAppendHead(out, cborMajorMap, 2)
+
+	out = cborAppendText(out, cborKeyRoot)
+	out = cborAppendBytes(out, pk.Root)
+
+	out = cborAppendText(out, cborKeyParameter)
+	out = cborAppendBytes(out, pk.Parameter)
+
+	return out, nil
+}
+
+// UnmarshalCBOR decodes a public key produced by MarshalCBOR
+func (pk *PublicKey) UnmarshalCBOR(data []byte) error {
+	d := &cborDecoder{data: data}
+	n, err := d.expectHead(cborMajorMap)
+	if err != nil {
+		return err
+	}
+
+	var decoded PublicKey
+	seen := make(map[string]bool)
+	for i := uint64(0); i < n; i++ {
+		key, err := d.readMapKey(seen)
+		if err != nil {
+			return err
+		}
+		switch key {
+		case cborKeyRoot:
+			decoded.Root, err = d.readBytes()
+		case cborKeyParameter:
+			decoded.Parameter, err = d.readBytes()
+		default:
+			return fmt.Errorf("%w: unknown public key field %q", ErrInvalidCBOR, key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := d.finish(); err != nil {
+		return err
+	}
+	if decoded.Root == nil || decoded.Parameter == nil {
+		return fmt.Errorf("%w: missing public key field", ErrInvalidCBOR)
+	}
+
+	*pk = decoded
+	return nil
+}
+
+// cborAppendHead appends a CBOR item head with the shortest argument encoding
+func cborAppendHead(out []byte, major byte, n uint64) []byte {
+	m := major << 5
+	switch {
+	case n < 24:
+		return append(out, m|byte(n))
+	case n <= 0xff:
+		return append(out, m|24, byte(n))
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(out, m|25), uint16(n))
+	case n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32(append(out, m|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(out, m|27), n)
+	}
+}
+
+// cborAppendBytes appends a CBOR byte string
+func cborAppendBytes(out []byte, b []byte) []byte {
+	out = cborAppendHead(out, cborMajorBytes, uint64(len(b)))
+	return append(out, b...)
+}
+
+// cborAppendText appends a CBOR text string
+func cborAppendText(out []byte, s string) []byte {
+	out = cborAppendHead(out, cborMajorText, uint64(len(s)))
+	return append(out, s...)
+}
+
+// cborAppendDomains appends an array of byte strings
+func cborAppendDomains(out []byte, domains []th.Domain) []byte {
+	out = cborAppendHead(out, cborMajorArray, uint64(len(domains)))
+	for _, d := range domains {
+		out = cborAppendBytes(out, d)
+	}
+	return out
+}
+
+// cborDecoder reads the CBOR subset produced by the encoder above
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+// readHead reads an item head and returns its major type and argument.
+// Indefinite lengths and reserved additional-info values are rejected.
+func (d *cborDecoder) readHead() (byte, uint64, error) {
+	if d.pos >= len(d.data) {
+		return 0, 0, fmt.Errorf("%w: unexpected end of input", ErrInvalidCBOR)
+	}
+	initial := d.data[d.pos]
+	d.pos++
+	major := initial >> 5
+	info := initial & 0x1f
+
+	var size int
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, 0, fmt.Errorf("%w: unsupported additional info %d", ErrInvalidCBOR, info)
+	}
+
+	if len(d.data)-d.pos < size {
+		return 0, 0, fmt.Errorf("%w: truncated item head", ErrInvalidCBOR)
+	}
+	var n uint64
+	for _, b := range d.data[d.pos : d.pos+size] {
+		n = n<<8 | uint64(b)
+	}
+	d.pos += size
+	return major, n, nil
+}
+
+// expectHead reads an item head and checks its major type
+func (d *cborDecoder) expectHead(major byte) (uint64, error) {
+	m, n, err := d.readHead()
+	if err != nil {
+		return 0, err
+	}
+	if m != major {
+		return 0, fmt.Errorf("%w: expected major type %d, got %d", ErrInvalidCBOR, major, m)
+	}
+	return n, nil
+}
+
+// readRaw reads n raw bytes following a string head
+func (d *cborDecoder) readRaw(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, fmt.Errorf("%w: string length %d exceeds input", ErrInvalidCBOR, n)
+	}
+	b := make([]byte, n)
+	copy(b, d.data[d.pos:])
+	d.pos += int(n)
+	return b, nil
+}
+
+// readBytes reads a byte string
+func (d *cborDecoder) readBytes() ([]byte, error) {
+	n, err := d.expectHead(cborMajorBytes)
+	if err != nil {
+		return nil, err
+	}
+	return d.readRaw(n)
+}
+
+// readText reads a text string
+func (d *cborDecoder) readText() (string, error) {
+	n, err := d.expectHead(cborMajorText)
+	if err != nil {
+		return "", err
+	}
+	b, err := d.readRaw(n)
+	return string(b), err
+}
+
+// readDomains reads an array of byte strings
+func (d *cborDecoder) readDomains() ([]th.Domain, error) {
+	n, err := d.expectHead(cborMajorArray)
+	if err != nil {
+		return nil, err
+	}
+	// Every element needs at least one byte, which bounds the allocation
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, fmt.Errorf("%w: array length %d exceeds input", ErrInvalidCBOR, n)
+	}
+	domains := make([]th.Domain, n)
+	for i := range domains {
+		if domains[i], err = d.readBytes(); err != nil {
+			return nil, err
+		}
+	}
+	return domains, nil
+}
+
+// readMapKey reads the next text-string key of a map, rejecting duplicates
+func (d *cborDecoder) readMapKey(seen map[string]bool) (string, error) {
+	key, err := d.readText()
+	if err != nil {
+		return "", err
+	}
+	if seen[key] {
+		return "", fmt.Errorf("%w: duplicate map key %q", ErrInvalidCBOR, key)
+	}
+	seen[key] = true
+	return key, nil
+}
+
+// finish checks that the whole input has been consumed
+func (d *cborDecoder) finish() error {
+	if d.pos != len(d.data) {
+		return fmt.Errorf("%w: %d trailing bytes", ErrInvalidCBOR, len(d.data)-d.pos)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package xmss
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/aerius-labs/hash-sig-go/encoding/winternitz"
+	"github.com/aerius-labs/hash-sig-go/internal/prf"
+	"github.com/aerius-labs/hash-sig-go/th/message_hash"
+	"github.com/aerius-labs/hash-sig-go/th/tweak_hash"
+)
+
+func TestSignatureCBORRoundTrip(t *testing.T) {
+	prfInstance := prf.NewSHA3PRF(24, 24)
+	thInstance := tweak_hash.NewSHA3TweakableHash(24, 24)
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	encInstance := winternitz.NewWinternitzEncoding(mhInstance, 4, 3)
+
+	xmss := NewGeneralizedXMSS(prfInstance, encInstance, thInstance, 5)
+	pk, sk := xmss.KeyGen(rand.Reader, 0, 32)
+
+	message := make([]byte, 32)
+	rand.Read(message)
+
+	sig, err := xmss.Sign(rand.Reader, sk, 7, message)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	// Round-trip the signature
+	sigCBOR, err := sig.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("MarshalCBOR failed: %v", err)
+	}
+	var restoredSig Signature
+	if err := restoredSig.UnmarshalCBOR(sigCBOR); err != nil {
+		t.Fatalf("UnmarshalCBOR failed: %v", err)
+	}
+
+	// Round-trip the public key
+	pkCBOR, err := pk.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("MarshalCBOR failed: %v", err)
+	}
+	var restoredPK PublicKey
+	if err := restoredPK.UnmarshalCBOR(pkCBOR); err != nil {
+		t.Fatalf("UnmarshalCBOR failed: %v", err)
+	}
+	if !bytes.Equal(restoredPK.Root, pk.Root) || !bytes.Equal(restoredPK.Parameter, pk.Parameter) {
+		t.Fatal("Public key CBOR round trip mismatch")
+	}
+
+	// The restored signature must still verify under the restored key
+	if !xmss.Verify(&restoredPK, 7, message, &restoredSig) {
+		t.Fatal("CBOR-restored signature failed to verify")
+	}
+
+	// Encoding is deterministic
+	again, _ := restoredSig.MarshalCBOR()
+	if !bytes.Equal(sigCBOR, again) {
+		t.Fatal("CBOR encoding is not deterministic")
+	}
+}
+
+func TestCBORRejectsMalformed(t *testing.T) {
+	pk := &PublicKey{Root: []byte{1, 2, 3}, Parameter: []byte{4, 5}}
+	encoded, _ := pk.MarshalCBOR()
+
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{"Empty", nil},
+		{"Truncated", encoded[:len(encoded)-1]},
+		{"Trailing", append(append([]byte{}, encoded...), 0x00)},
+		{"NotAMap", []byte{0x40}},
+		// {"root": h'', "root": h''}
+		{"DuplicateKey", []byte{0xa2, 0x64, 'r', 'o', 'o', 't', 0x40, 0x64, 'r', 'o', 'o', 't', 0x40}},
+		// {"root": h''}
+		{"MissingField", []byte{0xa1, 0x64, 'r', 'o', 'o', 't', 0x40}},
+		// {"x": h''}
+		{"UnknownField", []byte{0xa1, 0x61, 'x', 0x40}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var decoded PublicKey
+			err := decoded.UnmarshalCBOR(tc.data)
+			if !errors.Is(err, ErrInvalidCBOR) {
+				t.Fatalf("Expected ErrInvalidCBOR, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package xmss
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/aerius-labs/hash-sig-go/th"
+)
+
+// chainCacheKey identifies a chain value by where Sign stops walking
+type chainCacheKey struct {
+	epoch      uint32
+	chainIndex uint8
+	steps      uint8
+}
+
+// chainCache holds chain values Sign computed for one secret key. The
+// values are secret key material: Destroy wipes them.
+type chainCache struct {
+	mu sync.Mutex
+	// owner is the scheme the values were computed under; options such as
+	// WithPerEpochParameters change them, so another scheme starts afresh
+	owner   *GeneralizedXMSS
+	entries map[chainCacheKey]th.Domain
+}
+
+// chainCacheInit guards the lazy creation of SecretKey chain caches
+var chainCacheInit sync.Mutex
+
+// WithChainCache makes Sign remember up to maxEntries chain values per
+// secret key, keyed by (epoch, chain, steps), so signing at an epoch again
+// (e.g. re-signing, or in benchmarks) skips the chain walks it has done
+// before. When the cache is full it is dropped as a whole and refilled.
+// It is ignored with WithConstantTimeChains, whose point is to always walk.
+func WithChainCache(maxEntries int) Option {
+	return func(g *GeneralizedXMSS) {
+		g.chainCacheSize = maxEntries
+	}
+}
+
+// DropChainCache wipes and releases the chain values cached for sk by
+// WithChainCache. Signing refills the cache as needed.
+func (sk *SecretKey) DropChainCache() {
+	chainCacheInit.Lock()
+	c := sk.chainCache
+	sk.chainCache = nil
+	chainCacheInit.Unlock()
+
+	if c != nil {
+		c.mu.Lock()
+		c.wipe()
+		c.mu.Unlock()
+	}
+}
+
+// chainCacheFor returns sk's chain cache for this scheme, creating it on
+// first use, or nil if the scheme does not cache chain values
+func (g *GeneralizedXMSS) chainCacheFor(sk *SecretKey) *chainCache {
+	if g.chainCacheSize <= 0 || g.constantTimeChains {
+		return nil
+	}
+
+	chainCacheInit.Lock()
+	defer chainCacheInit.Unlock()
+	if sk.chainCache == nil {
+		sk.chainCache = &chainCache{}
+	}
+	return sk.chainCache
+}
+
+// get returns the cached value for key, if any
+func (c *chainCache) get(g *GeneralizedXMSS, key chainCacheKey) (th.Domain, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.owner != g {
+		return nil, false
+	}
+	value, ok := c.entries[key]
+	return bytes.Clone(value), ok
+}
+
+// put stores the value for key, dropping the whole cache if it is full or
+// holds values from another scheme
+func (c *chainCache) put(g *GeneralizedXMSS, key chainCacheKey, value th.Domain) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.owner != g || len(c.entries) >= g.chainCacheSize {
+		c.wipe()
+		c.owner = g
+		c.entries = make(map[chainCacheKey]th.Domain)
+	}
+	c.entries[key] = bytes.Clone(value)
+}
+
+// wipe zeroes and forgets every cached value. The caller holds c.mu.
+func (c *chainCache) wipe() {
+	for _, value := range c.entries {
+		clear(value)
+	}
+	c.entries = nil
+	c.owner = nil
+}
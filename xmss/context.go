@@ -0,0 +1,82 @@
+package xmss
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"golang.org/x/crypto/sha3"
+	"github.com/aerius-labs/hash-sig-go/encoding"
+)
+
+// Domain separators for binding an application context
+var (
+	contextPRFSep     = []byte("hash-sig-go 2025 context prf key")
+	contextMessageSep = []byte("hash-sig-go 2025 context message")
+)
+
+// WithContext binds the scheme to an application-specific context string,
+// so that instances with different contexts never cross-verify. An empty
+// context is the same as no context.
+//
+// The context is not placed in the PRF input or the message-hash tweak,
+// whose layouts are fixed by each PRF and message hash (Poseidon's tweak is
+// a fixed number of field elements). Instead, with ctx = uint32be(len(context))
+// || context:
+//
+//   - chain starts are derived under the PRF key
+//     SHAKE256("hash-sig-go 2025 context prf key" || ctx || K), truncated to
+//     len(K) bytes, in place of the key K
+//   - the encoding hashes the 32-byte
+//     SHA3-256("hash-sig-go 2025 context message" || ctx || M) in place of
+//     the message M (see ContextMessage)
+func WithContext(context []byte) Option {
+	return func(g *GeneralizedXMSS) {
+		g.context = bytes.Clone(context)
+	}
+}
+
+// ContextMessage returns the message the encoding hashes when signing or
+// verifying message: message itself without a context, and otherwise
+// the th.MessageLength-byte SHA3-256(separator || len(context) || context
+// || message)
+func (g *GeneralizedXMSS) ContextMessage(message []byte) []byte {
+	if len(g.context) == 0 {
+		return message
+	}
+	h := sha3.New256()
+	h.Write(contextMessageSep)
+	h.Write(binary.BigEndian.AppendUint32(nil, uint32(len(g.context))))
+	h.Write(g.context)
+	h.Write(message)
+	return h.Sum(nil)
+}
+
+// contextPRFKey returns the PRF key chain starts are derived under: prfKey
+// itself without a context, and otherwise len(prfKey) bytes of
+// SHAKE256(separator || len(context) || context || prfKey), so PRFs with a
+// fixed key length keep working
+func (g *GeneralizedXMSS) contextPRFKey(prfKey []byte) []byte {
+	if len(g.context) == 0 {
+		return prfKey
+	}
+	h := sha3.NewShake256()
+	h.Write(contextPRFSep)
+	h.Write(binary.BigEndian.AppendUint32(nil, uint32(len(g.context))))
+	h.Write(g.context)
+	h.Write(prfKey)
+	key := make([]byte, len(prfKey))
+	h.Read(key)
+	return key
+}
+
+// encodedMessage returns the message the encoding hashes, its
+// ContextMessage, checking that the message hash accepts its length
+func (g *GeneralizedXMSS) encodedMessage(message []byte) ([]byte, error) {
+	message = g.ContextMessage(message)
+	lengther, ok := g.encoding.(encoding.FixedLengthMessages)
+	if !ok || lengther.MessageLen() == 0 || len(message) == lengther.MessageLen() {
+		return message, nil
+	}
+	return nil, fmt.Errorf("%w: %d bytes, expected %d", ErrMessageLength, len(message), lengther.MessageLen())
+}
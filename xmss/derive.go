@@ -0,0 +1,56 @@
+package xmss
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/sha3"
+)
+
+// MinMasterSeedLen is the minimum master seed length accepted by DeriveKeyFromSeed
+const MinMasterSeedLen = 16
+
+// keyDerivationInfo is the HKDF info prefix for per-index key derivation
+var keyDerivationInfo = []byte("hash-sig-go xmss key derivation")
+
+// Labels domain-separating the SHAKE256 outputs of a per-index seed
+var (
+	deriveParameterLabel = []byte("hash-sig-go xmss derive parameter")
+	derivePRFKeyLabel    = []byte("hash-sig-go xmss derive prf key")
+	derivePaddingLabel   = []byte("hash-sig-go xmss derive tree padding")
+)
+
+// DeriveKeyFromSeed deterministically generates key pair number index from
+// a master seed, so wallet-style deployments can recreate any key from one
+// secret. HKDF-SHA3-256 derives a per-index seed, from which the parameter,
+// the PRF key and the tree padding are each read from their own
+// domain-separated SHAKE256 output. The same (masterSeed, index, scheme,
+// activation range) always yields the same key pair; different indices
+// yield independent keys.
+func DeriveKeyFromSeed(masterSeed []byte, index uint32, scheme *GeneralizedXMSS, activationEpoch, numActiveEpochs int) (*PublicKey, *SecretKey, error) {
+	if len(masterSeed) < MinMasterSeedLen {
+		return nil, nil, fmt.Errorf("master seed must be at least %d bytes, got %d", MinMasterSeedLen, len(masterSeed))
+	}
+	
+	info := binary.BigEndian.AppendUint32(append([]byte(nil), keyDerivationInfo...), index)
+	seed := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha3.New256, masterSeed, nil, info), seed); err != nil {
+		return nil, nil, fmt.Errorf("deriving key seed: %w", err)
+	}
+	
+	parameter := scheme.randParameter(deriveStream(deriveParameterLabel, seed))
+	prfKey := scheme.prf.KeyGen(deriveStream(derivePRFKeyLabel, seed))
+	
+	pk, sk := scheme.keyPair(deriveStream(derivePaddingLabel, seed), prfKey, parameter, activationEpoch, numActiveEpochs, nil)
+	return pk, sk, nil
+}
+
+// deriveStream returns SHAKE256(label || seed) as a reader
+func deriveStream(label, seed []byte) io.Reader {
+	h := sha3.NewShake256()
+	h.Write(label)
+	h.Write(seed)
+	return h
+}
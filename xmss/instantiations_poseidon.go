@@ -22,16 +22,24 @@ const (
 	PoseidonCapacity      = 9
 )
 
-// Winternitz w=1 instantiation
+// Winternitz w=1 instantiation. The message hash's 5 field elements hold
+// just under 2^155 values, so the Winternitz instantiations take as many
+// chunks as fit in 154 bits
 const (
 	PoseidonChunkSizeW1        = 1
 	PoseidonBaseW1             = 2
-	PoseidonNumChunksW1        = 155
+	PoseidonNumChunksW1        = 154
 	PoseidonNumChunksChecksumW1 = 8
 )
 
 // NewPoseidonWinternitzW1 creates Poseidon-based XMSS with Winternitz w=1
 func NewPoseidonWinternitzW1() *GeneralizedXMSS {
+	return NewPoseidonWinternitzW1Test(PoseidonLogLifetime18)
+}
+
+// NewPoseidonWinternitzW1Test creates the Winternitz w=1 instantiation with a reduced
+// lifetime of 2^logLifetime epochs, for tests and examples
+func NewPoseidonWinternitzW1Test(logLifetime int) *GeneralizedXMSS {
 	messageHash := message_hash.NewPoseidonMessageHash(
 		PoseidonParameterLen,
 		PoseidonRandLen,
@@ -62,7 +70,7 @@ func NewPoseidonWinternitzW1() *GeneralizedXMSS {
 		prfFunc,
 		winternitzEnc,
 		tweakHash,
-		PoseidonLogLifetime18,
+		logLifetime,
 	)
 }
 
@@ -70,12 +78,18 @@ func NewPoseidonWinternitzW1() *GeneralizedXMSS {
 const (
 	PoseidonChunkSizeW2         = 2
 	PoseidonBaseW2              = 4
-	PoseidonNumChunksW2         = 78
+	PoseidonNumChunksW2         = 77
 	PoseidonNumChunksChecksumW2 = 4
 )
 
 // NewPoseidonWinternitzW2 creates Poseidon-based XMSS with Winternitz w=2
 func NewPoseidonWinternitzW2() *GeneralizedXMSS {
+	return NewPoseidonWinternitzW2Test(PoseidonLogLifetime18)
+}
+
+// NewPoseidonWinternitzW2Test creates the Winternitz w=2 instantiation with a reduced
+// lifetime of 2^logLifetime epochs, for tests and examples
+func NewPoseidonWinternitzW2Test(logLifetime int) *GeneralizedXMSS {
 	messageHash := message_hash.NewPoseidonMessageHash(
 		PoseidonParameterLen,
 		PoseidonRandLen,
@@ -106,7 +120,7 @@ func NewPoseidonWinternitzW2() *GeneralizedXMSS {
 		prfFunc,
 		winternitzEnc,
 		tweakHash,
-		PoseidonLogLifetime18,
+		logLifetime,
 	)
 }
 
@@ -114,12 +128,18 @@ func NewPoseidonWinternitzW2() *GeneralizedXMSS {
 const (
 	PoseidonChunkSizeW4         = 4
 	PoseidonBaseW4              = 16
-	PoseidonNumChunksW4         = 39
+	PoseidonNumChunksW4         = 38
 	PoseidonNumChunksChecksumW4 = 3
 )
 
 // NewPoseidonWinternitzW4 creates Poseidon-based XMSS with Winternitz w=4
 func NewPoseidonWinternitzW4() *GeneralizedXMSS {
+	return NewPoseidonWinternitzW4Test(PoseidonLogLifetime18)
+}
+
+// NewPoseidonWinternitzW4Test creates the Winternitz w=4 instantiation with a reduced
+// lifetime of 2^logLifetime epochs, for tests and examples
+func NewPoseidonWinternitzW4Test(logLifetime int) *GeneralizedXMSS {
 	messageHash := message_hash.NewPoseidonMessageHash(
 		PoseidonParameterLen,
 		PoseidonRandLen,
@@ -150,24 +170,86 @@ func NewPoseidonWinternitzW4() *GeneralizedXMSS {
 		prfFunc,
 		winternitzEnc,
 		tweakHash,
-		PoseidonLogLifetime18,
+		logLifetime,
 	)
 }
 
-// Target-Sum w=256 instantiation
+// Winternitz w=8 instantiation
+const (
+	PoseidonChunkSizeW8         = 8
+	PoseidonBaseW8              = 256
+	PoseidonNumChunksW8         = 19
+	PoseidonNumChunksChecksumW8 = 2
+)
+
+// NewPoseidonWinternitzW8 creates Poseidon-based XMSS with Winternitz w=8
+func NewPoseidonWinternitzW8() *GeneralizedXMSS {
+	return NewPoseidonWinternitzW8Test(PoseidonLogLifetime18)
+}
+
+// NewPoseidonWinternitzW8Test creates the Winternitz w=8 instantiation with a reduced
+// lifetime of 2^logLifetime epochs, for tests and examples
+func NewPoseidonWinternitzW8Test(logLifetime int) *GeneralizedXMSS {
+	messageHash := message_hash.NewPoseidonMessageHash(
+		PoseidonParameterLen,
+		PoseidonRandLen,
+		PoseidonMsgHashLenFE,
+		PoseidonNumChunksW8,
+		PoseidonBaseW8,
+		PoseidonTweakLenFE,
+		PoseidonMsgLenFE,
+	)
+	
+	winternitzEnc := winternitz.NewWinternitzEncoding(
+		messageHash,
+		PoseidonChunkSizeW8,
+		PoseidonNumChunksChecksumW8,
+	)
+	
+	tweakHash := tweak_hash.NewPoseidonTweakHash(
+		PoseidonParameterLen,
+		PoseidonHashLenFE,
+		PoseidonTweakLenFE,
+		PoseidonCapacity,
+		PoseidonNumChunksW8,
+	)
+	
+	prfFunc := prf.NewShakePRFtoField(32, PoseidonHashLenFE)
+	
+	return NewGeneralizedXMSS(
+		prfFunc,
+		winternitzEnc,
+		tweakHash,
+		logLifetime,
+	)
+}
+
+// Target-Sum w=256 instantiation. The target is the expected chunk sum
+// Dim*(W-1)/2, the most likely sum for uniform chunks, so roughly one
+// message hash in a thousand encodes
 const (
 	PoseidonTargetSumW256      = 256
 	PoseidonTargetSumDim256    = 32
-	PoseidonTargetSumTarget256 = 768
+	PoseidonTargetSumTarget256 = PoseidonTargetSumDim256 * (PoseidonTargetSumW256 - 1) / 2
 	PoseidonTargetSumSlack256  = 1024
+	
+	// 32 base-256 chunks need 256 bits of message hash, more than the
+	// 5 field elements (about 155 bits) of the other instantiations
+	PoseidonMsgHashLenFE256 = 9
 )
 
 // NewPoseidonTargetSumW256 creates Poseidon-based XMSS with Target-Sum w=256
 func NewPoseidonTargetSumW256() *GeneralizedXMSS {
+	return NewPoseidonTargetSumW256Test(PoseidonLogLifetime18)
+}
+
+// NewPoseidonTargetSumW256Test creates the Target-Sum w=256 instantiation with a reduced
+// lifetime of 2^logLifetime epochs, for tests and examples
+func NewPoseidonTargetSumW256Test(logLifetime int) *GeneralizedXMSS {
 	messageHash := message_hash.NewPoseidonMessageHash(
 		PoseidonParameterLen,
 		PoseidonRandLen,
-		PoseidonMsgHashLenFE,
+		PoseidonMsgHashLenFE256,
 		PoseidonTargetSumDim256,
 		PoseidonTargetSumW256,
 		PoseidonTweakLenFE,
@@ -193,6 +275,6 @@ func NewPoseidonTargetSumW256() *GeneralizedXMSS {
 		prfFunc,
 		targetSumEnc,
 		tweakHash,
-		PoseidonLogLifetime18,
+		logLifetime,
 	)
 }
\ No newline at end of file
@@ -0,0 +1,118 @@
+package xmss
+
+import (
+	"github.com/aerius-labs/hash-sig-go/encoding"
+	"github.com/aerius-labs/hash-sig-go/encoding/targetsum"
+	"github.com/aerius-labs/hash-sig-go/encoding/winternitz"
+	"github.com/aerius-labs/hash-sig-go/internal/prf"
+	"github.com/aerius-labs/hash-sig-go/th/message_hash"
+	"github.com/aerius-labs/hash-sig-go/th/tweak_hash"
+)
+
+// SHA3-based instantiations with Lifetime 2^18
+
+// Constants for SHA3 instantiations. Message hashes use 192 of the 256
+// digest bits, matching the 24-byte hash output.
+const (
+	SHA3LogLifetime18 = 18
+	SHA3ParameterLen  = 24
+	SHA3HashLen       = 24
+	SHA3RandLen       = 24
+	SHA3MessageBits   = 192
+)
+
+// Winternitz chunk sizes and checksum lengths
+const (
+	SHA3ChunkSizeW1         = 1
+	SHA3NumChunksChecksumW1 = 8
+	SHA3ChunkSizeW2         = 2
+	SHA3NumChunksChecksumW2 = 5
+	SHA3ChunkSizeW4         = 4
+	SHA3NumChunksChecksumW4 = 3
+	SHA3ChunkSizeW8         = 8
+	SHA3NumChunksChecksumW8 = 2
+)
+
+// Target-Sum instantiation: 48 base-16 chunks summing to their expected value
+const (
+	SHA3TargetSumChunkSize = 4
+	SHA3TargetSumTarget    = 360
+)
+
+// NewSHA3WinternitzW1 creates SHA3-based XMSS with Winternitz w=1
+func NewSHA3WinternitzW1() *GeneralizedXMSS {
+	return NewSHA3WinternitzW1Test(SHA3LogLifetime18)
+}
+
+// NewSHA3WinternitzW1Test creates the SHA3 Winternitz w=1 instantiation with a
+// reduced lifetime of 2^logLifetime epochs, for tests and examples
+func NewSHA3WinternitzW1Test(logLifetime int) *GeneralizedXMSS {
+	return newSHA3Winternitz(SHA3ChunkSizeW1, SHA3NumChunksChecksumW1, logLifetime)
+}
+
+// NewSHA3WinternitzW2 creates SHA3-based XMSS with Winternitz w=2
+func NewSHA3WinternitzW2() *GeneralizedXMSS {
+	return NewSHA3WinternitzW2Test(SHA3LogLifetime18)
+}
+
+// NewSHA3WinternitzW2Test creates the SHA3 Winternitz w=2 instantiation with a
+// reduced lifetime of 2^logLifetime epochs, for tests and examples
+func NewSHA3WinternitzW2Test(logLifetime int) *GeneralizedXMSS {
+	return newSHA3Winternitz(SHA3ChunkSizeW2, SHA3NumChunksChecksumW2, logLifetime)
+}
+
+// NewSHA3WinternitzW4 creates SHA3-based XMSS with Winternitz w=4
+func NewSHA3WinternitzW4() *GeneralizedXMSS {
+	return NewSHA3WinternitzW4Test(SHA3LogLifetime18)
+}
+
+// NewSHA3WinternitzW4Test creates the SHA3 Winternitz w=4 instantiation with a
+// reduced lifetime of 2^logLifetime epochs, for tests and examples
+func NewSHA3WinternitzW4Test(logLifetime int) *GeneralizedXMSS {
+	return newSHA3Winternitz(SHA3ChunkSizeW4, SHA3NumChunksChecksumW4, logLifetime)
+}
+
+// NewSHA3WinternitzW8 creates SHA3-based XMSS with Winternitz w=8
+func NewSHA3WinternitzW8() *GeneralizedXMSS {
+	return NewSHA3WinternitzW8Test(SHA3LogLifetime18)
+}
+
+// NewSHA3WinternitzW8Test creates the SHA3 Winternitz w=8 instantiation with a
+// reduced lifetime of 2^logLifetime epochs, for tests and examples
+func NewSHA3WinternitzW8Test(logLifetime int) *GeneralizedXMSS {
+	return newSHA3Winternitz(SHA3ChunkSizeW8, SHA3NumChunksChecksumW8, logLifetime)
+}
+
+// NewSHA3TargetSum creates SHA3-based XMSS with Target-Sum w=4
+func NewSHA3TargetSum() *GeneralizedXMSS {
+	return NewSHA3TargetSumTest(SHA3LogLifetime18)
+}
+
+// NewSHA3TargetSumTest creates the SHA3 Target-Sum instantiation with a
+// reduced lifetime of 2^logLifetime epochs, for tests and examples
+func NewSHA3TargetSumTest(logLifetime int) *GeneralizedXMSS {
+	messageHash := newSHA3MessageHash(SHA3TargetSumChunkSize)
+	return newSHA3(targetsum.NewTargetSumEncoding(messageHash, SHA3TargetSumTarget), logLifetime)
+}
+
+// newSHA3Winternitz builds a SHA3 Winternitz instantiation for chunkSize
+func newSHA3Winternitz(chunkSize, numChunksChecksum, logLifetime int) *GeneralizedXMSS {
+	messageHash := newSHA3MessageHash(chunkSize)
+	return newSHA3(winternitz.NewWinternitzEncoding(messageHash, chunkSize, numChunksChecksum), logLifetime)
+}
+
+// newSHA3MessageHash returns the SHA3 message hash splitting
+// SHA3MessageBits into chunkSize-bit chunks
+func newSHA3MessageHash(chunkSize int) *message_hash.SHA3MessageHash {
+	return message_hash.NewSHA3MessageHash(SHA3ParameterLen, SHA3RandLen, SHA3MessageBits/chunkSize, chunkSize)
+}
+
+// newSHA3 pairs enc with the SHA3 PRF and tweakable hash
+func newSHA3(enc encoding.IncomparableEncoding, logLifetime int) *GeneralizedXMSS {
+	return NewGeneralizedXMSS(
+		prf.NewSHA3PRF(SHA3HashLen, SHA3HashLen),
+		enc,
+		tweak_hash.NewSHA3TweakableHash(SHA3ParameterLen, SHA3HashLen),
+		logLifetime,
+	)
+}
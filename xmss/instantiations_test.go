@@ -0,0 +1,336 @@
+package xmss
+
+import (
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+	
+	"github.com/aerius-labs/hash-sig-go/encoding/targetsum"
+	"github.com/aerius-labs/hash-sig-go/encoding/winternitz"
+	"github.com/aerius-labs/hash-sig-go/field"
+	"github.com/aerius-labs/hash-sig-go/internal/prf"
+	"github.com/aerius-labs/hash-sig-go/th"
+	"github.com/aerius-labs/hash-sig-go/th/message_hash"
+	"github.com/aerius-labs/hash-sig-go/th/tweak_hash"
+)
+
+// Test that every named instantiation is self-consistent and can sign.
+// Keys are generated for a couple of epochs only, so the full lifetime
+// does not have to be materialised.
+func TestAllInstantiationsValid(t *testing.T) {
+	instantiations := []struct {
+		name string
+		new  func() *GeneralizedXMSS
+	}{
+		{"PoseidonWinternitzW1", NewPoseidonWinternitzW1},
+		{"PoseidonWinternitzW2", NewPoseidonWinternitzW2},
+		{"PoseidonWinternitzW4", NewPoseidonWinternitzW4},
+		{"PoseidonWinternitzW8", NewPoseidonWinternitzW8},
+		{"PoseidonTargetSumW256", NewPoseidonTargetSumW256},
+	}
+	
+	for _, inst := range instantiations {
+		t.Run(inst.name, func(t *testing.T) {
+			xmss := inst.new()
+			if err := xmss.ValidateConfig(); err != nil {
+				t.Fatalf("ValidateConfig failed: %v", err)
+			}
+			if err := th.CheckDomainLength(xmss.th, rand.Reader); err != nil {
+				t.Fatalf("CheckDomainLength failed: %v", err)
+			}
+			
+			const activation = 5
+			pk, sk := xmss.KeyGen(rand.Reader, activation, 2)
+			
+			message := make([]byte, 32)
+			rand.Read(message)
+			
+			sig, err := xmss.Sign(rand.Reader, sk, activation+1, message)
+			if err != nil {
+				t.Fatalf("Failed to sign: %v", err)
+			}
+			if !xmss.Verify(pk, activation+1, message, sig) {
+				t.Fatal("Signature verification failed")
+			}
+		})
+	}
+}
+
+// Test a reduced-lifetime instantiation over its whole lifetime
+func TestPoseidonReducedLifetime(t *testing.T) {
+	xmss := NewPoseidonWinternitzW4Test(4)
+	if xmss.Lifetime() != 16 {
+		t.Fatalf("Expected lifetime 16, got %d", xmss.Lifetime())
+	}
+	
+	pk, sk := xmss.KeyGen(rand.Reader, 0, int(xmss.Lifetime()))
+	
+	message := make([]byte, 32)
+	rand.Read(message)
+	
+	for _, epoch := range []uint32{0, 7, 15} {
+		sig, err := xmss.Sign(rand.Reader, sk, epoch, message)
+		if err != nil {
+			t.Fatalf("Failed to sign at epoch %d: %v", epoch, err)
+		}
+		if !xmss.Verify(pk, epoch, message, sig) {
+			t.Fatalf("Signature verification failed at epoch %d", epoch)
+		}
+	}
+}
+
+// Test that every SHA3 instantiation signs and verifies, at full and at
+// reduced lifetime
+func TestSHA3Instantiations(t *testing.T) {
+	instantiations := []struct {
+		name    string
+		new     func() *GeneralizedXMSS
+		newTest func(logLifetime int) *GeneralizedXMSS
+	}{
+		{"SHA3WinternitzW1", NewSHA3WinternitzW1, NewSHA3WinternitzW1Test},
+		{"SHA3WinternitzW2", NewSHA3WinternitzW2, NewSHA3WinternitzW2Test},
+		{"SHA3WinternitzW4", NewSHA3WinternitzW4, NewSHA3WinternitzW4Test},
+		{"SHA3WinternitzW8", NewSHA3WinternitzW8, NewSHA3WinternitzW8Test},
+		{"SHA3TargetSum", NewSHA3TargetSum, NewSHA3TargetSumTest},
+	}
+	
+	for _, inst := range instantiations {
+		t.Run(inst.name, func(t *testing.T) {
+			full := inst.new()
+			if full.Lifetime() != 1<<SHA3LogLifetime18 {
+				t.Fatalf("Expected lifetime 2^18, got %d", full.Lifetime())
+			}
+			
+			for _, xmss := range []*GeneralizedXMSS{full, inst.newTest(4)} {
+				if err := xmss.ValidateConfig(); err != nil {
+					t.Fatalf("ValidateConfig failed: %v", err)
+				}
+				pk, sk := xmss.KeyGen(rand.Reader, 2, 2)
+				
+				message := make([]byte, 32)
+				rand.Read(message)
+				sig, err := xmss.Sign(rand.Reader, sk, 3, message)
+				if err != nil {
+					t.Fatalf("Failed to sign: %v", err)
+				}
+				if !xmss.Verify(pk, 3, message, sig) {
+					t.Fatal("Signature verification failed")
+				}
+				message[0] ^= 1
+				if xmss.Verify(pk, 3, message, sig) {
+					t.Fatal("Accepted a signature on another message")
+				}
+			}
+		})
+	}
+}
+
+// Test that the hard-coded Winternitz checksum lengths are the ones
+// ComputeChecksumLength gives for their message chunks
+func TestWinternitzChecksumLengths(t *testing.T) {
+	cases := []struct {
+		name                           string
+		numChunks, chunkSize, checksum int
+	}{
+		{"PoseidonW1", PoseidonNumChunksW1, PoseidonChunkSizeW1, PoseidonNumChunksChecksumW1},
+		{"PoseidonW2", PoseidonNumChunksW2, PoseidonChunkSizeW2, PoseidonNumChunksChecksumW2},
+		{"PoseidonW4", PoseidonNumChunksW4, PoseidonChunkSizeW4, PoseidonNumChunksChecksumW4},
+		{"PoseidonW8", PoseidonNumChunksW8, PoseidonChunkSizeW8, PoseidonNumChunksChecksumW8},
+		{"SHA3W1", SHA3MessageBits / SHA3ChunkSizeW1, SHA3ChunkSizeW1, SHA3NumChunksChecksumW1},
+		{"SHA3W2", SHA3MessageBits / SHA3ChunkSizeW2, SHA3ChunkSizeW2, SHA3NumChunksChecksumW2},
+		{"SHA3W4", SHA3MessageBits / SHA3ChunkSizeW4, SHA3ChunkSizeW4, SHA3NumChunksChecksumW4},
+		{"SHA3W8", SHA3MessageBits / SHA3ChunkSizeW8, SHA3ChunkSizeW8, SHA3NumChunksChecksumW8},
+	}
+	for _, c := range cases {
+		if want := winternitz.ComputeChecksumLength(c.numChunks, c.chunkSize); c.checksum != want {
+			t.Errorf("%s: %d checksum chunks, ComputeChecksumLength gives %d", c.name, c.checksum, want)
+		}
+	}
+}
+
+// Test that NewGeneralizedXMSS rejects a PRF whose output length differs
+// from the tweakable hash domain length
+func TestNewGeneralizedXMSSRejectsLengthMismatch(t *testing.T) {
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	encInstance := winternitz.NewWinternitzEncoding(mhInstance, 4, 3)
+	
+	// PRF emits 32-byte chain starts but the hash works on 24-byte domains
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatal("NewGeneralizedXMSS accepted mismatched PRF and hash lengths")
+		}
+		if msg, _ := r.(string); !strings.Contains(msg, "PRF output length 32") {
+			t.Fatalf("Unexpected panic: %v", r)
+		}
+	}()
+	NewGeneralizedXMSS(prf.NewSHA3PRF(24, 32), encInstance, tweak_hash.NewSHA3TweakableHash(24, 24), 5)
+}
+
+// Test that VerifyAgainstFieldRoot agrees with Verify on a Poseidon key
+func TestVerifyAgainstFieldRoot(t *testing.T) {
+	xmss := NewPoseidonWinternitzW4Test(3)
+	pk, sk := xmss.KeyGen(rand.Reader, 0, 8)
+	message := make([]byte, 32)
+	rand.Read(message)
+	
+	sig, err := xmss.Sign(rand.Reader, sk, 5, message)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	rootFE := field.BytesToElements(pk.Root, len(pk.Root)/4)
+	paramFE := field.BytesToElements(pk.Parameter, len(pk.Parameter)/4)
+	
+	if !xmss.Verify(pk, 5, message, sig) || !xmss.VerifyAgainstFieldRoot(rootFE, paramFE, 5, message, sig) {
+		t.Fatal("Valid signature rejected")
+	}
+	if xmss.VerifyAgainstFieldRoot(rootFE, paramFE, 4, message, sig) {
+		t.Fatal("Accepted the signature at the wrong epoch")
+	}
+	
+	// A root differing in one element, and a truncated root
+	other := append([]field.Element(nil), rootFE...)
+	other[0] = field.NewElement(other[0].Uint64() + 1)
+	if xmss.VerifyAgainstFieldRoot(other, paramFE, 5, message, sig) || xmss.VerifyAgainstFieldRoot(rootFE[1:], paramFE, 5, message, sig) {
+		t.Fatal("Accepted a wrong root")
+	}
+}
+
+// Test that NewGeneralizedXMSS rejects an encoding whose message hash is
+// too short for its chunks, and ValidateConfig reports it
+func TestNewGeneralizedXMSSValidatesMessageHash(t *testing.T) {
+	// 32 base-256 chunks need 256 bits, but 5 field elements carry ~155
+	messageHash := message_hash.NewPoseidonMessageHash(PoseidonParameterLen, PoseidonRandLen, 5, 32, 256, PoseidonTweakLenFE, PoseidonMsgLenFE)
+	enc := targetsum.NewTargetSumEncoding(messageHash, PoseidonTargetSumTarget256)
+	if err := enc.Validate(); err == nil {
+		t.Fatal("Encoding accepted an under-provisioned message hash")
+	}
+	
+	defer func() {
+		if recover() == nil {
+			t.Fatal("NewGeneralizedXMSS accepted an under-provisioned message hash")
+		}
+	}()
+	NewGeneralizedXMSS(prf.NewShakePRFtoField(32, PoseidonHashLenFE), enc,
+		tweak_hash.NewPoseidonTweakHash(PoseidonParameterLen, PoseidonHashLenFE, PoseidonTweakLenFE, PoseidonCapacity, 32), 4)
+}
+
+// Test that Poseidon schemes accept only 32-byte messages, that
+// SignDigest signs such a digest, and that byte-oriented hashes and
+// context-bound schemes take any length
+func TestMessageLength(t *testing.T) {
+	xmss := NewPoseidonWinternitzW4Test(3)
+	pk, sk := xmss.KeyGen(rand.Reader, 0, 8)
+	
+	// 33 bytes would silently wrap modulo p^9 and could collide
+	for _, n := range []int{0, 31, 33, 64} {
+		message := make([]byte, n)
+		if _, err := xmss.Sign(rand.Reader, sk, 1, message); !errors.Is(err, ErrMessageLength) {
+			t.Fatalf("%d-byte message: expected ErrMessageLength, got %v", n, err)
+		}
+	}
+	
+	var digest [32]byte
+	rand.Read(digest[:])
+	sig, err := xmss.SignDigest(rand.Reader, sk, 1, digest)
+	if err != nil {
+		t.Fatalf("Failed to sign digest: %v", err)
+	}
+	if !xmss.Verify(pk, 1, digest[:], sig) {
+		t.Fatal("Digest signature rejected")
+	}
+	if err := xmss.VerifyDetailed(pk, 1, append(digest[:], 0), sig); !errors.Is(err, ErrMessageLength) {
+		t.Fatalf("Expected ErrMessageLength, got %v", err)
+	}
+	if xmss.VerifyConstantTime(pk, 1, append(digest[:], 0), sig) {
+		t.Fatal("Accepted a 33-byte message")
+	}
+	
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	sha3Scheme := NewGeneralizedXMSS(prf.NewSHA3PRF(24, 24), winternitz.NewWinternitzEncoding(mhInstance, 4, 3), tweak_hash.NewSHA3TweakableHash(24, 24), 3)
+	// The named constructors take no options, so set the context directly
+	contextScheme := NewPoseidonWinternitzW4Test(3)
+	WithContext([]byte("app"))(contextScheme)
+	for name, scheme := range map[string]*GeneralizedXMSS{"SHA3": sha3Scheme, "PoseidonWithContext": contextScheme} {
+		pk, sk := scheme.KeyGen(rand.Reader, 0, 8)
+		message := make([]byte, 100)
+		sig, err := scheme.Sign(rand.Reader, sk, 2, message)
+		if err != nil {
+			t.Fatalf("%s: failed to sign a 100-byte message: %v", name, err)
+		}
+		if !scheme.Verify(pk, 2, message, sig) {
+			t.Fatalf("%s: 100-byte message signature rejected", name)
+		}
+	}
+}
+
+// Benchmark Verify for the Target-Sum w=256 instantiation, whose 32 chains
+// of up to 255 steps dominate verification, walking chains one after the
+// other and in parallel
+func BenchmarkPoseidonTargetSumW256Verify(b *testing.B) {
+	xmss := NewPoseidonTargetSumW256Test(4)
+	pk, sk := xmss.KeyGen(rand.Reader, 0, 16)
+	
+	var digest [th.MessageLength]byte
+	rand.Read(digest[:])
+	sig, err := xmss.SignDigest(rand.Reader, sk, 3, digest)
+	if err != nil {
+		b.Fatal(err)
+	}
+	
+	for _, bc := range []struct {
+		name      string
+		threshold int
+	}{
+		{"Serial", xmss.encoding.Dimension()},
+		{"Parallel", parallelChainThreshold},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			defer func(saved int) { parallelChainThreshold = saved }(parallelChainThreshold)
+			parallelChainThreshold = bc.threshold
+			
+			for i := 0; i < b.N; i++ {
+				if !xmss.Verify(pk, 3, digest[:], sig) {
+					b.Fatal("Verification failed")
+				}
+			}
+		})
+	}
+}
+
+// Test that the configuration accessors report the instantiation parameters
+func TestSchemeIntrospection(t *testing.T) {
+	testCases := []struct {
+		name                                      string
+		xmss                                      *GeneralizedXMSS
+		base, dimension, chunkSize, hashOutputLen int
+	}{
+		{"PoseidonWinternitzW4", NewPoseidonWinternitzW4Test(4), PoseidonBaseW4, PoseidonNumChunksW4 + PoseidonNumChunksChecksumW4, PoseidonChunkSizeW4, 4 * PoseidonHashLenFE},
+		{"SHA3WinternitzW2", NewSHA3WinternitzW2Test(4), 4, SHA3MessageBits/SHA3ChunkSizeW2 + SHA3NumChunksChecksumW2, SHA3ChunkSizeW2, SHA3HashLen},
+		{"SHA3TargetSum", NewSHA3TargetSumTest(4), 16, SHA3MessageBits / SHA3TargetSumChunkSize, SHA3TargetSumChunkSize, SHA3HashLen},
+	}
+	
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			x := tc.xmss
+			if x.Base() != tc.base || x.Dimension() != tc.dimension || x.ChunkSize() != tc.chunkSize || x.HashOutputLen() != tc.hashOutputLen {
+				t.Fatalf("Got base %d, dimension %d, chunk size %d, output length %d; want %d, %d, %d, %d",
+					x.Base(), x.Dimension(), x.ChunkSize(), x.HashOutputLen(),
+					tc.base, tc.dimension, tc.chunkSize, tc.hashOutputLen)
+			}
+			
+			// The accessors describe the signatures the scheme produces
+			pk, sk := x.KeyGen(rand.Reader, 0, 4)
+			message := make([]byte, 32)
+			rand.Read(message)
+			sig, err := x.Sign(rand.Reader, sk, 1, message)
+			if err != nil {
+				t.Fatalf("Failed to sign: %v", err)
+			}
+			if len(sig.Hashes) != x.Dimension() || len(sig.Hashes[0]) != x.HashOutputLen() || len(pk.Root) != x.HashOutputLen() {
+				t.Fatal("Signature shape disagrees with the accessors")
+			}
+		})
+	}
+}
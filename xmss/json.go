@@ -3,6 +3,8 @@ package xmss
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 
 	"github.com/aerius-labs/hash-sig-go/merkle"
 	"github.com/aerius-labs/hash-sig-go/th"
@@ -15,6 +17,9 @@ type secretKeyJSON struct {
 	Parameter       string         `json:"Parameter"`
 	ActivationEpoch int            `json:"ActivationEpoch"`
 	NumActiveEpochs int            `json:"NumActiveEpochs"`
+	
+	// Scheme tags the tweakable hash (see RegisterScheme); empty if unregistered
+	Scheme          string         `json:"Scheme,omitempty"`
 }
 
 // hashTreeJSON represents the JSON structure of a HashTree
@@ -31,6 +36,13 @@ type hashTreeLayerJSON struct {
 
 // MarshalJSON implements custom JSON marshaling for SecretKey
 func (sk *SecretKey) MarshalJSON() ([]byte, error) {
+	if sk.destroyed {
+		return nil, ErrKeyDestroyed
+	}
+	if sk.Tree == nil {
+		return nil, errors.New("secret key has no tree")
+	}
+	
 	// Marshal PRFKey
 	prfKeyStr := base64.StdEncoding.EncodeToString(sk.PRFKey)
 	
@@ -68,6 +80,7 @@ func (sk *SecretKey) MarshalJSON() ([]byte, error) {
 		Parameter:       paramStr,
 		ActivationEpoch: sk.ActivationEpoch,
 		NumActiveEpochs: sk.NumActiveEpochs,
+		Scheme:          schemeTag(sk.Tree.TweakableHash()),
 	}
 	
 	return json.Marshal(jsonSK)
@@ -111,13 +124,21 @@ func (sk *SecretKey) UnmarshalJSON(data []byte) error {
 		layers = append(layers, layer)
 	}
 	
-	// Note: We cannot fully reconstruct the tree without knowing which TweakableHash to use
-	// This is a limitation compared to Rust which maintains the type parameter
-	// The caller must provide the correct TweakableHash instance
-	sk.Tree = nil // Will be set by UnmarshalWithTH
+	// The tree needs its TweakableHash. Keys with a registered scheme tag
+	// carry it; for others the caller must use UnmarshalSecretKey, and the
+	// tree is left nil (Sign rejects such keys).
+	sk.Tree = nil
+	if jsonSK.Scheme != "" {
+		thash, ok := SchemeTweakableHash(jsonSK.Scheme)
+		if !ok {
+			return fmt.Errorf("unknown secret key scheme %q", jsonSK.Scheme)
+		}
+		sk.Tree = merkle.NewHashTreeFromLayers(jsonSK.Tree.Depth, layers, param, thash)
+	}
 	
 	sk.ActivationEpoch = jsonSK.ActivationEpoch
 	sk.NumActiveEpochs = jsonSK.NumActiveEpochs
+	sk.destroyed = false
 	
 	return nil
 }
@@ -168,4 +189,65 @@ func UnmarshalSecretKey(data []byte, thash th.TweakableHash) (*SecretKey, error)
 		ActivationEpoch: jsonSK.ActivationEpoch,
 		NumActiveEpochs: jsonSK.NumActiveEpochs,
 	}, nil
-}
\ No newline at end of file
+}
+
+// publicKeyJSON is used for JSON serialization
+type publicKeyJSON struct {
+	Root      string `json:"Root"`
+	Parameter string `json:"Parameter"`
+}
+
+// MarshalJSON implements custom JSON marshaling for PublicKey
+func (pk *PublicKey) MarshalJSON() ([]byte, error) {
+	return json.Marshal(publicKeyJSON{
+		Root:      base64.StdEncoding.EncodeToString(pk.Root),
+		Parameter: base64.StdEncoding.EncodeToString(pk.Parameter),
+	})
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for PublicKey. The
+// decoded Root and Parameter keep their encoded lengths, so the key can be
+// passed to Verify directly.
+func (pk *PublicKey) UnmarshalJSON(data []byte) error {
+	var jsonPK publicKeyJSON
+	if err := json.Unmarshal(data, &jsonPK); err != nil {
+		return err
+	}
+	
+	root, err := base64.StdEncoding.DecodeString(jsonPK.Root)
+	if err != nil {
+		return err
+	}
+	param, err := base64.StdEncoding.DecodeString(jsonPK.Parameter)
+	if err != nil {
+		return err
+	}
+	if len(root) == 0 || len(param) == 0 {
+		return errors.New("public key JSON is missing Root or Parameter")
+	}
+	
+	pk.Root = root
+	pk.Parameter = param
+	return nil
+}
+
+// UnmarshalPublicKeyJSON decodes a public key and, if thash is non-nil,
+// checks its parameter with th.ValidateParameterCanonical and the length of
+// its root. Use this when loading keys from storage for a known scheme.
+func UnmarshalPublicKeyJSON(data []byte, thash th.TweakableHash) (*PublicKey, error) {
+	pk := &PublicKey{}
+	if err := pk.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	if thash == nil {
+		return pk, nil
+	}
+	
+	if err := th.ValidateParameterCanonical(thash, pk.Parameter); err != nil {
+		return nil, err
+	}
+	if len(pk.Root) != thash.OutputLen() {
+		return nil, fmt.Errorf("public key root has %d bytes, expected %d", len(pk.Root), thash.OutputLen())
+	}
+	return pk, nil
+}
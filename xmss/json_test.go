@@ -0,0 +1,174 @@
+package xmss
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"testing"
+	
+	"github.com/aerius-labs/hash-sig-go/encoding/winternitz"
+	"github.com/aerius-labs/hash-sig-go/internal/prf"
+	"github.com/aerius-labs/hash-sig-go/th"
+	"github.com/aerius-labs/hash-sig-go/th/message_hash"
+	"github.com/aerius-labs/hash-sig-go/th/tweak_hash"
+)
+
+// Test that a JSON round-tripped public key verifies signatures
+func TestPublicKeyJSONRoundTrip(t *testing.T) {
+	prfInstance := prf.NewSHA3PRF(24, 24)
+	thInstance := tweak_hash.NewSHA3TweakableHash(24, 24)
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	encInstance := winternitz.NewWinternitzEncoding(mhInstance, 4, 3)
+	
+	xmss := NewGeneralizedXMSS(prfInstance, encInstance, thInstance, 5)
+	pk, sk := xmss.KeyGen(rand.Reader, 0, 8)
+	
+	message := make([]byte, 32)
+	rand.Read(message)
+	sig, err := xmss.Sign(rand.Reader, sk, 2, message)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	
+	// Marshal as part of a keypair, the way callers store both halves
+	data, err := json.Marshal(struct {
+		PublicKey *PublicKey
+		SecretKey *SecretKey
+	}{pk, sk})
+	if err != nil {
+		t.Fatalf("Failed to marshal keypair: %v", err)
+	}
+	
+	var decoded struct {
+		PublicKey *PublicKey
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal public key: %v", err)
+	}
+	if !bytes.Equal(decoded.PublicKey.Root, pk.Root) || !bytes.Equal(decoded.PublicKey.Parameter, pk.Parameter) {
+		t.Fatal("Public key changed across JSON round trip")
+	}
+	if !xmss.Verify(decoded.PublicKey, 2, message, sig) {
+		t.Fatal("Decoded public key does not verify")
+	}
+	
+	for _, bad := range []string{`{"Root":"AAAA"}`, `{"Root":"!!","Parameter":"AAAA"}`, `[]`} {
+		var pk PublicKey
+		if err := json.Unmarshal([]byte(bad), &pk); err == nil {
+			t.Fatalf("Expected error for %s", bad)
+		}
+	}
+}
+
+// Test that a secret key with a registered scheme decodes with plain json.Unmarshal
+func TestSecretKeyJSONScheme(t *testing.T) {
+	prfInstance := prf.NewSHA3PRF(24, 24)
+	thInstance := tweak_hash.NewSHA3_192_192()
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	encInstance := winternitz.NewWinternitzEncoding(mhInstance, 4, 3)
+	
+	xmss := NewGeneralizedXMSS(prfInstance, encInstance, thInstance, 5)
+	pk, sk := xmss.KeyGen(rand.Reader, 0, 8)
+	
+	data, err := json.Marshal(sk)
+	if err != nil {
+		t.Fatalf("Failed to marshal secret key: %v", err)
+	}
+	var tagged struct{ Scheme string }
+	if err := json.Unmarshal(data, &tagged); err != nil || tagged.Scheme != "sha3-192-192" {
+		t.Fatalf("Expected scheme sha3-192-192, got %q (%v)", tagged.Scheme, err)
+	}
+	
+	var decoded SecretKey
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal secret key: %v", err)
+	}
+	message := make([]byte, 32)
+	rand.Read(message)
+	sig, err := xmss.Sign(rand.Reader, &decoded, 3, message)
+	if err != nil {
+		t.Fatalf("Failed to sign with decoded key: %v", err)
+	}
+	if !xmss.Verify(pk, 3, message, sig) {
+		t.Fatal("Signature from decoded key does not verify")
+	}
+	
+	// Unknown tags are an error rather than a key without a tree
+	bad := bytes.Replace(data, []byte(`"sha3-192-192"`), []byte(`"no-such-scheme"`), 1)
+	if err := json.Unmarshal(bad, &decoded); err == nil {
+		t.Fatal("Expected error for unknown scheme")
+	}
+	
+	// A key whose hash is not registered has no tag, and signing with its
+	// plain-decoded form fails cleanly
+	unregistered := NewGeneralizedXMSS(prf.NewSHA3PRF(24, 20), winternitz.NewWinternitzEncoding(message_hash.NewSHA3MessageHash(20, 24, 48, 4), 4, 3), tweak_hash.NewSHA3TweakableHash(20, 20), 5)
+	_, sk2 := unregistered.KeyGen(rand.Reader, 0, 8)
+	data, err = json.Marshal(sk2)
+	if err != nil {
+		t.Fatalf("Failed to marshal secret key: %v", err)
+	}
+	if bytes.Contains(data, []byte(`"Scheme"`)) {
+		t.Fatal("Unregistered hash should not be tagged")
+	}
+	var untagged SecretKey
+	if err := json.Unmarshal(data, &untagged); err != nil {
+		t.Fatalf("Failed to unmarshal secret key: %v", err)
+	}
+	if _, err := unregistered.Sign(rand.Reader, &untagged, 1, message); err == nil {
+		t.Fatal("Signing with a tree-less key should fail")
+	}
+	
+	// Once registered, the same configuration round-trips
+	RegisterScheme("test-sha3-160-160", func() th.TweakableHash { return tweak_hash.NewSHA3TweakableHash(20, 20) })
+	t.Cleanup(func() {
+		schemeMu.Lock()
+		delete(schemeRegistry, "test-sha3-160-160")
+		schemeMu.Unlock()
+	})
+	data, err = json.Marshal(sk2)
+	if err != nil {
+		t.Fatalf("Failed to marshal secret key: %v", err)
+	}
+	if err := json.Unmarshal(data, &untagged); err != nil || untagged.Tree == nil {
+		t.Fatalf("Registered scheme did not rebuild the tree: %v", err)
+	}
+}
+
+// Test that UnmarshalPublicKeyJSON rejects non-canonical Poseidon parameters
+func TestUnmarshalPublicKeyJSONCanonical(t *testing.T) {
+	thash := tweak_hash.NewPoseidonTweakHash(5, 7, 2, 9, 155)
+	
+	pk := &PublicKey{
+		Root:      thash.RandDomain(rand.Reader),
+		Parameter: thash.RandParameter(rand.Reader),
+	}
+	if err := th.ValidateParameterCanonical(thash, pk.Parameter); err != nil {
+		t.Fatalf("RandParameter produced a non-canonical parameter: %v", err)
+	}
+	data, err := json.Marshal(pk)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	if _, err := UnmarshalPublicKeyJSON(data, thash); err != nil {
+		t.Fatalf("Canonical key rejected: %v", err)
+	}
+	
+	// Element 2 encoded as p itself, which reads as zero
+	pk.Parameter = bytes.Clone(pk.Parameter)
+	copy(pk.Parameter[8:], []byte{0x78, 0x00, 0x00, 0x01})
+	data, _ = json.Marshal(pk)
+	if _, err := UnmarshalPublicKeyJSON(data, thash); !errors.Is(err, th.ErrNonCanonicalParameter) {
+		t.Fatalf("Expected ErrNonCanonicalParameter, got %v", err)
+	}
+	// Without a tweakable hash no validation happens
+	if _, err := UnmarshalPublicKeyJSON(data, nil); err != nil {
+		t.Fatalf("Unvalidated decode failed: %v", err)
+	}
+	
+	pk.Parameter = pk.Parameter[:16]
+	data, _ = json.Marshal(pk)
+	if _, err := UnmarshalPublicKeyJSON(data, thash); !errors.Is(err, th.ErrNonCanonicalParameter) {
+		t.Fatalf("Expected ErrNonCanonicalParameter for a short parameter, got %v", err)
+	}
+}
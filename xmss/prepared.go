@@ -0,0 +1,30 @@
+package xmss
+
+import (
+	"github.com/aerius-labs/hash-sig-go/th"
+)
+
+// PreparedPublicKey is a public key whose parameter has been precomputed
+// for a tweakable hash (e.g. converted to field elements), for verifiers
+// checking many signatures under one key
+type PreparedPublicKey struct {
+	*PublicKey
+	thash th.TweakableHash
+}
+
+// Prepare precomputes pk's parameter for thash, which must be the
+// tweakable hash of the scheme that will verify with it (see
+// VerifyPrepared). Hashes without a prepared form are used as they are.
+func (pk *PublicKey) Prepare(thash th.TweakableHash) *PreparedPublicKey {
+	return &PreparedPublicKey{
+		PublicKey: pk,
+		thash:     th.WithPreparedParams(thash, pk.Parameter),
+	}
+}
+
+// VerifyPrepared is Verify for a prepared public key. Chain walks and the
+// Merkle path reuse the precomputed parameter instead of converting it on
+// every hash call.
+func (g *GeneralizedXMSS) VerifyPrepared(pk *PreparedPublicKey, epoch uint32, message []byte, sig *Signature) bool {
+	return g.verifyDetailed(pk.PublicKey, pk.thash, epoch, message, sig) == nil
+}
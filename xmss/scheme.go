@@ -0,0 +1,89 @@
+package xmss
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	
+	"github.com/aerius-labs/hash-sig-go/th"
+	"github.com/aerius-labs/hash-sig-go/th/tweak_hash"
+)
+
+// Scheme tags name tweakable-hash configurations in serialized secret keys,
+// so a key can be decoded without the caller supplying the hash
+var (
+	schemeMu       sync.RWMutex
+	schemeRegistry = map[string]func() th.TweakableHash{
+		"sha3-128-192":   func() th.TweakableHash { return tweak_hash.NewSHA3_128_192() },
+		"sha3-192-192":   func() th.TweakableHash { return tweak_hash.NewSHA3_192_192() },
+		"blake3-128-192": func() th.TweakableHash { return tweak_hash.NewBlake3_128_192() },
+		"blake3-192-192": func() th.TweakableHash { return tweak_hash.NewBlake3_192_192() },
+		"poseidon-w1":    func() th.TweakableHash { return newPoseidonTweakHash(PoseidonNumChunksW1) },
+		"poseidon-w2":    func() th.TweakableHash { return newPoseidonTweakHash(PoseidonNumChunksW2) },
+		"poseidon-w4":    func() th.TweakableHash { return newPoseidonTweakHash(PoseidonNumChunksW4) },
+		"poseidon-w8":    func() th.TweakableHash { return newPoseidonTweakHash(PoseidonNumChunksW8) },
+		"poseidon-w256":  func() th.TweakableHash { return newPoseidonTweakHash(PoseidonTargetSumDim256) },
+	}
+)
+
+// newPoseidonTweakHash creates the tweakable hash of the Poseidon instantiations
+func newPoseidonTweakHash(numChunks int) th.TweakableHash {
+	return tweak_hash.NewPoseidonTweakHash(
+		PoseidonParameterLen,
+		PoseidonHashLenFE,
+		PoseidonTweakLenFE,
+		PoseidonCapacity,
+		numChunks,
+	)
+}
+
+// RegisterScheme registers a tweakable-hash configuration under tag. A
+// secret key whose hash equals (by value) the one newHash returns is
+// serialized with this tag and rebuilt with newHash by json.Unmarshal.
+// It panics if tag is empty or already registered.
+func RegisterScheme(tag string, newHash func() th.TweakableHash) {
+	if tag == "" {
+		panic("scheme tag must not be empty")
+	}
+	schemeMu.Lock()
+	defer schemeMu.Unlock()
+	if _, ok := schemeRegistry[tag]; ok {
+		panic("scheme " + tag + " already registered")
+	}
+	schemeRegistry[tag] = newHash
+}
+
+// SchemeTweakableHash returns a new instance of the tweakable hash registered under tag
+func SchemeTweakableHash(tag string) (th.TweakableHash, bool) {
+	schemeMu.RLock()
+	newHash, ok := schemeRegistry[tag]
+	schemeMu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return newHash(), true
+}
+
+// schemeTag returns the tag of the registered configuration equal to h, or
+// "" if there is none. Tags are tried in sorted order, so the result is
+// deterministic even if several tags share a configuration.
+func schemeTag(h th.TweakableHash) string {
+	if h == nil {
+		return ""
+	}
+	h = th.Unprepared(h)
+	schemeMu.RLock()
+	defer schemeMu.RUnlock()
+	
+	tags := make([]string, 0, len(schemeRegistry))
+	for tag := range schemeRegistry {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		if reflect.DeepEqual(schemeRegistry[tag](), h) {
+			return tag
+		}
+	}
+	return ""
+}
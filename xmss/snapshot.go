@@ -0,0 +1,40 @@
+package xmss
+
+import (
+	"github.com/aerius-labs/hash-sig-go/merkle"
+)
+
+// WithoutPath returns sig without its Merkle co-path, for verifiers that
+// pin the signer's tree and recompute co-paths with VerifyWithTree. This
+// saves logLifetime nodes per signature. The result shares Rho and Hashes
+// with sig.
+func (sig *Signature) WithoutPath() *Signature {
+	return &Signature{
+		Rho:    sig.Rho,
+		Hashes: sig.Hashes,
+	}
+}
+
+// VerifyWithTree verifies a signature without a co-path (see WithoutPath)
+// against snapshot, a copy of the signer's Merkle tree such as
+// sk.Tree. The co-path for epoch is taken from snapshot, and the public
+// key is the snapshot's root and parameter, so the snapshot must be
+// trusted: callers pinning one should check its Root against the signer's
+// public key once. Signatures that carry a co-path are rejected.
+func (g *GeneralizedXMSS) VerifyWithTree(snapshot *merkle.HashTree, epoch uint32, message []byte, sig *Signature) bool {
+	if len(sig.Path.CoPath) != 0 || uint64(epoch) >= g.Lifetime() {
+		return false
+	}
+	if snapshot.GetDepth() != g.logLifetime || len(snapshot.GetLayers()) != g.logLifetime+1 {
+		return false
+	}
+	root := snapshot.Root()
+	if root == nil {
+		return false
+	}
+	
+	full := *sig
+	full.Path = snapshot.Path(epoch)
+	pk := &PublicKey{Root: root, Parameter: snapshot.Parameter()}
+	return g.Verify(pk, epoch, message, &full)
+}
@@ -1,12 +1,16 @@
 package xmss
 
 import (
+	"bytes"
+	"crypto/subtle"
 	"errors"
 	"fmt"
 	"io"
+	"runtime"
 	"sync"
 	
 	"github.com/aerius-labs/hash-sig-go/encoding"
+	"github.com/aerius-labs/hash-sig-go/field"
 	"github.com/aerius-labs/hash-sig-go/internal/prf"
 	"github.com/aerius-labs/hash-sig-go/merkle"
 	"github.com/aerius-labs/hash-sig-go/th"
@@ -16,9 +20,17 @@ import (
 type SigningError struct {
 	Message  string
 	Attempts int
+	
+	// TargetSum and LastSum are set for sum-constrained encodings
+	// (Target-Sum) from the final failed attempt
+	TargetSum int
+	LastSum   int
 }
 
 func (e *SigningError) Error() string {
+	if e.TargetSum != 0 || e.LastSum != 0 {
+		return fmt.Sprintf("%s after %d attempts (last sum %d, target %d)", e.Message, e.Attempts, e.LastSum, e.TargetSum)
+	}
 	return fmt.Sprintf("%s after %d attempts", e.Message, e.Attempts)
 }
 
@@ -28,6 +40,14 @@ type PublicKey struct {
 	Parameter th.Params
 }
 
+// Equal reports whether two public keys have the same root and parameter
+func (pk *PublicKey) Equal(other *PublicKey) bool {
+	if pk == nil || other == nil {
+		return pk == other
+	}
+	return bytes.Equal(pk.Root, other.Root) && bytes.Equal(pk.Parameter, other.Parameter)
+}
+
 // SecretKey represents a generalized XMSS secret key
 type SecretKey struct {
 	PRFKey           []byte
@@ -35,6 +55,100 @@ type SecretKey struct {
 	Parameter        th.Params
 	ActivationEpoch  int
 	NumActiveEpochs  int
+	
+	// compact replaces Tree after CompactSecretKey
+	compact   *merkle.CompactHashTree
+	destroyed bool
+	// chainCache holds chain values for WithChainCache
+	chainCache *chainCache
+}
+
+// ErrKeyDestroyed indicates use of a secret key after Destroy
+var ErrKeyDestroyed = errors.New("secret key has been destroyed")
+
+// Destroy overwrites the PRF key and every tree node with zeros and marks
+// the key unusable: Sign and serialization fail with ErrKeyDestroyed
+// afterwards. This is best-effort. The Go runtime may already have copied
+// the key material (e.g. when growing slices or during garbage collection),
+// and copies handed out earlier are not affected.
+func (sk *SecretKey) Destroy() {
+	clear(sk.PRFKey)
+	sk.DropChainCache()
+	if sk.compact != nil {
+		sk.compact.Wipe()
+	}
+	if sk.Tree != nil {
+		layers := sk.Tree.GetLayers()
+		for i := range layers {
+			for _, node := range layers[i].GetNodes() {
+				clear(node)
+			}
+		}
+	}
+	sk.destroyed = true
+}
+
+// Equal reports whether two secret keys have the same PRF key, parameter,
+// activation range and Merkle tree, full or compact (see
+// CompactSecretKey), and are both destroyed or both usable. The PRF key
+// is compared in constant time. Chain caches are not compared.
+func (sk *SecretKey) Equal(other *SecretKey) bool {
+	if sk == nil || other == nil {
+		return sk == other
+	}
+	if subtle.ConstantTimeCompare(sk.PRFKey, other.PRFKey) != 1 {
+		return false
+	}
+	return bytes.Equal(sk.Parameter, other.Parameter) &&
+		sk.ActivationEpoch == other.ActivationEpoch &&
+		sk.NumActiveEpochs == other.NumActiveEpochs &&
+		sk.destroyed == other.destroyed &&
+		sk.Tree.Equal(other.Tree) &&
+		sk.compact.Equal(other.compact)
+}
+
+// Destroyed reports whether Destroy has been called on the key
+func (sk *SecretKey) Destroyed() bool {
+	return sk.destroyed
+}
+
+// ActiveRange returns the epochs sk was generated for, [start, end) with
+// end exclusive
+func (sk *SecretKey) ActiveRange() (start, end uint32) {
+	return uint32(sk.ActivationEpoch), uint32(sk.ActivationEpoch + sk.NumActiveEpochs)
+}
+
+// CanSign reports whether Sign can use sk at epoch: the key is not
+// destroyed, has a tree, and epoch is in its active range. Whether the
+// epoch was already used is up to the caller to track.
+func (sk *SecretKey) CanSign(epoch uint32) bool {
+	if sk.destroyed || (sk.Tree == nil && sk.compact == nil) {
+		return false
+	}
+	return int64(epoch) >= int64(sk.ActivationEpoch) && int64(epoch) < int64(sk.ActivationEpoch)+int64(sk.NumActiveEpochs)
+}
+
+// PublicKey returns the public key matching sk, e.g. after decoding a
+// secret key whose public key was not stored. It returns nil if the key
+// has been destroyed or has no tree.
+func (sk *SecretKey) PublicKey() *PublicKey {
+	if sk.destroyed {
+		return nil
+	}
+	var root th.Domain
+	switch {
+	case sk.compact != nil:
+		root = sk.compact.Root()
+	case sk.Tree != nil:
+		root = sk.Tree.Root()
+	}
+	if root == nil {
+		return nil
+	}
+	return &PublicKey{
+		Root:      root,
+		Parameter: bytes.Clone(sk.Parameter),
+	}
 }
 
 // Signature represents a generalized XMSS signature
@@ -44,12 +158,110 @@ type Signature struct {
 	Hashes []th.Domain
 }
 
+// Canonicalize rewrites every co-path node and chain hash in its canonical
+// encoding under thash, so that signatures differing only in the encoding
+// of equal field elements become byte-equal. It is a no-op for hashes
+// without alternative encodings (those not implementing
+// th.DomainCanonicalizer, such as SHA3). Verification is unaffected.
+func (sig *Signature) Canonicalize(thash th.TweakableHash) {
+	canon, ok := thash.(th.DomainCanonicalizer)
+	if !ok {
+		return
+	}
+	for i := range sig.Path.CoPath {
+		sig.Path.CoPath[i] = canon.CanonicalDomain(sig.Path.CoPath[i])
+	}
+	for i := range sig.Hashes {
+		sig.Hashes[i] = canon.CanonicalDomain(sig.Hashes[i])
+	}
+}
+
+// Equal reports whether two signatures have identical Rho, co-path and
+// chain hashes. Byte contents are compared in constant time; lengths are
+// public and may short-circuit.
+func (sig *Signature) Equal(other *Signature) bool {
+	if sig == nil || other == nil {
+		return sig == other
+	}
+	if len(sig.Path.CoPath) != len(other.Path.CoPath) || len(sig.Hashes) != len(other.Hashes) {
+		return false
+	}
+	
+	equal := subtle.ConstantTimeCompare(sig.Rho, other.Rho)
+	for i := range sig.Path.CoPath {
+		equal &= subtle.ConstantTimeCompare(sig.Path.CoPath[i], other.Path.CoPath[i])
+	}
+	for i := range sig.Hashes {
+		equal &= subtle.ConstantTimeCompare(sig.Hashes[i], other.Hashes[i])
+	}
+	return equal == 1
+}
+
 // GeneralizedXMSS implements the generalized XMSS signature scheme (Construction 3)
 type GeneralizedXMSS struct {
 	prf          prf.PRF
 	encoding     encoding.IncomparableEncoding
 	th           th.TweakableHash
 	logLifetime  int
+	
+	// perEpochParameters derives a separate chain parameter for every epoch
+	perEpochParameters bool
+	// progress, if set, is told about key-generation progress
+	progress func(done, total int)
+	// constantTimeChains makes Sign walk every chain its full length
+	constantTimeChains bool
+	// chainCacheSize bounds the chain values cached per secret key
+	chainCacheSize int
+	// context is the application context set by WithContext
+	context []byte
+	// treeSelfCheck makes KeyGen check sample paths of the new tree
+	treeSelfCheck bool
+}
+
+// Option configures optional behavior of a GeneralizedXMSS instance
+type Option func(*GeneralizedXMSS)
+
+// WithPerEpochParameters makes every epoch hash its chains under its own
+// parameter, derived publicly from the key's parameter and the epoch (see
+// EpochParameter). Message hashing and the Merkle tree keep using the key's
+// parameter, so keys and signatures have the same shape as without the
+// option, but the two schemes are incompatible.
+func WithPerEpochParameters() Option {
+	return func(g *GeneralizedXMSS) {
+		g.perEpochParameters = true
+	}
+}
+
+// WithProgress makes KeyGen (and RecomputeTree) call fn as work completes:
+// once per active epoch whose leaf is computed, then once per Merkle level
+// built, with total = numActiveEpochs + logLifetime. fn is always called
+// from the goroutine that called KeyGen, never concurrently.
+func WithProgress(fn func(done, total int)) Option {
+	return func(g *GeneralizedXMSS) {
+		g.progress = fn
+	}
+}
+
+// WithTreeSelfCheck makes KeyGen check the authentication paths of the
+// first, middle and last active epochs against the new tree's root (see
+// merkle.HashTree.SelfCheck), panicking if one does not match. It is a
+// debugging aid that costs a few path recomputations per key.
+func WithTreeSelfCheck() Option {
+	return func(g *GeneralizedXMSS) {
+		g.treeSelfCheck = true
+	}
+}
+
+// WithConstantTimeChains makes Sign walk every chain the full base-1 steps
+// and pick out the value at the codeword position with a constant-time
+// copy, so signing time does not depend on the codeword and thus on the
+// message hash. Signatures are identical to those without the option, at
+// roughly twice the average chain-walking cost for Winternitz and
+// Target-Sum encodings.
+func WithConstantTimeChains() Option {
+	return func(g *GeneralizedXMSS) {
+		g.constantTimeChains = true
+	}
 }
 
 // NewGeneralizedXMSS creates a new generalized XMSS instance
@@ -58,6 +270,7 @@ func NewGeneralizedXMSS(
 	encoding encoding.IncomparableEncoding,
 	th th.TweakableHash,
 	logLifetime int,
+	opts ...Option,
 ) *GeneralizedXMSS {
 	if logLifetime > 32 {
 		panic("lifetime beyond 2^32 not supported")
@@ -70,13 +283,91 @@ func NewGeneralizedXMSS(
 	if encoding.Dimension() > 256 {
 		panic("encoding dimension too large, must be at most 256")
 	}
+	// Chain starts come from the PRF and are walked as tweakable-hash domains
+	if prf.OutputLen() != th.OutputLen() {
+		panic(fmt.Sprintf("PRF output length %d differs from tweakable hash output length %d", prf.OutputLen(), th.OutputLen()))
+	}
 	
-	return &GeneralizedXMSS{
+	g := &GeneralizedXMSS{
 		prf:         prf,
 		encoding:    encoding,
 		th:          th,
 		logLifetime: logLifetime,
 	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	
+	if g.perEpochParameters && th.ParameterLen() > th.OutputLen() {
+		panic("per-epoch parameters need a parameter no longer than the hash output")
+	}
+	if err := g.validateEncoding(); err != nil {
+		panic(err.Error())
+	}
+	
+	return g
+}
+
+// epochParameterLevel is the tree-tweak level used to derive per-epoch
+// parameters. Trees have at most 32 levels, so it never collides with a node.
+const epochParameterLevel = 0xff
+
+// EpochParameter returns the parameter the chains of epoch are hashed
+// under. Without WithPerEpochParameters this is parameter itself. With it,
+// it is the first ParameterLen bytes of Th(parameter, (0xff, epoch), 0),
+// which anyone holding the public key can recompute.
+func (g *GeneralizedXMSS) EpochParameter(parameter th.Params, epoch uint32) th.Params {
+	if !g.perEpochParameters {
+		return parameter
+	}
+	zero := make(th.Domain, g.th.OutputLen())
+	derived := g.th.Apply(parameter, g.th.TreeTweak(epochParameterLevel, epoch), []th.Domain{zero})
+	return th.Params(derived[:g.th.ParameterLen()])
+}
+
+// ErrInvalidConfig indicates an inconsistent combination of scheme components
+var ErrInvalidConfig = errors.New("invalid scheme configuration")
+
+// ValidateConfig checks that the PRF, encoding and tweakable hash fit
+// together. Constructors panic on obviously broken single components; this
+// catches mismatches between components, e.g. in named instantiations.
+func (g *GeneralizedXMSS) ValidateConfig() error {
+	if g.logLifetime < 0 || g.logLifetime > 32 {
+		return fmt.Errorf("%w: log lifetime %d not in [0, 32]", ErrInvalidConfig, g.logLifetime)
+	}
+	
+	base := g.encoding.Base()
+	if base < 2 || base > 256 {
+		return fmt.Errorf("%w: encoding base %d not in [2, 256]", ErrInvalidConfig, base)
+	}
+	dimension := g.encoding.Dimension()
+	if dimension < 1 || dimension > 256 {
+		return fmt.Errorf("%w: encoding dimension %d not in [1, 256]", ErrInvalidConfig, dimension)
+	}
+	if err := g.validateEncoding(); err != nil {
+		return err
+	}
+	
+	// Chain starts come from the PRF and are fed to the tweakable hash
+	if g.prf.OutputLen() != g.th.OutputLen() {
+		return fmt.Errorf("%w: PRF output length %d differs from tweakable hash output length %d",
+			ErrInvalidConfig, g.prf.OutputLen(), g.th.OutputLen())
+	}
+	
+	return nil
+}
+
+// validateEncoding runs the encoding's own configuration check, which
+// covers its message hash, if it has one
+func (g *GeneralizedXMSS) validateEncoding() error {
+	v, ok := g.encoding.(encoding.Validator)
+	if !ok {
+		return nil
+	}
+	if err := v.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	return nil
 }
 
 // Lifetime returns the maximum number of epochs (L)
@@ -84,243 +375,812 @@ func (g *GeneralizedXMSS) Lifetime() uint64 {
 	return 1 << g.logLifetime
 }
 
+// Base returns the chain length, the base 2^w of the encoding
+func (g *GeneralizedXMSS) Base() int {
+	return g.encoding.Base()
+}
+
+// Dimension returns the number of chains, the encoding's codeword length v
+func (g *GeneralizedXMSS) Dimension() int {
+	return g.encoding.Dimension()
+}
+
+// ChunkSize returns w, the bits per codeword chunk
+func (g *GeneralizedXMSS) ChunkSize() int {
+	return g.encoding.ChunkSize()
+}
+
+// HashOutputLen returns the tweakable hash output length in bytes, the
+// size of every chain hash, co-path node and root
+func (g *GeneralizedXMSS) HashOutputLen() int {
+	return g.th.OutputLen()
+}
+
 // KeyGen generates a new key pair
 func (g *GeneralizedXMSS) KeyGen(rng io.Reader, activationEpoch, numActiveEpochs int) (*PublicKey, *SecretKey) {
-	// Validate parameters
-	if activationEpoch+numActiveEpochs > int(g.Lifetime()) {
-		panic("activation epoch and num active epochs invalid for this lifetime")
+	return g.keyGen(rng, activationEpoch, numActiveEpochs, nil)
+}
+
+// KeyGenWithMemStats runs KeyGen and also returns the peak runtime HeapAlloc
+// observed in bytes. The heap is sampled before and after key generation,
+// once the chain ends are computed, and after every Merkle tree layer, so
+// short-lived spikes between samples can be missed. The keys are the same as
+// KeyGen's for the same RNG output.
+func (g *GeneralizedXMSS) KeyGenWithMemStats(rng io.Reader, activationEpoch, numActiveEpochs int) (*PublicKey, *SecretKey, uint64) {
+	var stats runtime.MemStats
+	var peak uint64
+	sample := func(int) {
+		runtime.ReadMemStats(&stats)
+		peak = max(peak, stats.HeapAlloc)
 	}
 	
+	sample(0)
+	pk, sk := g.keyGen(rng, activationEpoch, numActiveEpochs, sample)
+	sample(0)
+	return pk, sk, peak
+}
+
+// keyGen implements KeyGen, calling onLayer (if non-nil) after each Merkle
+// tree layer is built
+func (g *GeneralizedXMSS) keyGen(rng io.Reader, activationEpoch, numActiveEpochs int, onLayer func(level int)) (*PublicKey, *SecretKey) {
 	// Generate random parameter for tweakable hash
-	parameter := g.th.RandParameter(rng)
+	parameter := g.randParameter(rng)
 	
 	// Generate PRF key
 	prfKey := g.prf.KeyGen(rng)
 	
-	// Generate chain ends for each active epoch
-	numChains := g.encoding.Dimension()
-	chainLength := g.encoding.Base()
+	return g.keyPair(rng, prfKey, parameter, activationEpoch, numActiveEpochs, onLayer)
+}
+
+// randParameter draws a tweakable-hash parameter from rng, refusing the
+// degenerate ones only a broken RNG produces
+func (g *GeneralizedXMSS) randParameter(rng io.Reader) th.Params {
+	parameter := g.th.RandParameter(rng)
+	if err := th.ValidateParameter(g.th, parameter); err != nil {
+		panic("generated parameter rejected: " + err.Error())
+	}
+	return parameter
+}
+
+// keyPair builds the Merkle tree of a key with the given PRF key and
+// parameter, drawing padding nodes from rng, and returns the key pair
+func (g *GeneralizedXMSS) keyPair(rng io.Reader, prfKey []byte, parameter th.Params, activationEpoch, numActiveEpochs int, onLayer func(level int)) (*PublicKey, *SecretKey) {
+	// Validate parameters
+	if activationEpoch+numActiveEpochs > int(g.Lifetime()) {
+		panic("activation epoch and num active epochs invalid for this lifetime")
+	}
+	
+	tree := g.buildTree(rng, prfKey, parameter, activationEpoch, numActiveEpochs, onLayer)
+	if g.treeSelfCheck && numActiveEpochs > 0 {
+		first := uint32(activationEpoch)
+		last := uint32(activationEpoch + numActiveEpochs - 1)
+		if !tree.SelfCheck([]uint32{first, first + (last-first)/2, last}) {
+			panic("Merkle tree failed self-check")
+		}
+	}
+	
+	root := tree.Root()
+	
+	pk := &PublicKey{
+		Root:      root,
+		Parameter: parameter,
+	}
+	
+	sk := &SecretKey{
+		PRFKey:          prfKey,
+		Tree:            tree,
+		Parameter:       parameter,
+		ActivationEpoch: activationEpoch,
+		NumActiveEpochs: numActiveEpochs,
+	}
+	
+	return pk, sk
+}
+
+// RecomputeTree rebuilds the Merkle tree of a key from its PRF key and
+// parameter alone and returns the root, independently of any stored tree.
+// Padding nodes are the only randomness in the tree: KeyGen draws them from
+// its rng after the parameter and the PRF key, so to audit a key generated
+// from a deterministic rng, pass that rng advanced past those two values.
+func (g *GeneralizedXMSS) RecomputeTree(prfKey []byte, parameter th.Params, activationEpoch, numActiveEpochs int, paddingRng io.Reader) th.Domain {
+	if activationEpoch+numActiveEpochs > int(g.Lifetime()) {
+		panic("activation epoch and num active epochs invalid for this lifetime")
+	}
+	return g.buildTree(paddingRng, prfKey, parameter, activationEpoch, numActiveEpochs, nil).Root()
+}
+
+// buildTree computes the leaves of all active epochs and builds the Merkle
+// tree over them, drawing padding nodes from rng
+func (g *GeneralizedXMSS) buildTree(rng io.Reader, prfKey []byte, parameter th.Params, activationEpoch, numActiveEpochs int, onLayer func(level int)) *merkle.HashTree {
+	// The parameter is fixed for the whole key, so prepare it once
+	thash := th.WithPreparedParams(g.th, parameter)
 	
 	// Parallelize chain end computation for each epoch
 	activationRange := activationEpoch
 	
 	chainEndsHashes := make([]th.Domain, numActiveEpochs)
 	
+	// Progress is counted over leaves, then tree levels
+	total := numActiveEpochs + g.logLifetime
+	report := func(done int) {
+		if g.progress != nil {
+			g.progress(done, total)
+		}
+	}
+	
 	// Use goroutines for parallel computation if we have many epochs
 	if numActiveEpochs > 10 {
-		var wg sync.WaitGroup
-		wg.Add(numActiveEpochs)
+		// Workers signal completed leaves; this goroutine reports them
+		finished := make(chan struct{}, numActiveEpochs)
 		
 		for i := 0; i < numActiveEpochs; i++ {
 			go func(epochOffset int) {
-				defer wg.Done()
 				epoch := activationRange + epochOffset
-				
-				// Compute chain ends for this epoch
-				chainEnds := make([]th.Domain, numChains)
-				for chainIndex := 0; chainIndex < numChains; chainIndex++ {
-					// Get chain start from PRF
-					start := g.prf.Apply(prfKey, uint32(epoch), uint64(chainIndex))
-					// Walk chain to get public chain end
-					chainEnds[chainIndex] = th.Chain(
-						g.th,
-						parameter,
-						uint32(epoch),
-						uint8(chainIndex),
-						0,
-						chainLength-1,
-						start,
-					)
-				}
-				
-				// Hash chain ends to get epoch's public key
-				leafTweak := g.th.TreeTweak(0, uint32(epoch))
-				chainEndsHashes[epochOffset] = g.th.Apply(parameter, leafTweak, chainEnds)
+				chainEndsHashes[epochOffset] = g.leafHash(thash, prfKey, parameter, uint32(epoch))
+				finished <- struct{}{}
 			}(i)
 		}
-		wg.Wait()
+		for done := 1; done <= numActiveEpochs; done++ {
+			<-finished
+			report(done)
+		}
 	} else {
 		// Sequential for small number of epochs
 		for epochOffset := 0; epochOffset < numActiveEpochs; epochOffset++ {
 			epoch := activationRange + epochOffset
-			
-			chainEnds := make([]th.Domain, numChains)
-			for chainIndex := 0; chainIndex < numChains; chainIndex++ {
-				start := g.prf.Apply(prfKey, uint32(epoch), uint64(chainIndex))
-				chainEnds[chainIndex] = th.Chain(
-					g.th,
-					parameter,
-					uint32(epoch),
-					uint8(chainIndex),
-					0,
-					chainLength-1,
-					start,
-				)
+			chainEndsHashes[epochOffset] = g.leafHash(thash, prfKey, parameter, uint32(epoch))
+			report(epochOffset + 1)
+		}
+	}
+	
+	observe := onLayer
+	if g.progress != nil {
+		observe = func(level int) {
+			if level > 0 {
+				report(numActiveEpochs + level)
+			}
+			if onLayer != nil {
+				onLayer(level)
 			}
-			
-			leafTweak := g.th.TreeTweak(0, uint32(epoch))
-			chainEndsHashes[epochOffset] = g.th.Apply(parameter, leafTweak, chainEnds)
 		}
 	}
 	
 	// Build Merkle tree
-	tree := merkle.NewHashTree(
+	return merkle.NewHashTreeObserved(
 		rng,
-		g.th,
+		thash,
 		g.logLifetime,
 		activationEpoch,
 		parameter,
 		chainEndsHashes,
+		observe,
 	)
+}
+
+// leafHash computes the Merkle leaf of an epoch: the hash of the ends of
+// all its chains, whose starts are derived from the PRF key
+func (g *GeneralizedXMSS) leafHash(thash th.TweakableHash, prfKey []byte, parameter th.Params, epoch uint32) th.Domain {
+	numChains := g.encoding.Dimension()
+	chainLength := g.encoding.Base()
+	prfKey = g.contextPRFKey(prfKey)
 	
-	root := tree.Root()
+	chainHash, chainParam := thash, parameter
+	if g.perEpochParameters {
+		chainParam = g.EpochParameter(parameter, epoch)
+		chainHash = th.WithPreparedParams(g.th, chainParam)
+	}
 	
-	pk := &PublicKey{
-		Root:      root,
-		Parameter: parameter,
+	chainEnds := domains(numChains, g.th.OutputLen())
+	for chainIndex := 0; chainIndex < numChains; chainIndex++ {
+		// Get chain start from PRF
+		start := g.prf.Apply(prfKey, epoch, uint64(chainIndex))
+		// Walk chain to get public chain end
+		th.ChainInto(
+			chainEnds[chainIndex],
+			chainHash,
+			chainParam,
+			epoch,
+			uint8(chainIndex),
+			0,
+			chainLength-1,
+			start,
+		)
 	}
 	
-	sk := &SecretKey{
-		PRFKey:          prfKey,
-		Tree:            tree,
-		Parameter:       parameter,
-		ActivationEpoch: activationEpoch,
-		NumActiveEpochs: numActiveEpochs,
+	// Hash chain ends to get epoch's public key
+	return merkle.HashLeaf(thash, parameter, epoch, chainEnds)
+}
+
+// CompactSecretKey replaces the key's Merkle tree with a CompactHashTree
+// that keeps only the top topLevels levels, so that the key holds about
+// 2^topLevels instead of 2^(logLifetime+1) nodes. Signing then
+// recomputes the leaves of a 2^(logLifetime+1-topLevels)-epoch subtree from
+// the PRF key, which costs that many key-generation leaf computations per
+// signature. Signatures are identical to those of the full key. A compact
+// key cannot be serialized.
+func (g *GeneralizedXMSS) CompactSecretKey(sk *SecretKey, topLevels int) error {
+	if sk.Tree == nil {
+		return errors.New("secret key has no Merkle tree")
 	}
 	
-	return pk, sk
+	thash := th.WithPreparedParams(g.th, sk.Parameter)
+	leafFn := func(index uint32) th.Domain {
+		return g.leafHash(thash, sk.PRFKey, sk.Parameter, index)
+	}
+	compact, err := sk.Tree.Compact(topLevels, sk.ActivationEpoch, sk.ActivationEpoch+sk.NumActiveEpochs, leafFn)
+	if err != nil {
+		return err
+	}
+	sk.compact = compact
+	sk.Tree = nil
+	return nil
 }
 
-// Sign creates a signature for a message at a specific epoch
+// ErrMessageLength indicates a message of a length the message hash does
+// not accept
+var ErrMessageLength = errors.New("message length not accepted by the message hash")
+
+// Sign creates a signature for a message at a specific epoch. Message
+// hashes that pack the message into field elements (the Poseidon ones)
+// accept only th.MessageLength-byte messages, and Sign returns
+// ErrMessageLength for others rather than let distinct messages collide.
 func (g *GeneralizedXMSS) Sign(rng io.Reader, sk *SecretKey, epoch uint32, message []byte) (*Signature, error) {
+	return g.sign(rng, sk, epoch, message, nil)
+}
+
+// SignDigest signs a th.MessageLength-byte digest of the actual message,
+// which every message hash accepts. Callers hash their messages to a
+// digest with a collision-resistant hash first; verify with Verify on
+// digest[:].
+func (g *GeneralizedXMSS) SignDigest(rng io.Reader, sk *SecretKey, epoch uint32, digest [th.MessageLength]byte) (*Signature, error) {
+	return g.sign(rng, sk, epoch, digest[:], nil)
+}
+
+// SignWithAttemptHook is Sign, calling hook after every encoding attempt
+// with the zero-based attempt index and the sum of the attempt's chunks.
+// For Target-Sum a successful attempt reports the target. The sum is -1
+// when a failed attempt has no sum to report (e.g. constant-weight
+// rejection). The hook observes only; the signature is the same as Sign's.
+func (g *GeneralizedXMSS) SignWithAttemptHook(rng io.Reader, sk *SecretKey, epoch uint32, message []byte, hook func(attempt int, sum int)) (*Signature, error) {
+	return g.sign(rng, sk, epoch, message, hook)
+}
+
+// sign implements Sign, reporting encoding attempts to hook if non-nil
+func (g *GeneralizedXMSS) sign(rng io.Reader, sk *SecretKey, epoch uint32, message []byte, hook func(attempt int, sum int)) (*Signature, error) {
+	if sk.destroyed {
+		return nil, ErrKeyDestroyed
+	}
+	if sk.Tree == nil && sk.compact == nil {
+		return nil, errors.New("secret key has no Merkle tree; decode untagged keys with UnmarshalSecretKey")
+	}
+	
 	// Check epoch is in activation range
-	if int(epoch) < sk.ActivationEpoch || int(epoch) >= sk.ActivationEpoch+sk.NumActiveEpochs {
+	if !sk.CanSign(epoch) {
 		return nil, errors.New("key not active during this epoch")
 	}
+	message, err := g.encodedMessage(message)
+	if err != nil {
+		return nil, err
+	}
 	
 	// Get Merkle path for this epoch
-	path := sk.Tree.Path(epoch)
+	var path merkle.HashTreeOpening
+	if sk.compact != nil {
+		path = sk.compact.Path(epoch)
+	} else {
+		path = sk.Tree.Path(epoch)
+	}
 	
+	codeword, rho, err := g.encodeForSigning(rng, sk.Parameter, epoch, message, hook)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Walk chain for steps determined by codeword, unless it is cached
+	prfKey := g.contextPRFKey(sk.PRFKey)
+	start := func(chainIndex int) th.Domain {
+		return g.prf.Apply(prfKey, epoch, uint64(chainIndex))
+	}
+	return &Signature{
+		Path:   path,
+		Rho:    rho,
+		Hashes: g.signatureHashes(sk.Parameter, epoch, codeword, start, g.chainCacheFor(sk)),
+	}, nil
+}
+
+// ChainStarts returns the chain starts of epoch derived from prfKey (bound
+// to the scheme's context, see WithContext), as SignWithChainStarts takes
+// them. This is the only part of signing that needs the PRF key.
+func (g *GeneralizedXMSS) ChainStarts(prfKey []byte, epoch uint32) []th.Domain {
+	prfKey = g.contextPRFKey(prfKey)
+	starts := make([]th.Domain, g.encoding.Dimension())
+	for chainIndex := range starts {
+		starts[chainIndex] = g.prf.Apply(prfKey, epoch, uint64(chainIndex))
+	}
+	return starts
+}
+
+// SignWithChainStarts signs message at epoch from explicit inputs instead
+// of a secret key: the key parameter, the epoch's chain starts (see
+// ChainStarts) and its Merkle path. It touches neither a PRF key nor a
+// tree, so the PRF can live behind a boundary such as an HSM that hands
+// out only the starts of the epoch being signed. Sign is
+//
+//	SignWithChainStarts(rng, sk.Parameter, epoch, message, g.ChainStarts(sk.PRFKey, epoch), sk.Tree.Path(epoch))
+//
+// except that it derives only the starts it needs and can use the chain
+// cache. The caller is responsible for signing each epoch only once.
+func (g *GeneralizedXMSS) SignWithChainStarts(rng io.Reader, parameter th.Params, epoch uint32, message []byte, chainStarts []th.Domain, path merkle.HashTreeOpening) (*Signature, error) {
+	if uint64(epoch) >= g.Lifetime() {
+		return nil, fmt.Errorf("%w: %d, lifetime is %d", ErrEpochOutOfRange, epoch, g.Lifetime())
+	}
+	if len(chainStarts) != g.encoding.Dimension() {
+		return nil, fmt.Errorf("%d chain starts, expected %d", len(chainStarts), g.encoding.Dimension())
+	}
+	for i, start := range chainStarts {
+		if len(start) != g.th.OutputLen() {
+			return nil, fmt.Errorf("chain start %d has %d bytes, expected %d", i, len(start), g.th.OutputLen())
+		}
+	}
+	if len(path.CoPath) != g.logLifetime {
+		return nil, fmt.Errorf("%d co-path nodes, expected %d", len(path.CoPath), g.logLifetime)
+	}
+	message, err := g.encodedMessage(message)
+	if err != nil {
+		return nil, err
+	}
+	
+	codeword, rho, err := g.encodeForSigning(rng, parameter, epoch, message, nil)
+	if err != nil {
+		return nil, err
+	}
+	start := func(chainIndex int) th.Domain {
+		return chainStarts[chainIndex]
+	}
+	return &Signature{
+		Path:   path,
+		Rho:    rho,
+		Hashes: g.signatureHashes(parameter, epoch, codeword, start, nil),
+	}, nil
+}
+
+// encodeForSigning draws randomness until message encodes at epoch, up to
+// the encoding's MaxTries attempts, reporting every attempt to hook if
+// non-nil
+func (g *GeneralizedXMSS) encodeForSigning(rng io.Reader, parameter th.Params, epoch uint32, message []byte, hook func(attempt int, sum int)) (encoding.Codeword, []byte, error) {
 	// Try to encode message
 	maxTries := g.encoding.MaxTries()
 	var codeword encoding.Codeword
 	var rho []byte
 	
+	// Only rho changes between attempts, so let the encoding precompute
+	// everything else once when it can
+	encode := func(rho []byte) (encoding.Codeword, error) {
+		return g.encoding.Encode(parameter, message, rho, epoch)
+	}
+	if preparer, ok := g.encoding.(encoding.EncodePreparer); ok {
+		prepared := preparer.PrepareEncode(parameter, message, epoch)
+		encode = func(rho []byte) (encoding.Codeword, error) {
+			return preparer.EncodePrepared(prepared, rho)
+		}
+	}
+	
+	// Refill one buffer per attempt when the encoding can; rho is only
+	// kept from the successful attempt, after which the loop stops
+	deterministic, isDeterministic := g.encoding.(encoding.DeterministicRandomness)
+	filler, isFiller := g.encoding.(encoding.RandomnessFiller)
+	if isFiller && !isDeterministic {
+		rho = make([]byte, filler.RandLen())
+	}
 	for attempts := 0; attempts < maxTries; attempts++ {
-		// Generate randomness
-		rho = g.encoding.RandRandomness(rng)
+		// Generate randomness, or derive it from the attempt counter
+		if isDeterministic {
+			rho = deterministic.AttemptRandomness(epoch, message, attempts)
+		} else if isFiller {
+			if err := filler.RandRandomnessInto(rho, rng); err != nil {
+				return nil, nil, err
+			}
+		} else {
+			var err error
+			if rho, err = g.encoding.RandRandomness(rng); err != nil {
+				return nil, nil, err
+			}
+		}
 		
 		// Try to encode
 		var err error
-		codeword, err = g.encoding.Encode(sk.Parameter, message, rho, epoch)
+		codeword, err = encode(rho)
+		if hook != nil {
+			hook(attempts, attemptSum(codeword, err))
+		}
 		if err == nil {
 			// Success
 			break
 		}
 		
 		if attempts == maxTries-1 {
-			return nil, &SigningError{
+			signErr := &SigningError{
 				Message:  "failed to encode message",
 				Attempts: maxTries,
 			}
+			var mismatch *encoding.SumMismatchError
+			if errors.As(err, &mismatch) {
+				signErr.TargetSum = mismatch.Target
+				signErr.LastSum = mismatch.Sum
+			}
+			return nil, nil, signErr
 		}
 	}
-	
-	// Compute hash values for each chain based on codeword
+	return codeword, rho, nil
+}
+
+// signatureHashes walks each chain of epoch from start(chainIndex) to its
+// codeword position. With a non-nil cache, cached values are reused and
+// new ones stored.
+func (g *GeneralizedXMSS) signatureHashes(parameter th.Params, epoch uint32, codeword encoding.Codeword, start func(chainIndex int) th.Domain, cache *chainCache) []th.Domain {
+	chainParam := g.EpochParameter(parameter, epoch)
+	thash := th.WithPreparedParams(g.th, chainParam)
 	numChains := g.encoding.Dimension()
-	hashes := make([]th.Domain, numChains)
+	hashes := domains(numChains, g.th.OutputLen())
 	
-	// Parallel computation for many chains
-	if numChains > 20 {
-		var wg sync.WaitGroup
-		wg.Add(numChains)
-		
-		for i := 0; i < numChains; i++ {
-			go func(chainIndex int) {
-				defer wg.Done()
-				// Get chain start from PRF
-				start := g.prf.Apply(sk.PRFKey, epoch, uint64(chainIndex))
-				// Walk chain for steps determined by codeword
-				steps := int(codeword[chainIndex])
-				hashes[chainIndex] = th.Chain(
-					g.th,
-					sk.Parameter,
-					epoch,
-					uint8(chainIndex),
-					0,
-					steps,
-					start,
-				)
-			}(i)
+	forEachChain(numChains, func(chainIndex int) {
+		key := chainCacheKey{epoch: epoch, chainIndex: uint8(chainIndex), steps: codeword[chainIndex]}
+		if cache != nil {
+			if value, ok := cache.get(g, key); ok {
+				copy(hashes[chainIndex], value)
+				return
+			}
 		}
-		wg.Wait()
-	} else {
-		// Sequential for small number of chains
+		g.signChain(hashes[chainIndex], thash, chainParam, epoch, chainIndex, int(codeword[chainIndex]), start(chainIndex))
+		if cache != nil {
+			cache.put(g, key, hashes[chainIndex])
+		}
+	})
+	return hashes
+}
+
+// parallelChainThreshold is the number of chains above which Sign and
+// Verify walk each chain in its own goroutine
+var parallelChainThreshold = 20
+
+// forEachChain calls walk for every chain index, in parallel for many
+// chains and sequentially otherwise
+func forEachChain(numChains int, walk func(chainIndex int)) {
+	if numChains <= parallelChainThreshold {
 		for chainIndex := 0; chainIndex < numChains; chainIndex++ {
-			start := g.prf.Apply(sk.PRFKey, epoch, uint64(chainIndex))
-			steps := int(codeword[chainIndex])
-			hashes[chainIndex] = th.Chain(
-				g.th,
-				sk.Parameter,
-				epoch,
-				uint8(chainIndex),
-				0,
-				steps,
-				start,
-			)
+			walk(chainIndex)
 		}
+		return
 	}
 	
-	return &Signature{
-		Path:   path,
-		Rho:    rho,
-		Hashes: hashes,
-	}, nil
+	var wg sync.WaitGroup
+	wg.Add(numChains)
+	for i := 0; i < numChains; i++ {
+		go func(chainIndex int) {
+			defer wg.Done()
+			walk(chainIndex)
+		}(i)
+	}
+	wg.Wait()
 }
 
+// domains returns n domain elements of the given length, sharing one
+// backing array; each is capped so appending to it cannot overwrite the next
+func domains(n, length int) []th.Domain {
+	backing := make([]byte, n*length)
+	out := make([]th.Domain, n)
+	for i := range out {
+		out[i] = backing[i*length : (i+1)*length : (i+1)*length]
+	}
+	return out
+}
+
+// signChain walks a chain from its start for the given number of steps,
+// writing the value into dst. With WithConstantTimeChains it walks the
+// whole chain instead, copying out the value after steps steps without
+// branching on steps.
+func (g *GeneralizedXMSS) signChain(dst th.Domain, thash th.TweakableHash, parameter th.Params, epoch uint32, chainIndex int, steps int, start th.Domain) {
+	if !g.constantTimeChains {
+		th.ChainInto(dst, thash, parameter, epoch, uint8(chainIndex), 0, steps, start)
+		return
+	}
+	
+	current := start
+	copy(dst, start)
+	for pos := 1; pos < g.encoding.Base(); pos++ {
+		tweak := thash.ChainTweak(epoch, uint8(chainIndex), uint8(pos))
+		current = thash.Apply(parameter, tweak, []th.Domain{current})
+		subtle.ConstantTimeCopy(subtle.ConstantTimeEq(int32(pos), int32(steps)), dst, current)
+	}
+}
+
+// Verification failures reported by VerifyDetailed
+var (
+	// ErrEpochOutOfRange indicates an epoch beyond the scheme's lifetime
+	ErrEpochOutOfRange = errors.New("epoch out of range")
+	
+	// ErrEncode indicates that the message and randomness do not encode
+	ErrEncode = errors.New("message encoding failed")
+	
+	// ErrCodewordLength indicates a codeword whose length is not the
+	// encoding dimension
+	ErrCodewordLength = errors.New("codeword has wrong length")
+	
+	// ErrMerkleMismatch indicates that the signature recomputes a root
+	// other than the public key's
+	ErrMerkleMismatch = errors.New("recomputed root does not match public key")
+	
+	// ErrMalformedSignature indicates a signature with the wrong number of
+	// chain hashes or co-path nodes
+	ErrMalformedSignature = errors.New("malformed signature")
+)
+
 // Verify verifies a signature
 func (g *GeneralizedXMSS) Verify(pk *PublicKey, epoch uint32, message []byte, sig *Signature) bool {
+	return g.VerifyDetailed(pk, epoch, message, sig) == nil
+}
+
+// VerifyDetailed verifies a signature like Verify, returning nil if it is
+// valid and otherwise an error wrapping ErrEpochOutOfRange,
+// ErrMalformedSignature, ErrMessageLength, ErrEncode, ErrCodewordLength or
+// ErrMerkleMismatch to say which step failed
+func (g *GeneralizedXMSS) VerifyDetailed(pk *PublicKey, epoch uint32, message []byte, sig *Signature) error {
+	return g.verifyDetailed(pk, th.WithPreparedParams(g.th, pk.Parameter), epoch, message, sig)
+}
+
+// verifyDetailed is VerifyDetailed hashing with thash, the scheme's
+// tweakable hash, possibly prepared for pk.Parameter
+func (g *GeneralizedXMSS) verifyDetailed(pk *PublicKey, thash th.TweakableHash, epoch uint32, message []byte, sig *Signature) error {
+	root, err := g.recomputeRoot(pk, thash, epoch, message, sig)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(root, pk.Root) {
+		return ErrMerkleMismatch
+	}
+	return nil
+}
+
+// RecomputeRoot runs verification up to the Merkle root, encoding the
+// message, completing the chains and walking the co-path, and returns the
+// root sig leads to without comparing it to pk.Root; only pk.Parameter is
+// used. Verify accepts exactly when this root equals pk.Root. It returns
+// the errors VerifyDetailed does for the steps before the comparison.
+func (g *GeneralizedXMSS) RecomputeRoot(pk *PublicKey, epoch uint32, message []byte, sig *Signature) (th.Domain, error) {
+	return g.recomputeRoot(pk, th.WithPreparedParams(g.th, pk.Parameter), epoch, message, sig)
+}
+
+// recomputeRoot is RecomputeRoot hashing with thash, as verifyDetailed
+func (g *GeneralizedXMSS) recomputeRoot(pk *PublicKey, thash th.TweakableHash, epoch uint32, message []byte, sig *Signature) (th.Domain, error) {
+	codeword, err := g.signatureCodeword(pk.Parameter, epoch, message, sig)
+	if err != nil {
+		return nil, err
+	}
+	return g.codewordRoot(thash, pk.Parameter, epoch, codeword, sig, false)
+}
+
+// signatureCodeword checks epoch and the shape of sig and recomputes the
+// codeword of message under sig.Rho, the steps of verification before the
+// chains are completed
+func (g *GeneralizedXMSS) signatureCodeword(parameter th.Params, epoch uint32, message []byte, sig *Signature) (encoding.Codeword, error) {
 	if uint64(epoch) >= g.Lifetime() {
-		return false
+		return nil, fmt.Errorf("%w: %d, lifetime is %d", ErrEpochOutOfRange, epoch, g.Lifetime())
+	}
+	if err := g.checkSignatureShape(sig); err != nil {
+		return nil, err
+	}
+	
+	message, err := g.encodedMessage(message)
+	if err != nil {
+		return nil, err
 	}
 	
 	// Recompute codeword from message and randomness
-	codeword, err := g.encoding.Encode(pk.Parameter, message, sig.Rho, epoch)
+	codeword, err := g.encoding.Encode(parameter, message, sig.Rho, epoch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEncode, err)
+	}
+	return codeword, nil
+}
+
+// VerifyAgainstFieldRoot is Verify for a public key stored as field
+// elements, for tweakable hashes whose domain elements and parameters are
+// 4-byte field-element words (the Poseidon instantiations). The recomputed
+// root is compared to rootFE as field elements, so non-canonical byte
+// encodings cannot cause a mismatch. It returns false if rootFE or
+// parameterFE has the wrong length.
+func (g *GeneralizedXMSS) VerifyAgainstFieldRoot(rootFE, parameterFE []field.Element, epoch uint32, message []byte, sig *Signature) bool {
+	if uint64(epoch) >= g.Lifetime() {
+		return false
+	}
+	if 4*len(rootFE) != g.th.OutputLen() || 4*len(parameterFE) != g.th.ParameterLen() {
+		return false
+	}
+	if g.checkSignatureShape(sig) != nil {
+		return false
+	}
+	
+	parameter := th.Params(field.ElementsToBytes(parameterFE))
+	message, err := g.encodedMessage(message)
+	if err != nil {
+		return false
+	}
+	codeword, err := g.encoding.Encode(parameter, message, sig.Rho, epoch)
 	if err != nil {
 		return false
 	}
+	root, err := g.codewordRoot(th.WithPreparedParams(g.th, parameter), parameter, epoch, codeword, sig, false)
+	if err != nil {
+		return false
+	}
+	
+	computed := field.BytesToElements(root, len(rootFE))
+	for i := range rootFE {
+		if !computed[i].Equal(&rootFE[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// VerifyWithChunks is Verify for a caller that already has the message-hash
+// output chunks, e.g. transmitted alongside the signature. It builds the
+// codeword from messageChunks instead of hashing a message, so sig.Rho is
+// not checked; the chunks must come from the message hash of the signed
+// message (its ContextMessage, with WithContext) under sig.Rho. Encodings must implement encoding.ChunkEncoder;
+// for others, and for chunks of the wrong count or range, it returns false.
+func (g *GeneralizedXMSS) VerifyWithChunks(pk *PublicKey, epoch uint32, messageChunks []uint8, sig *Signature) bool {
+	if uint64(epoch) >= g.Lifetime() {
+		return false
+	}
 	
+	if g.checkSignatureShape(sig) != nil {
+		return false
+	}
+	
+	chunkEncoder, ok := g.encoding.(encoding.ChunkEncoder)
+	if !ok {
+		return false
+	}
+	codeword, err := chunkEncoder.CodewordFromChunks(messageChunks)
+	if err != nil {
+		return false
+	}
+	return g.verifyCodeword(pk, th.WithPreparedParams(g.th, pk.Parameter), epoch, codeword, sig) == nil
+}
+
+// checkSignatureShape checks that sig has one hash per chain and one
+// co-path node per tree level, all of the hash output length, so
+// verification never indexes past them
+func (g *GeneralizedXMSS) checkSignatureShape(sig *Signature) error {
+	if len(sig.Hashes) != g.encoding.Dimension() {
+		return fmt.Errorf("%w: %d chain hashes, expected %d", ErrMalformedSignature, len(sig.Hashes), g.encoding.Dimension())
+	}
+	if len(sig.Path.CoPath) != g.logLifetime {
+		return fmt.Errorf("%w: %d co-path nodes, expected %d", ErrMalformedSignature, len(sig.Path.CoPath), g.logLifetime)
+	}
+	// Chain walks write into OutputLen buffers, and some hashes read
+	// inputs as a fixed number of field elements, so a longer element
+	// could otherwise verify like its prefix
+	outputLen := g.th.OutputLen()
+	for i, h := range sig.Hashes {
+		if len(h) != outputLen {
+			return fmt.Errorf("%w: chain hash %d has %d bytes, expected %d", ErrMalformedSignature, i, len(h), outputLen)
+		}
+	}
+	for i, node := range sig.Path.CoPath {
+		if len(node) != outputLen {
+			return fmt.Errorf("%w: co-path node %d has %d bytes, expected %d", ErrMalformedSignature, i, len(node), outputLen)
+		}
+	}
+	return nil
+}
+
+// verifyCodeword completes the chains of sig from codeword and checks the
+// resulting leaf against the public key
+func (g *GeneralizedXMSS) verifyCodeword(pk *PublicKey, thash th.TweakableHash, epoch uint32, codeword encoding.Codeword, sig *Signature) error {
+	root, err := g.codewordRoot(thash, pk.Parameter, epoch, codeword, sig, false)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(root, pk.Root) {
+		return ErrMerkleMismatch
+	}
+	return nil
+}
+
+// codewordRoot completes the chains of sig from codeword and returns the
+// root its Merkle path leads to. thash is the scheme's tweakable hash,
+// possibly prepared for parameter. With constantTime, each chain i also
+// hashes the codeword[i] steps the verifier skips, from the signature value
+// into a discarded buffer, so every chain costs base-1 steps.
+func (g *GeneralizedXMSS) codewordRoot(thash th.TweakableHash, parameter th.Params, epoch uint32, codeword encoding.Codeword, sig *Signature, constantTime bool) (th.Domain, error) {
 	// Recompute public keys from signature
 	chainLength := g.encoding.Base()
 	numChains := g.encoding.Dimension()
 	
 	if len(codeword) != numChains {
-		return false
+		return nil, fmt.Errorf("%w: %d, expected %d", ErrCodewordLength, len(codeword), numChains)
 	}
 	
-	chainEnds := make([]th.Domain, numChains)
-	for chainIndex := 0; chainIndex < numChains; chainIndex++ {
+	chainParam := g.EpochParameter(parameter, epoch)
+	chainHash := thash
+	if g.perEpochParameters {
+		chainHash = th.WithPreparedParams(g.th, chainParam)
+	}
+	chainEnds := domains(numChains, g.th.OutputLen())
+	var dummies []th.Domain
+	if constantTime {
+		dummies = domains(numChains, g.th.OutputLen())
+	}
+	forEachChain(numChains, func(chainIndex int) {
 		xi := codeword[chainIndex]
 		// Verifier walks from xi to chain end
 		steps := chainLength - 1 - int(xi)
-		chainEnds[chainIndex] = th.Chain(
-			g.th,
-			pk.Parameter,
+		th.ChainInto(
+			chainEnds[chainIndex],
+			chainHash,
+			chainParam,
 			epoch,
 			uint8(chainIndex),
 			uint8(xi),
 			steps,
 			sig.Hashes[chainIndex],
 		)
-	}
+		if constantTime {
+			th.ChainInto(dummies[chainIndex], chainHash, chainParam, epoch, uint8(chainIndex), 0, int(xi), sig.Hashes[chainIndex])
+		}
+	})
 	
-	// Verify Merkle path
-	return merkle.VerifyPath(
-		g.th,
-		pk.Parameter,
-		pk.Root,
-		epoch,
-		chainEnds,
-		sig.Path,
-	)
-}
\ No newline at end of file
+	// Recompute the root from the Merkle path
+	return merkle.PathRoot(thash, parameter, epoch, chainEnds, sig.Path), nil
+}
+
+// attemptSum returns the chunk sum of an encoding attempt, or -1 if a
+// failed attempt does not report one
+func attemptSum(codeword encoding.Codeword, err error) int {
+	if err != nil {
+		var mismatch *encoding.SumMismatchError
+		if errors.As(err, &mismatch) {
+			return mismatch.Sum
+		}
+		return -1
+	}
+	sum := 0
+	for _, x := range codeword {
+		sum += int(x)
+	}
+	return sum
+}
+
+// VerifyConstantTime is like Verify, but walks base-1 steps on every chain.
+// After completing chain i from codeword position xi, it hashes xi dummy
+// steps from the signature value and discards the result, so the number of
+// hash invocations does not depend on the codeword and timing does not leak
+// the message hash.
+func (g *GeneralizedXMSS) VerifyConstantTime(pk *PublicKey, epoch uint32, message []byte, sig *Signature) bool {
+	codeword, err := g.signatureCodeword(pk.Parameter, epoch, message, sig)
+	if err != nil {
+		return false
+	}
+	root, err := g.codewordRoot(th.WithPreparedParams(g.th, pk.Parameter), pk.Parameter, epoch, codeword, sig, true)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(root, pk.Root)
+}
+
+// VerifyWithTolerance verifies a signature for claimedEpoch, but only if
+// claimedEpoch is within tolerance epochs of currentEpoch in either
+// direction. This is a policy wrapper for verifiers that must accept
+// signatures slightly ahead of or behind their own view of the chain tip.
+func (g *GeneralizedXMSS) VerifyWithTolerance(pk *PublicKey, claimedEpoch, currentEpoch, tolerance uint32, message []byte, sig *Signature) bool {
+	distance := claimedEpoch - currentEpoch
+	if currentEpoch > claimedEpoch {
+		distance = currentEpoch - claimedEpoch
+	}
+	if distance > tolerance {
+		return false
+	}
+	return g.Verify(pk, claimedEpoch, message, sig)
+}
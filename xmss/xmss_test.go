@@ -1,12 +1,24 @@
 package xmss
 
 import (
+	"bytes"
 	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"sync/atomic"
 	"testing"
 	
+	"golang.org/x/crypto/sha3"
+	"github.com/aerius-labs/hash-sig-go/encoding"
+	"github.com/aerius-labs/hash-sig-go/encoding/constantweight"
 	"github.com/aerius-labs/hash-sig-go/encoding/winternitz"
+	"github.com/aerius-labs/hash-sig-go/field"
 	"github.com/aerius-labs/hash-sig-go/encoding/targetsum"
 	"github.com/aerius-labs/hash-sig-go/internal/prf"
+	"github.com/aerius-labs/hash-sig-go/merkle"
+	"github.com/aerius-labs/hash-sig-go/th"
 	"github.com/aerius-labs/hash-sig-go/th/tweak_hash"
 	"github.com/aerius-labs/hash-sig-go/th/message_hash"
 )
@@ -114,6 +126,347 @@ func TestTargetSumXMSS(t *testing.T) {
 	}
 }
 
+// Test that the attempt hook sees every Target-Sum attempt without changing the signature
+func TestSignWithAttemptHook(t *testing.T) {
+	const targetSum = 360
+	prfInstance := prf.NewSHA3PRF(24, 24)
+	thInstance := tweak_hash.NewSHA3TweakableHash(24, 24)
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	
+	// Counter-derived randomness makes both signing calls take the same attempts
+	encInstance := targetsum.NewTargetSumEncodingCounterRho(mhInstance, targetSum, []byte("hook seed"))
+	xmss := NewGeneralizedXMSS(prfInstance, encInstance, thInstance, 4)
+	pk, sk := xmss.KeyGen(rand.Reader, 0, 4)
+	
+	message := make([]byte, 32)
+	rand.Read(message)
+	
+	var attempts, sums []int
+	sig, err := xmss.SignWithAttemptHook(rand.Reader, sk, 2, message, func(attempt, sum int) {
+		attempts = append(attempts, attempt)
+		sums = append(sums, sum)
+	})
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	t.Logf("Sums per attempt: %v", sums)
+	
+	if len(attempts) == 0 {
+		t.Fatal("Hook was never called")
+	}
+	for i, a := range attempts {
+		if a != i {
+			t.Fatalf("Attempt %d reported as %d", i, a)
+		}
+	}
+	for _, sum := range sums[:len(sums)-1] {
+		if sum == targetSum || sum < 0 {
+			t.Fatalf("Failed attempt reported sum %d", sum)
+		}
+	}
+	if last := sums[len(sums)-1]; last != targetSum {
+		t.Fatalf("Final attempt reported sum %d, expected %d", last, targetSum)
+	}
+	
+	plain, err := xmss.Sign(rand.Reader, sk, 2, message)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	if !plain.Equal(sig) {
+		t.Fatal("Hook changed the signature")
+	}
+	if !xmss.Verify(pk, 2, message, sig) {
+		t.Fatal("Signature verification failed")
+	}
+}
+
+// Test the all-zero and all-0xFF messages, the extremes of the message
+// field-element packing, with SHA3 and Poseidon instantiations
+func TestEdgeCaseMessages(t *testing.T) {
+	schemes := []struct {
+		name string
+		new  func() *GeneralizedXMSS
+	}{
+		{"SHA3Winternitz", func() *GeneralizedXMSS {
+			mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+			return NewGeneralizedXMSS(prf.NewSHA3PRF(24, 24), winternitz.NewWinternitzEncoding(mhInstance, 4, 3), tweak_hash.NewSHA3TweakableHash(24, 24), 4)
+		}},
+		{"SHA3TargetSum", func() *GeneralizedXMSS {
+			mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+			return NewGeneralizedXMSS(prf.NewSHA3PRF(24, 24), targetsum.NewTargetSumEncoding(mhInstance, 360), tweak_hash.NewSHA3TweakableHash(24, 24), 4)
+		}},
+		{"PoseidonWinternitzW4", func() *GeneralizedXMSS { return NewPoseidonWinternitzW4Test(4) }},
+		{"PoseidonWinternitzW2", func() *GeneralizedXMSS { return NewPoseidonWinternitzW2Test(4) }},
+		{"PoseidonWinternitzW8", func() *GeneralizedXMSS { return NewPoseidonWinternitzW8Test(4) }},
+	}
+	messages := map[string][]byte{
+		"AllZeros": make([]byte, 32),
+		"AllOnes":  bytes.Repeat([]byte{0xFF}, 32),
+	}
+	
+	for _, scheme := range schemes {
+		t.Run(scheme.name, func(t *testing.T) {
+			xmss := scheme.new()
+			pk, sk := xmss.KeyGen(rand.Reader, 2, 2)
+			
+			for name, message := range messages {
+				sig, err := xmss.Sign(rand.Reader, sk, 2, message)
+				if err != nil {
+					t.Fatalf("%s: failed to sign: %v", name, err)
+				}
+				if !xmss.Verify(pk, 2, message, sig) {
+					t.Fatalf("%s: signature verification failed", name)
+				}
+				if xmss.Verify(pk, 3, message, sig) {
+					t.Fatalf("%s: signature verified for the wrong epoch", name)
+				}
+			}
+			
+			// The two messages must not be interchangeable
+			sig, err := xmss.Sign(rand.Reader, sk, 3, messages["AllZeros"])
+			if err != nil {
+				t.Fatalf("Failed to sign: %v", err)
+			}
+			if xmss.Verify(pk, 3, messages["AllOnes"], sig) {
+				t.Fatal("All-zero signature verified for the all-0xFF message")
+			}
+		})
+	}
+}
+
+func TestTargetSumSigningErrorReportsSums(t *testing.T) {
+	prfInstance := prf.NewSHA3PRF(24, 24)
+	thInstance := tweak_hash.NewSHA3TweakableHash(24, 24)
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	
+	// The maximum possible sum: every chunk would have to be 15. A small
+	// budget keeps the test fast and must be reported back.
+	encInstance := targetsum.NewTargetSumEncodingWithTries(mhInstance, 48*15, 1000)
+	
+	xmss := NewGeneralizedXMSS(prfInstance, encInstance, thInstance, 2)
+	_, sk := xmss.KeyGen(rand.Reader, 0, 4)
+	
+	message := make([]byte, 32)
+	rand.Read(message)
+	
+	_, err := xmss.Sign(rand.Reader, sk, 1, message)
+	var signErr *SigningError
+	if !errors.As(err, &signErr) {
+		t.Fatalf("Expected a SigningError, got %v", err)
+	}
+	if signErr.Attempts != 1000 {
+		t.Fatalf("Expected 1000 attempts, got %d", signErr.Attempts)
+	}
+	if signErr.TargetSum != 48*15 {
+		t.Fatalf("Expected target sum %d, got %d", 48*15, signErr.TargetSum)
+	}
+	if signErr.LastSum <= 0 || signErr.LastSum >= 48*15 {
+		t.Fatalf("Implausible last sum %d", signErr.LastSum)
+	}
+}
+
+func TestConstantWeightXMSS(t *testing.T) {
+	prfInstance := prf.NewSHA3PRF(24, 24)
+	thInstance := tweak_hash.NewSHA3TweakableHash(24, 24)
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	
+	// 160 binary chains with exactly 40 set: log2(C(160, 40)) is about 130 bits
+	encInstance := constantweight.NewConstantWeightEncoding(mhInstance, 160, 40)
+	
+	xmss := NewGeneralizedXMSS(prfInstance, encInstance, thInstance, 8)
+	pk, sk := xmss.KeyGen(rand.Reader, 0, int(xmss.Lifetime()))
+	
+	testEpochs := []uint32{0, 9, 13, 21, 31}
+	
+	for _, epoch := range testEpochs {
+		message := make([]byte, 32)
+		if _, err := rand.Read(message); err != nil {
+			t.Fatalf("Failed to generate random message: %v", err)
+		}
+		
+		sig, err := xmss.Sign(rand.Reader, sk, epoch, message)
+		if err != nil {
+			t.Fatalf("Failed to sign at epoch %d: %v", epoch, err)
+		}
+		
+		if !xmss.Verify(pk, epoch, message, sig) {
+			t.Fatalf("Signature verification failed at epoch %d", epoch)
+		}
+	}
+}
+
+func TestTargetSumCounterRhoDeterministic(t *testing.T) {
+	prfInstance := prf.NewSHA3PRF(24, 24)
+	thInstance := tweak_hash.NewSHA3TweakableHash(24, 24)
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	targetSum := targetsum.ComputeOptimalTarget(48, 4, 1.0)
+	seed := []byte("counter rho test seed")
+	
+	xmss := NewGeneralizedXMSS(prfInstance, targetsum.NewTargetSumEncodingCounterRho(mhInstance, targetSum, seed), thInstance, 5)
+	pk, sk := xmss.KeyGen(rand.Reader, 0, 32)
+	
+	message := make([]byte, 32)
+	rand.Read(message)
+	
+	sig1, err := xmss.Sign(rand.Reader, sk, 4, message)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	if !xmss.Verify(pk, 4, message, sig1) {
+		t.Fatal("Signature verification failed")
+	}
+	
+	// A separately constructed scheme with the same seed converges to the same rho
+	again := NewGeneralizedXMSS(prfInstance, targetsum.NewTargetSumEncodingCounterRho(mhInstance, targetSum, seed), thInstance, 5)
+	sig2, err := again.Sign(rand.Reader, sk, 4, message)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	if !sig1.Equal(sig2) {
+		t.Fatal("Same seed and message produced different signatures")
+	}
+	
+	// A different seed walks a different attempt sequence
+	other := NewGeneralizedXMSS(prfInstance, targetsum.NewTargetSumEncodingCounterRho(mhInstance, targetSum, []byte("other seed")), thInstance, 5)
+	sig3, err := other.Sign(rand.Reader, sk, 4, message)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	if bytes.Equal(sig1.Rho, sig3.Rho) {
+		t.Fatal("Different seeds produced the same rho")
+	}
+}
+
+func TestSignatureEqual(t *testing.T) {
+	prfInstance := prf.NewSHA3PRF(24, 24)
+	thInstance := tweak_hash.NewSHA3TweakableHash(24, 24)
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	encInstance := winternitz.NewWinternitzEncoding(mhInstance, 4, 3)
+	
+	xmss := NewGeneralizedXMSS(prfInstance, encInstance, thInstance, 5)
+	_, sk := xmss.KeyGen(rand.Reader, 0, 32)
+	
+	message := make([]byte, 32)
+	rand.Read(message)
+	
+	sig, err := xmss.Sign(rand.Reader, sk, 6, message)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	
+	// A serialize-deserialize round trip is equal
+	encoded, err := sig.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	decoded, err := UnmarshalSignatureVersioned(encoded)
+	if err != nil {
+		t.Fatalf("UnmarshalSignatureVersioned failed: %v", err)
+	}
+	if !sig.Equal(decoded) {
+		t.Fatal("Round-tripped signature should be equal")
+	}
+	
+	// Re-signing draws a fresh rho
+	resigned, err := xmss.Sign(rand.Reader, sk, 6, message)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	if sig.Equal(resigned) {
+		t.Fatal("Signatures under different rho should differ")
+	}
+	
+	if sig.Equal(nil) {
+		t.Fatal("Signature should not equal nil")
+	}
+	
+	// Nil and truncated fields
+	for name, other := range map[string]*Signature{
+		"NilRho":      {Path: sig.Path, Hashes: sig.Hashes},
+		"FewerHashes": {Path: sig.Path, Rho: sig.Rho, Hashes: sig.Hashes[1:]},
+		"NoCoPath":    {Rho: sig.Rho, Hashes: sig.Hashes},
+	} {
+		if sig.Equal(other) || other.Equal(sig) {
+			t.Fatalf("%s: signature compares equal", name)
+		}
+	}
+}
+
+// Test public- and secret-key equality across round trips, nil fields and unequal lengths
+func TestKeyEqual(t *testing.T) {
+	xmss := NewSHA3WinternitzW4Test(4)
+	seeded := func() io.Reader {
+		shake := sha3.NewShake128()
+		shake.Write([]byte("key equality"))
+		return shake
+	}
+	pk, sk := xmss.KeyGen(seeded(), 2, 10)
+	pk2, sk2 := xmss.KeyGen(seeded(), 2, 10)
+	otherPK, otherSK := xmss.KeyGen(rand.Reader, 2, 10)
+	
+	if !pk.Equal(pk2) || !sk.Equal(sk2) {
+		t.Fatal("Keys from the same RNG output should be equal")
+	}
+	if pk.Equal(otherPK) || sk.Equal(otherSK) {
+		t.Fatal("Independent keys should differ")
+	}
+	if pk.Equal(nil) || sk.Equal(nil) || !(*PublicKey)(nil).Equal(nil) {
+		t.Fatal("Only nil equals nil")
+	}
+	
+	encoded, err := sk.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	decoded, err := UnmarshalSecretKeyBinary(encoded, xmss.th)
+	if err != nil {
+		t.Fatalf("UnmarshalSecretKeyBinary failed: %v", err)
+	}
+	if !sk.Equal(decoded) || !pk.Equal(decoded.PublicKey()) {
+		t.Fatal("Round-tripped keys should be equal")
+	}
+	
+	// Nil and truncated fields
+	if pk.Equal(&PublicKey{Root: pk.Root}) || pk.Equal(&PublicKey{Root: pk.Root[1:], Parameter: pk.Parameter}) {
+		t.Fatal("Public key equal to one with a missing parameter or short root")
+	}
+	mutations := map[string]func(sk *SecretKey){
+		"NilTree":    func(sk *SecretKey) { sk.Tree = nil },
+		"ShortPRF":   func(sk *SecretKey) { sk.PRFKey = sk.PRFKey[1:] },
+		"NilParam":   func(sk *SecretKey) { sk.Parameter = nil },
+		"Range":      func(sk *SecretKey) { sk.NumActiveEpochs-- },
+		"Destroyed":  func(sk *SecretKey) { sk.Destroy() },
+		"FewerNodes": func(sk *SecretKey) {
+			layers := sk.Tree.GetLayers()
+			layers[0] = merkle.NewHashTreeLayer(layers[0].GetStartIndex(), layers[0].GetNodes()[1:])
+		},
+	}
+	for name, mutate := range mutations {
+		mutated, err := UnmarshalSecretKeyBinary(encoded, xmss.th)
+		if err != nil {
+			t.Fatalf("UnmarshalSecretKeyBinary failed: %v", err)
+		}
+		mutate(mutated)
+		if sk.Equal(mutated) || mutated.Equal(sk) {
+			t.Fatalf("%s: mutated key compares equal", name)
+		}
+	}
+	
+	// Compact keys equal each other, not the full key
+	if err := xmss.CompactSecretKey(sk, 2); err != nil {
+		t.Fatalf("CompactSecretKey failed: %v", err)
+	}
+	if sk.Equal(sk2) {
+		t.Fatal("Compact key equal to the full key")
+	}
+	if err := xmss.CompactSecretKey(sk2, 2); err != nil {
+		t.Fatalf("CompactSecretKey failed: %v", err)
+	}
+	if !sk.Equal(sk2) {
+		t.Fatal("Equal keys compacted alike should be equal")
+	}
+}
+
 func TestPartialLifetime(t *testing.T) {
 	// Test with partial lifetime activation
 	prfInstance := prf.NewSHA3PRF(24, 24)
@@ -154,45 +507,1246 @@ func TestPartialLifetime(t *testing.T) {
 	}
 }
 
-func BenchmarkWinternitzSign(b *testing.B) {
+// Test that ActiveRange and CanSign agree with what Sign accepts
+func TestSecretKeyActiveRange(t *testing.T) {
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	xmss := NewGeneralizedXMSS(prf.NewSHA3PRF(24, 24), winternitz.NewWinternitzEncoding(mhInstance, 4, 3), tweak_hash.NewSHA3TweakableHash(24, 24), 5)
+	_, sk := xmss.KeyGen(rand.Reader, 10, 10)
+	
+	if start, end := sk.ActiveRange(); start != 10 || end != 20 {
+		t.Fatalf("Expected range [10, 20), got [%d, %d)", start, end)
+	}
+	
+	message := make([]byte, 32)
+	for _, epoch := range []uint32{0, 9, 10, 19, 20, 31, 1 << 31} {
+		_, err := xmss.Sign(rand.Reader, sk, epoch, message)
+		if sk.CanSign(epoch) != (err == nil) {
+			t.Fatalf("Epoch %d: CanSign %v, but Sign returned %v", epoch, sk.CanSign(epoch), err)
+		}
+	}
+	
+	sk.Destroy()
+	if sk.CanSign(15) {
+		t.Fatal("Destroyed key can sign")
+	}
+}
+
+func TestVerifyWithTolerance(t *testing.T) {
 	prfInstance := prf.NewSHA3PRF(24, 24)
 	thInstance := tweak_hash.NewSHA3TweakableHash(24, 24)
 	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
 	encInstance := winternitz.NewWinternitzEncoding(mhInstance, 4, 3)
 	
-	xmss := NewGeneralizedXMSS(prfInstance, encInstance, thInstance, 9)
-	_, sk := xmss.KeyGen(rand.Reader, 0, 512)
+	xmss := NewGeneralizedXMSS(prfInstance, encInstance, thInstance, 5)
+	pk, sk := xmss.KeyGen(rand.Reader, 0, 32)
 	
 	message := make([]byte, 32)
 	rand.Read(message)
 	
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		epoch := uint32(i % 512)
-		_, err := xmss.Sign(rand.Reader, sk, epoch, message)
-		if err != nil {
-			b.Fatal(err)
+	const claimed = 10
+	sig, err := xmss.Sign(rand.Reader, sk, claimed, message)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	
+	// Within tolerance on either side of the current epoch
+	for _, current := range []uint32{8, 10, 12} {
+		if !xmss.VerifyWithTolerance(pk, claimed, current, 2, message, sig) {
+			t.Fatalf("Should accept claimed epoch %d at current epoch %d", claimed, current)
+		}
+	}
+	
+	// Beyond tolerance, even though the signature itself is valid
+	for _, current := range []uint32{7, 13, 0xFFFFFFFF} {
+		if xmss.VerifyWithTolerance(pk, claimed, current, 2, message, sig) {
+			t.Fatalf("Should reject claimed epoch %d at current epoch %d", claimed, current)
 		}
 	}
+	
+	// Within tolerance but cryptographically invalid
+	if xmss.VerifyWithTolerance(pk, claimed+1, claimed, 2, message, sig) {
+		t.Fatal("Should reject a signature for a different epoch")
+	}
 }
 
-func BenchmarkWinternitzVerify(b *testing.B) {
+// Test that Destroy wipes key material and blocks further use
+func TestSecretKeyDestroy(t *testing.T) {
 	prfInstance := prf.NewSHA3PRF(24, 24)
 	thInstance := tweak_hash.NewSHA3TweakableHash(24, 24)
 	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
 	encInstance := winternitz.NewWinternitzEncoding(mhInstance, 4, 3)
 	
-	xmss := NewGeneralizedXMSS(prfInstance, encInstance, thInstance, 9)
-	pk, sk := xmss.KeyGen(rand.Reader, 0, 512)
+	xmss := NewGeneralizedXMSS(prfInstance, encInstance, thInstance, 5)
+	pk, sk := xmss.KeyGen(rand.Reader, 0, 8)
 	
 	message := make([]byte, 32)
 	rand.Read(message)
-	sig, _ := xmss.Sign(rand.Reader, sk, 0, message)
+	sig, err := xmss.Sign(rand.Reader, sk, 3, message)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
 	
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		if !xmss.Verify(pk, 0, message, sig) {
-			b.Fatal("Verification failed")
+	sk.Destroy()
+	if !sk.Destroyed() {
+		t.Fatal("Key not marked destroyed")
+	}
+	
+	zero := func(b []byte) bool {
+		for _, x := range b {
+			if x != 0 {
+				return false
+			}
 		}
+		return true
 	}
-}
\ No newline at end of file
+	if !zero(sk.PRFKey) {
+		t.Error("PRF key not zeroed")
+	}
+	layers := sk.Tree.GetLayers()
+	for i := range layers {
+		for j, node := range layers[i].GetNodes() {
+			if !zero(node) {
+				t.Fatalf("Tree node %d in layer %d not zeroed", j, i)
+			}
+		}
+	}
+	
+	if _, err := xmss.Sign(rand.Reader, sk, 4, message); !errors.Is(err, ErrKeyDestroyed) {
+		t.Fatalf("Expected ErrKeyDestroyed from Sign, got %v", err)
+	}
+	if _, err := sk.MarshalBinary(); !errors.Is(err, ErrKeyDestroyed) {
+		t.Fatalf("Expected ErrKeyDestroyed from MarshalBinary, got %v", err)
+	}
+	
+	// Signatures made before destruction stay valid
+	if !xmss.Verify(pk, 3, message, sig) {
+		t.Fatal("Earlier signature no longer verifies")
+	}
+}
+
+// Test that a signature cannot be moved to another epoch by splicing in that
+// epoch's co-path, in a sparse tree where both epochs are active
+func TestVerifyRejectsSplicedCoPath(t *testing.T) {
+	prfInstance := prf.NewSHA3PRF(24, 24)
+	thInstance := tweak_hash.NewSHA3TweakableHash(24, 24)
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	encInstance := winternitz.NewWinternitzEncoding(mhInstance, 4, 3)
+	
+	xmss := NewGeneralizedXMSS(prfInstance, encInstance, thInstance, 5)
+	pk, sk := xmss.KeyGen(rand.Reader, 3, 10)
+	
+	message := make([]byte, 32)
+	rand.Read(message)
+	
+	// Sibling leaves (4, 5), leaves sharing only upper levels (4, 7), and
+	// leaves next to padding at either end of the active range
+	for _, pair := range [][2]uint32{{4, 5}, {5, 4}, {4, 7}, {3, 12}, {12, 3}} {
+		a, b := pair[0], pair[1]
+		sigA, err := xmss.Sign(rand.Reader, sk, a, message)
+		if err != nil {
+			t.Fatalf("Failed to sign epoch %d: %v", a, err)
+		}
+		if !xmss.Verify(pk, a, message, sigA) {
+			t.Fatalf("Valid signature at epoch %d rejected", a)
+		}
+		
+		spliced := *sigA
+		spliced.Path = sk.Tree.Path(b)
+		if xmss.Verify(pk, a, message, &spliced) {
+			t.Fatalf("Epoch %d signature with epoch %d co-path accepted at %d", a, b, a)
+		}
+		if xmss.Verify(pk, b, message, &spliced) {
+			t.Fatalf("Epoch %d signature with epoch %d co-path accepted at %d", a, b, b)
+		}
+		if xmss.Verify(pk, b, message, sigA) {
+			t.Fatalf("Epoch %d signature accepted at epoch %d", a, b)
+		}
+	}
+}
+
+// Test that non-canonical field-element encodings verify like their
+// canonical form and normalize to it under Canonicalize
+func TestSignatureCanonicalize(t *testing.T) {
+	xmss := NewPoseidonWinternitzW4Test(4)
+	pk, sk := xmss.KeyGen(rand.Reader, 5, 1)
+	
+	message := make([]byte, 32)
+	rand.Read(message)
+	sig, err := xmss.Sign(rand.Reader, sk, 5, message)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	
+	// Replace the first word of a node by word + p wherever that still fits
+	// in 32 bits; the field element is unchanged
+	alias := func(d th.Domain) (th.Domain, bool) {
+		v := binary.BigEndian.Uint32(d)
+		if uint64(v)+field.P > math.MaxUint32 {
+			return nil, false
+		}
+		out := bytes.Clone(d)
+		binary.BigEndian.PutUint32(out, v+uint32(field.P))
+		return out, true
+	}
+	malleated := &Signature{Rho: sig.Rho}
+	changed := 0
+	for _, d := range sig.Path.CoPath {
+		if a, ok := alias(d); ok {
+			d = a
+			changed++
+		}
+		malleated.Path.CoPath = append(malleated.Path.CoPath, d)
+	}
+	for _, d := range sig.Hashes {
+		if a, ok := alias(d); ok {
+			d = a
+			changed++
+		}
+		malleated.Hashes = append(malleated.Hashes, d)
+	}
+	if changed == 0 {
+		t.Fatal("No node could be re-encoded")
+	}
+	
+	if malleated.Equal(sig) {
+		t.Fatal("Re-encoded signature should differ byte-wise")
+	}
+	if !xmss.Verify(pk, 5, message, malleated) {
+		t.Fatal("Non-canonical encoding should verify like the canonical one")
+	}
+	
+	// Random padding nodes on the co-path need not be canonical either, so
+	// compare both signatures after normalization
+	malleated.Canonicalize(xmss.th)
+	sig.Canonicalize(xmss.th)
+	if !malleated.Equal(sig) {
+		t.Fatal("Canonicalized signatures should be byte-equal")
+	}
+	if !xmss.Verify(pk, 5, message, malleated) {
+		t.Fatal("Canonicalized signature should verify")
+	}
+}
+
+// Test that a compacted secret key signs exactly like the full key
+func TestCompactSecretKey(t *testing.T) {
+	prfInstance := prf.NewSHA3PRF(24, 24)
+	thInstance := tweak_hash.NewSHA3TweakableHash(24, 24)
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	
+	// Counter-derived randomness makes signatures deterministic
+	encInstance := targetsum.NewTargetSumEncodingCounterRho(mhInstance, 360, []byte("compact seed"))
+	xmss := NewGeneralizedXMSS(prfInstance, encInstance, thInstance, 6)
+	seeded := func() io.Reader {
+		shake := sha3.NewShake128()
+		shake.Write([]byte("compact"))
+		return shake
+	}
+	
+	pk, full := xmss.KeyGen(seeded(), 5, 50)
+	_, compact := xmss.KeyGen(seeded(), 5, 50)
+	if err := xmss.CompactSecretKey(compact, 3); err != nil {
+		t.Fatalf("CompactSecretKey failed: %v", err)
+	}
+	if compact.Tree != nil {
+		t.Fatal("Compact key still holds the full tree")
+	}
+	
+	message := make([]byte, 32)
+	rand.Read(message)
+	for _, epoch := range []uint32{5, 6, 31, 32, 54} {
+		want, err := xmss.Sign(rand.Reader, full, epoch, message)
+		if err != nil {
+			t.Fatalf("Failed to sign with full key: %v", err)
+		}
+		got, err := xmss.Sign(rand.Reader, compact, epoch, message)
+		if err != nil {
+			t.Fatalf("Failed to sign with compact key: %v", err)
+		}
+		if !got.Equal(want) {
+			t.Fatalf("Signatures differ at epoch %d", epoch)
+		}
+		if !xmss.Verify(pk, epoch, message, got) {
+			t.Fatalf("Compact signature fails at epoch %d", epoch)
+		}
+	}
+	
+	if _, err := compact.MarshalBinary(); err == nil {
+		t.Fatal("Compact key should not serialize")
+	}
+}
+
+// Test that RecomputeTree reproduces the KeyGen root from the PRF key and parameter
+func TestRecomputeTree(t *testing.T) {
+	prfInstance := prf.NewSHA3PRF(24, 24)
+	thInstance := tweak_hash.NewSHA3TweakableHash(24, 24)
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	encInstance := winternitz.NewWinternitzEncoding(mhInstance, 4, 3)
+	
+	xmss := NewGeneralizedXMSS(prfInstance, encInstance, thInstance, 5)
+	seeded := func() io.Reader {
+		shake := sha3.NewShake128()
+		shake.Write([]byte("recompute"))
+		return shake
+	}
+	
+	// A sparse range, so the tree has padding at both ends
+	pk, sk := xmss.KeyGen(seeded(), 3, 20)
+	
+	// Advance a fresh copy of the rng past the parameter and the PRF key
+	padding := seeded()
+	thInstance.RandParameter(padding)
+	prfInstance.KeyGen(padding)
+	
+	root := xmss.RecomputeTree(sk.PRFKey, sk.Parameter, 3, 20, padding)
+	if !bytes.Equal(root, pk.Root) {
+		t.Fatal("Recomputed root differs from the public key root")
+	}
+	
+	// Different padding or a different PRF key give a different root
+	if bytes.Equal(xmss.RecomputeTree(sk.PRFKey, sk.Parameter, 3, 20, rand.Reader), pk.Root) {
+		t.Fatal("Root should depend on the padding")
+	}
+	otherKey := bytes.Clone(sk.PRFKey)
+	otherKey[0] ^= 1
+	padding = seeded()
+	thInstance.RandParameter(padding)
+	prfInstance.KeyGen(padding)
+	if bytes.Equal(xmss.RecomputeTree(otherKey, sk.Parameter, 3, 20, padding), pk.Root) {
+		t.Fatal("Root should depend on the PRF key")
+	}
+}
+
+// Test that KeyGenWithMemStats reports a peak and generates the same keys as KeyGen
+func TestKeyGenWithMemStats(t *testing.T) {
+	prfInstance := prf.NewSHA3PRF(24, 24)
+	thInstance := tweak_hash.NewSHA3TweakableHash(24, 24)
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	encInstance := winternitz.NewWinternitzEncoding(mhInstance, 4, 3)
+	
+	xmss := NewGeneralizedXMSS(prfInstance, encInstance, thInstance, 5)
+	seeded := func() io.Reader {
+		shake := sha3.NewShake128()
+		shake.Write([]byte("memstats"))
+		return shake
+	}
+	
+	pk1, sk1 := xmss.KeyGen(seeded(), 2, 20)
+	pk2, sk2, peak := xmss.KeyGenWithMemStats(seeded(), 2, 20)
+	if peak == 0 {
+		t.Fatal("Expected a nonzero peak HeapAlloc")
+	}
+	t.Logf("Peak HeapAlloc %d bytes", peak)
+	
+	if !bytes.Equal(pk1.Root, pk2.Root) || !bytes.Equal(pk1.Parameter, pk2.Parameter) {
+		t.Fatal("Public keys differ")
+	}
+	enc1, err := sk1.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to encode secret key: %v", err)
+	}
+	enc2, err := sk2.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to encode secret key: %v", err)
+	}
+	if !bytes.Equal(enc1, enc2) {
+		t.Fatal("Secret keys differ")
+	}
+}
+
+// failingReader returns err after serving n bytes
+type failingReader struct {
+	n   int
+	err error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.n == 0 {
+		return 0, r.err
+	}
+	k := min(len(p), r.n)
+	for i := range p[:k] {
+		p[i] = 0
+	}
+	r.n -= k
+	return k, nil
+}
+
+// Test that a failing or short RNG aborts signing instead of yielding weak randomness
+func TestSignSurfacesRandomnessError(t *testing.T) {
+	prfInstance := prf.NewSHA3PRF(24, 24)
+	thInstance := tweak_hash.NewSHA3TweakableHash(24, 24)
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	
+	errRNG := errors.New("rng unavailable")
+	schemes := map[string]*GeneralizedXMSS{
+		"Winternitz": NewGeneralizedXMSS(prfInstance, winternitz.NewWinternitzEncoding(mhInstance, 4, 3), thInstance, 2),
+		"TargetSum":  NewGeneralizedXMSS(prfInstance, targetsum.NewTargetSumEncoding(mhInstance, 360), thInstance, 2),
+	}
+	for name, xmss := range schemes {
+		t.Run(name, func(t *testing.T) {
+			_, sk := xmss.KeyGen(rand.Reader, 0, 4)
+			message := make([]byte, 32)
+			
+			if _, err := xmss.Sign(&failingReader{err: errRNG}, sk, 1, message); !errors.Is(err, errRNG) {
+				t.Fatalf("Expected RNG error, got %v", err)
+			}
+			
+			// A reader that ends halfway through rho is a short read, not zero padding
+			if _, err := xmss.Sign(&failingReader{n: 12, err: io.EOF}, sk, 1, message); !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Fatalf("Expected short-read error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyConstantTime(t *testing.T) {
+	prfInstance := prf.NewSHA3PRF(24, 24)
+	thInstance := tweak_hash.NewSHA3TweakableHash(24, 24)
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	encInstance := winternitz.NewWinternitzEncoding(mhInstance, 4, 3)
+	
+	xmss := NewGeneralizedXMSS(prfInstance, encInstance, thInstance, 5)
+	pk, sk := xmss.KeyGen(rand.Reader, 0, 32)
+	
+	for epoch := uint32(0); epoch < 4; epoch++ {
+		message := make([]byte, 32)
+		rand.Read(message)
+		
+		sig, err := xmss.Sign(rand.Reader, sk, epoch, message)
+		if err != nil {
+			t.Fatalf("Failed to sign epoch %d: %v", epoch, err)
+		}
+		
+		// Same verdicts as Verify for valid and invalid inputs
+		other := make([]byte, 32)
+		rand.Read(other)
+		cases := []struct {
+			name    string
+			epoch   uint32
+			message []byte
+		}{
+			{"valid", epoch, message},
+			{"wrong message", epoch, other},
+			{"wrong epoch", epoch + 1, message},
+			{"beyond lifetime", 32, message},
+		}
+		for _, c := range cases {
+			want := xmss.Verify(pk, c.epoch, c.message, sig)
+			if got := xmss.VerifyConstantTime(pk, c.epoch, c.message, sig); got != want {
+				t.Fatalf("Epoch %d, %s: VerifyConstantTime = %v, Verify = %v", epoch, c.name, got, want)
+			}
+		}
+		if !xmss.VerifyConstantTime(pk, epoch, message, sig) {
+			t.Fatalf("Valid signature rejected at epoch %d", epoch)
+		}
+	}
+}
+
+func BenchmarkWinternitzSign(b *testing.B) {
+	prfInstance := prf.NewSHA3PRF(24, 24)
+	thInstance := tweak_hash.NewSHA3TweakableHash(24, 24)
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	encInstance := winternitz.NewWinternitzEncoding(mhInstance, 4, 3)
+	
+	xmss := NewGeneralizedXMSS(prfInstance, encInstance, thInstance, 9)
+	_, sk := xmss.KeyGen(rand.Reader, 0, 512)
+	
+	message := make([]byte, 32)
+	rand.Read(message)
+	
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		epoch := uint32(i % 512)
+		_, err := xmss.Sign(rand.Reader, sk, epoch, message)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWinternitzVerify(b *testing.B) {
+	prfInstance := prf.NewSHA3PRF(24, 24)
+	thInstance := tweak_hash.NewSHA3TweakableHash(24, 24)
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	encInstance := winternitz.NewWinternitzEncoding(mhInstance, 4, 3)
+	
+	xmss := NewGeneralizedXMSS(prfInstance, encInstance, thInstance, 9)
+	pk, sk := xmss.KeyGen(rand.Reader, 0, 512)
+	
+	message := make([]byte, 32)
+	rand.Read(message)
+	sig, _ := xmss.Sign(rand.Reader, sk, 0, message)
+	
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !xmss.Verify(pk, 0, message, sig) {
+			b.Fatal("Verification failed")
+		}
+	}
+}
+
+// Test that a tracing Poseidon hash records every hash call made by Sign and Verify
+func TestPoseidonSignTrace(t *testing.T) {
+	const (
+		logLifetime = 4
+		epoch       = 3
+	)
+	
+	tracer := tweak_hash.NewTracingPoseidonTweakHash(tweak_hash.NewPoseidonTweakHash(
+		PoseidonParameterLen, PoseidonHashLenFE, PoseidonTweakLenFE, PoseidonCapacity, PoseidonNumChunksW4,
+	))
+	mhInstance := message_hash.NewPoseidonMessageHash(
+		PoseidonParameterLen, PoseidonRandLen, PoseidonMsgHashLenFE,
+		PoseidonNumChunksW4, PoseidonBaseW4, PoseidonTweakLenFE, PoseidonMsgLenFE,
+	)
+	encInstance := winternitz.NewWinternitzEncoding(mhInstance, PoseidonChunkSizeW4, PoseidonNumChunksChecksumW4)
+	xmss := NewGeneralizedXMSS(prf.NewShakePRFtoField(32, PoseidonHashLenFE), encInstance, tracer, logLifetime)
+	
+	pk, sk := xmss.KeyGen(rand.Reader, epoch, 1)
+	
+	message := make([]byte, 32)
+	rand.Read(message)
+	
+	tracer.Reset()
+	sig, err := xmss.Sign(rand.Reader, sk, epoch, message)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	
+	codeword, err := encInstance.Encode(pk.Parameter, message, sig.Rho, epoch)
+	if err != nil {
+		t.Fatalf("Failed to re-encode: %v", err)
+	}
+	
+	// Signing walks chain i for codeword[i] steps
+	signCalls := 0
+	for _, x := range codeword {
+		signCalls += int(x)
+	}
+	trace := tracer.Trace()
+	if len(trace) != signCalls {
+		t.Fatalf("Sign recorded %d invocations, expected %d", len(trace), signCalls)
+	}
+	for i, inv := range trace {
+		if len(inv.Params) != PoseidonParameterLen || len(inv.Tweak) != PoseidonTweakLenFE || len(inv.Data) != PoseidonHashLenFE {
+			t.Fatalf("Invocation %d has unexpected input sizes", i)
+		}
+	}
+	
+	// Verification completes every chain, hashes the leaf and walks the path
+	tracer.Reset()
+	if !xmss.Verify(pk, epoch, message, sig) {
+		t.Fatal("Signature verification failed")
+	}
+	verifyCalls := len(codeword)*(encInstance.Base()-1) - signCalls + 1 + logLifetime
+	if got := len(tracer.Trace()); got != verifyCalls {
+		t.Fatalf("Verify recorded %d invocations, expected %d", got, verifyCalls)
+	}
+	
+	// The constant-time variant walks every chain in full, whatever the codeword
+	tracer.Reset()
+	if !xmss.VerifyConstantTime(pk, epoch, message, sig) {
+		t.Fatal("Constant-time signature verification failed")
+	}
+	constantCalls := len(codeword)*(encInstance.Base()-1) + 1 + logLifetime
+	if got := len(tracer.Trace()); got != constantCalls {
+		t.Fatalf("VerifyConstantTime recorded %d invocations, expected %d", got, constantCalls)
+	}
+}
+
+// Test per-epoch chain parameters: signatures verify, epochs get distinct
+// parameters, and the scheme is incompatible with the shared-parameter one
+func TestPerEpochParameters(t *testing.T) {
+	newScheme := func(opts ...Option) *GeneralizedXMSS {
+		mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+		return NewGeneralizedXMSS(prf.NewSHA3PRF(24, 24), winternitz.NewWinternitzEncoding(mhInstance, 4, 3),
+			tweak_hash.NewSHA3TweakableHash(24, 24), 4, opts...)
+	}
+	seeded := func() io.Reader {
+		shake := sha3.NewShake128()
+		shake.Write([]byte("per-epoch"))
+		return shake
+	}
+	shared := newScheme()
+	perEpoch := newScheme(WithPerEpochParameters())
+	
+	pk, sk := perEpoch.KeyGen(seeded(), 0, 16)
+	sharedPK, _ := shared.KeyGen(seeded(), 0, 16)
+	if bytes.Equal(pk.Root, sharedPK.Root) {
+		t.Fatal("Per-epoch and shared-parameter keys have the same root")
+	}
+	
+	p0 := perEpoch.EpochParameter(pk.Parameter, 0)
+	p1 := perEpoch.EpochParameter(pk.Parameter, 1)
+	if len(p0) != len(pk.Parameter) || bytes.Equal(p0, p1) || bytes.Equal(p0, pk.Parameter) {
+		t.Fatal("Epoch parameters are not distinct parameter-length values")
+	}
+	if !bytes.Equal(shared.EpochParameter(pk.Parameter, 3), pk.Parameter) {
+		t.Fatal("Shared-parameter scheme should use the key parameter")
+	}
+	
+	message := []byte("per-epoch parameters")
+	for _, epoch := range []uint32{0, 7, 15} {
+		sig, err := perEpoch.Sign(rand.Reader, sk, epoch, message)
+		if err != nil {
+			t.Fatalf("Failed to sign at epoch %d: %v", epoch, err)
+		}
+		if !perEpoch.Verify(pk, epoch, message, sig) || !perEpoch.VerifyConstantTime(pk, epoch, message, sig) {
+			t.Fatalf("Valid signature rejected at epoch %d", epoch)
+		}
+		if shared.Verify(pk, epoch, message, sig) {
+			t.Fatalf("Shared-parameter scheme accepted a per-epoch signature at epoch %d", epoch)
+		}
+	}
+}
+
+// Test that VerifyWithChunks agrees with Verify for chunks from the message hash
+func TestVerifyWithChunks(t *testing.T) {
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	schemes := map[string]*GeneralizedXMSS{
+		"Winternitz": NewGeneralizedXMSS(prf.NewSHA3PRF(24, 24), winternitz.NewWinternitzEncoding(mhInstance, 4, 3), tweak_hash.NewSHA3TweakableHash(24, 24), 4),
+		"TargetSum":  NewGeneralizedXMSS(prf.NewSHA3PRF(24, 24), targetsum.NewTargetSumEncoding(mhInstance, 360), tweak_hash.NewSHA3TweakableHash(24, 24), 4),
+	}
+	
+	for name, xmss := range schemes {
+		t.Run(name, func(t *testing.T) {
+			pk, sk := xmss.KeyGen(rand.Reader, 0, 16)
+			message := make([]byte, 32)
+			rand.Read(message)
+			const epoch = 6
+			
+			sig, err := xmss.Sign(rand.Reader, sk, epoch, message)
+			if err != nil {
+				t.Fatalf("Failed to sign: %v", err)
+			}
+			chunks := mhInstance.Hash(pk.Parameter, message, sig.Rho, epoch)
+			
+			if !xmss.Verify(pk, epoch, message, sig) || !xmss.VerifyWithChunks(pk, epoch, chunks, sig) {
+				t.Fatal("Valid signature rejected")
+			}
+			if xmss.VerifyWithChunks(pk, epoch+1, chunks, sig) {
+				t.Fatal("Accepted chunks at the wrong epoch")
+			}
+			
+			// Chunks of another message, with the sum kept for Target-Sum
+			other := bytes.Clone(chunks)
+			other[0], other[1] = other[1], other[0]
+			if other[0] == other[1] {
+				other[0], other[2] = other[2], other[0]
+			}
+			if !bytes.Equal(other, chunks) && xmss.VerifyWithChunks(pk, epoch, other, sig) {
+				t.Fatal("Accepted different chunks")
+			}
+			
+			if xmss.VerifyWithChunks(pk, epoch, chunks[1:], sig) {
+				t.Fatal("Accepted the wrong number of chunks")
+			}
+			outOfRange := bytes.Clone(chunks)
+			outOfRange[0] = 16
+			if xmss.VerifyWithChunks(pk, epoch, outOfRange, sig) {
+				t.Fatal("Accepted a chunk out of range")
+			}
+		})
+	}
+}
+
+// Test that WithProgress reports every leaf and level in order. The
+// callback appends without locking, so concurrent calls fail under -race.
+func TestKeyGenProgress(t *testing.T) {
+	for _, numActive := range []int{5, 24} {
+		var calls [][2]int
+		mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+		xmss := NewGeneralizedXMSS(prf.NewSHA3PRF(24, 24), winternitz.NewWinternitzEncoding(mhInstance, 4, 3),
+			tweak_hash.NewSHA3TweakableHash(24, 24), 5,
+			WithProgress(func(done, total int) { calls = append(calls, [2]int{done, total}) }))
+		
+		xmss.KeyGen(rand.Reader, 3, numActive)
+		
+		total := numActive + 5
+		if len(calls) != total {
+			t.Fatalf("%d active epochs: got %d progress calls, want %d", numActive, len(calls), total)
+		}
+		for i, c := range calls {
+			if c != [2]int{i + 1, total} {
+				t.Fatalf("%d active epochs: call %d reported %v, want [%d %d]", numActive, i, c, i+1, total)
+			}
+		}
+	}
+}
+
+// truncatingEncoding wraps an encoding and drops the last chunk of every
+// codeword, to exercise the codeword length check
+type truncatingEncoding struct {
+	encoding.IncomparableEncoding
+}
+
+func (e truncatingEncoding) Encode(P th.Params, msg []byte, rho []byte, epoch uint32) (encoding.Codeword, error) {
+	codeword, err := e.IncomparableEncoding.Encode(P, msg, rho, epoch)
+	if err != nil {
+		return nil, err
+	}
+	return codeword[:len(codeword)-1], nil
+}
+
+// Test that VerifyDetailed reports which verification step failed
+func TestVerifyDetailed(t *testing.T) {
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	thInstance := tweak_hash.NewSHA3TweakableHash(24, 24)
+	xmss := NewGeneralizedXMSS(prf.NewSHA3PRF(24, 24), targetsum.NewTargetSumEncoding(mhInstance, 360), thInstance, 4)
+	
+	pk, sk := xmss.KeyGen(rand.Reader, 0, 16)
+	message := make([]byte, 32)
+	rand.Read(message)
+	const epoch = 3
+	
+	sig, err := xmss.Sign(rand.Reader, sk, epoch, message)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	if err := xmss.VerifyDetailed(pk, epoch, message, sig); err != nil {
+		t.Fatalf("Valid signature rejected: %v", err)
+	}
+	
+	if err := xmss.VerifyDetailed(pk, 16, message, sig); !errors.Is(err, ErrEpochOutOfRange) {
+		t.Fatalf("Expected ErrEpochOutOfRange, got %v", err)
+	}
+	
+	// Other randomness almost never hits the target sum, and if it does
+	// the chains end elsewhere
+	badRho := *sig
+	badRho.Rho = bytes.Clone(sig.Rho)
+	badRho.Rho[0] ^= 1
+	err = xmss.VerifyDetailed(pk, epoch, message, &badRho)
+	if !errors.Is(err, ErrEncode) && !errors.Is(err, ErrMerkleMismatch) {
+		t.Fatalf("Expected ErrEncode or ErrMerkleMismatch, got %v", err)
+	}
+	
+	badHash := *sig
+	badHash.Hashes = append([]th.Domain(nil), sig.Hashes...)
+	badHash.Hashes[0] = bytes.Clone(sig.Hashes[0])
+	badHash.Hashes[0][0] ^= 1
+	if err := xmss.VerifyDetailed(pk, epoch, message, &badHash); !errors.Is(err, ErrMerkleMismatch) {
+		t.Fatalf("Expected ErrMerkleMismatch, got %v", err)
+	}
+	
+	truncating := NewGeneralizedXMSS(prf.NewSHA3PRF(24, 24), truncatingEncoding{xmss.encoding}, thInstance, 4)
+	if err := truncating.VerifyDetailed(pk, epoch, message, sig); !errors.Is(err, ErrCodewordLength) {
+		t.Fatalf("Expected ErrCodewordLength, got %v", err)
+	}
+	
+	if xmss.Verify(pk, epoch, message, &badHash) || !xmss.Verify(pk, epoch, message, sig) {
+		t.Fatal("Verify disagrees with VerifyDetailed")
+	}
+}
+
+// Test that RecomputeRoot returns the root Verify compares against pk.Root
+func TestRecomputeRoot(t *testing.T) {
+	xmss := NewPoseidonWinternitzW4Test(4)
+	pk, sk := xmss.KeyGen(rand.Reader, 0, 16)
+	message := make([]byte, 32)
+	rand.Read(message)
+	const epoch = 5
+	
+	sig, err := xmss.Sign(rand.Reader, sk, epoch, message)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	root, err := xmss.RecomputeRoot(pk, epoch, message, sig)
+	if err != nil || !bytes.Equal(root, pk.Root) {
+		t.Fatalf("Valid signature recomputes %x, %v; want %x", root, err, pk.Root)
+	}
+	
+	// A corrupted co-path node yields a different root, not an error
+	badPath := *sig
+	badPath.Path.CoPath = append([]th.Domain(nil), sig.Path.CoPath...)
+	badPath.Path.CoPath[2] = bytes.Clone(sig.Path.CoPath[2])
+	badPath.Path.CoPath[2][0] ^= 1
+	root, err = xmss.RecomputeRoot(pk, epoch, message, &badPath)
+	if err != nil || len(root) != len(pk.Root) || bytes.Equal(root, pk.Root) {
+		t.Fatalf("Corrupted co-path recomputes %x, %v", root, err)
+	}
+	if xmss.Verify(pk, epoch, message, &badPath) {
+		t.Fatal("Verify accepted a corrupted co-path")
+	}
+	
+	if _, err := xmss.RecomputeRoot(pk, 16, message, sig); !errors.Is(err, ErrEpochOutOfRange) {
+		t.Fatalf("Expected ErrEpochOutOfRange, got %v", err)
+	}
+	if _, err := xmss.RecomputeRoot(pk, epoch, message, &Signature{}); !errors.Is(err, ErrMalformedSignature) {
+		t.Fatalf("Expected ErrMalformedSignature, got %v", err)
+	}
+}
+
+// Test that truncated or padded signatures are rejected rather than
+// indexed out of range
+func TestVerifyRejectsMalformedSignature(t *testing.T) {
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	encInstance := winternitz.NewWinternitzEncoding(mhInstance, 4, 3)
+	xmss := NewGeneralizedXMSS(prf.NewSHA3PRF(24, 24), encInstance, tweak_hash.NewSHA3TweakableHash(24, 24), 4)
+	
+	pk, sk := xmss.KeyGen(rand.Reader, 0, 16)
+	message := make([]byte, 32)
+	rand.Read(message)
+	const epoch = 9
+	
+	sig, err := xmss.Sign(rand.Reader, sk, epoch, message)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	chunks := mhInstance.Hash(pk.Parameter, message, sig.Rho, epoch)
+	
+	hashes := func(h []th.Domain) Signature { return Signature{Rho: sig.Rho, Path: sig.Path, Hashes: h} }
+	coPath := func(c []th.Domain) Signature {
+		bad := Signature{Rho: sig.Rho, Hashes: sig.Hashes}
+		bad.Path.CoPath = c
+		return bad
+	}
+	malformed := map[string]Signature{
+		"NoHashes":    hashes(nil),
+		"ShortHashes": hashes(sig.Hashes[:len(sig.Hashes)-1]),
+		"LongHashes":  hashes(append(append([]th.Domain(nil), sig.Hashes...), sig.Hashes[0])),
+		"NoCoPath":    coPath(nil),
+		"ShortCoPath": coPath(sig.Path.CoPath[:len(sig.Path.CoPath)-1]),
+		"LongCoPath":  coPath(append(append([]th.Domain(nil), sig.Path.CoPath...), sig.Path.CoPath[0])),
+		"LongHash":    hashes(append([]th.Domain{append(bytes.Clone(sig.Hashes[0]), 0)}, sig.Hashes[1:]...)),
+		"ShortNode":   coPath(append([]th.Domain{sig.Path.CoPath[0][:23]}, sig.Path.CoPath[1:]...)),
+	}
+	
+	for name, bad := range malformed {
+		t.Run(name, func(t *testing.T) {
+			if err := xmss.VerifyDetailed(pk, epoch, message, &bad); !errors.Is(err, ErrMalformedSignature) {
+				t.Fatalf("Expected ErrMalformedSignature, got %v", err)
+			}
+			if xmss.Verify(pk, epoch, message, &bad) || xmss.VerifyConstantTime(pk, epoch, message, &bad) || xmss.VerifyWithChunks(pk, epoch, chunks, &bad) {
+				t.Fatal("Accepted malformed signature")
+			}
+		})
+	}
+}
+
+// Test that the public key derived from a decoded or compacted secret key
+// matches KeyGen's and verifies its signatures
+func TestSecretKeyPublicKey(t *testing.T) {
+	thInstance := tweak_hash.NewSHA3TweakableHash(24, 24)
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	xmss := NewGeneralizedXMSS(prf.NewSHA3PRF(24, 24), winternitz.NewWinternitzEncoding(mhInstance, 4, 3), thInstance, 5)
+	pk, sk := xmss.KeyGen(rand.Reader, 2, 12)
+	
+	data, err := sk.MarshalJSON()
+	if err != nil {
+		t.Fatalf("Failed to encode secret key: %v", err)
+	}
+	decoded, err := UnmarshalSecretKey(data, thInstance)
+	if err != nil {
+		t.Fatalf("Failed to decode secret key: %v", err)
+	}
+	derived := decoded.PublicKey()
+	if !bytes.Equal(derived.Root, pk.Root) || !bytes.Equal(derived.Parameter, pk.Parameter) {
+		t.Fatal("Derived public key differs from KeyGen's")
+	}
+	
+	message := make([]byte, 32)
+	rand.Read(message)
+	sig, err := xmss.Sign(rand.Reader, decoded, 7, message)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	if !xmss.Verify(derived, 7, message, sig) {
+		t.Fatal("Derived public key rejected a valid signature")
+	}
+	
+	if err := xmss.CompactSecretKey(sk, 2); err != nil {
+		t.Fatalf("Failed to compact: %v", err)
+	}
+	if !bytes.Equal(sk.PublicKey().Root, pk.Root) {
+		t.Fatal("Compacted key derives a different root")
+	}
+	
+	sk.Destroy()
+	if sk.PublicKey() != nil {
+		t.Fatal("Destroyed key derived a public key")
+	}
+}
+
+// Test that KeyGen with WithTreeSelfCheck produces working keys for full,
+// sparse and single-epoch activation ranges
+func TestWithTreeSelfCheck(t *testing.T) {
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	xmss := NewGeneralizedXMSS(prf.NewSHA3PRF(24, 24), winternitz.NewWinternitzEncoding(mhInstance, 4, 3), tweak_hash.NewSHA3TweakableHash(24, 24), 5, WithTreeSelfCheck())
+	
+	message := make([]byte, 32)
+	rand.Read(message)
+	for _, r := range [][2]int{{0, 32}, {3, 17}, {31, 1}} {
+		pk, sk := xmss.KeyGen(rand.Reader, r[0], r[1])
+		epoch := uint32(r[0] + r[1] - 1)
+		sig, err := xmss.Sign(rand.Reader, sk, epoch, message)
+		if err != nil {
+			t.Fatalf("Range %v: failed to sign: %v", r, err)
+		}
+		if !xmss.Verify(pk, epoch, message, sig) {
+			t.Fatalf("Range %v: signature rejected", r)
+		}
+	}
+}
+
+// Test that WithConstantTimeChains produces the same signatures as the
+// default chain walk
+func TestConstantTimeChains(t *testing.T) {
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	encodings := map[string]encoding.IncomparableEncoding{
+		"Winternitz": winternitz.NewWinternitzEncoding(mhInstance, 4, 3),
+		"TargetSum":  targetsum.NewTargetSumEncoding(mhInstance, 360),
+	}
+	seeded := func() io.Reader {
+		shake := sha3.NewShake128()
+		shake.Write([]byte("constant-time chains"))
+		return shake
+	}
+	
+	for name, enc := range encodings {
+		t.Run(name, func(t *testing.T) {
+			thInstance := tweak_hash.NewSHA3TweakableHash(24, 24)
+			plain := NewGeneralizedXMSS(prf.NewSHA3PRF(24, 24), enc, thInstance, 4)
+			constant := NewGeneralizedXMSS(prf.NewSHA3PRF(24, 24), enc, thInstance, 4, WithConstantTimeChains())
+			
+			pk, sk := plain.KeyGen(rand.Reader, 0, 16)
+			message := make([]byte, 32)
+			rand.Read(message)
+			for _, epoch := range []uint32{0, 5, 15} {
+				want, err := plain.Sign(seeded(), sk, epoch, message)
+				if err != nil {
+					t.Fatalf("Failed to sign: %v", err)
+				}
+				got, err := constant.Sign(seeded(), sk, epoch, message)
+				if err != nil {
+					t.Fatalf("Failed to sign with constant-time chains: %v", err)
+				}
+				if !got.Equal(want) {
+					t.Fatalf("Signatures differ at epoch %d", epoch)
+				}
+				if !constant.Verify(pk, epoch, message, got) {
+					t.Fatalf("Signature at epoch %d rejected", epoch)
+				}
+			}
+		})
+	}
+}
+
+// countingPRF counts PRF evaluations
+type countingPRF struct {
+	prf.PRF
+	calls atomic.Int64
+}
+
+func (c *countingPRF) Apply(key []byte, epoch uint32, chainIndex uint64) th.Domain {
+	c.calls.Add(1)
+	return c.PRF.Apply(key, epoch, chainIndex)
+}
+
+// Test that WithChainCache reuses chain walks when an epoch is signed
+// again, stays bounded, and is wiped by Destroy
+func TestChainCache(t *testing.T) {
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	encInstance := winternitz.NewWinternitzEncoding(mhInstance, 4, 3)
+	counter := &countingPRF{PRF: prf.NewSHA3PRF(24, 24)}
+	numChains := encInstance.Dimension()
+	xmss := NewGeneralizedXMSS(counter, encInstance, tweak_hash.NewSHA3TweakableHash(24, 24), 4, WithChainCache(2*numChains))
+	uncached := NewGeneralizedXMSS(counter, encInstance, tweak_hash.NewSHA3TweakableHash(24, 24), 4)
+	
+	pk, sk := xmss.KeyGen(rand.Reader, 0, 16)
+	message := make([]byte, 32)
+	rand.Read(message)
+	seeded := func() io.Reader {
+		shake := sha3.NewShake128()
+		shake.Write([]byte("chain cache"))
+		return shake
+	}
+	
+	first, err := xmss.Sign(seeded(), sk, 4, message)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	counter.calls.Store(0)
+	second, err := xmss.Sign(seeded(), sk, 4, message)
+	if err != nil {
+		t.Fatalf("Failed to sign again: %v", err)
+	}
+	if calls := counter.calls.Load(); calls != 0 {
+		t.Fatalf("Re-signing evaluated the PRF %d times", calls)
+	}
+	if !second.Equal(first) || !xmss.Verify(pk, 4, message, second) {
+		t.Fatal("Cached signature differs from or fails to verify like the original")
+	}
+	
+	// A scheme without the cache computes the same signature
+	want, err := uncached.Sign(seeded(), sk, 4, message)
+	if err != nil {
+		t.Fatalf("Failed to sign without cache: %v", err)
+	}
+	if !want.Equal(first) {
+		t.Fatal("Cached and uncached signatures differ")
+	}
+	
+	// Two more epochs overflow the cache, which starts afresh
+	for _, epoch := range []uint32{5, 6} {
+		if _, err := xmss.Sign(seeded(), sk, epoch, message); err != nil {
+			t.Fatalf("Failed to sign epoch %d: %v", epoch, err)
+		}
+		if n := len(sk.chainCache.entries); n > 2*numChains {
+			t.Fatalf("Cache holds %d entries, limit %d", n, 2*numChains)
+		}
+	}
+	
+	cache := sk.chainCache
+	entries := cache.entries
+	sk.Destroy()
+	if sk.chainCache != nil || cache.entries != nil {
+		t.Fatal("Destroy kept the chain cache")
+	}
+	for _, value := range entries {
+		if !bytes.Equal(value, make([]byte, len(value))) {
+			t.Fatal("Destroy did not wipe cached chain values")
+		}
+	}
+}
+
+// Test that schemes with different contexts derive different keys and
+// reject each other's signatures, and that an empty context changes nothing
+func TestWithContext(t *testing.T) {
+	newScheme := func(opts ...Option) *GeneralizedXMSS {
+		mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+		return NewGeneralizedXMSS(prf.NewSHA3PRF(24, 24), winternitz.NewWinternitzEncoding(mhInstance, 4, 3),
+			tweak_hash.NewSHA3TweakableHash(24, 24), 4, opts...)
+	}
+	seeded := func() io.Reader {
+		shake := sha3.NewShake128()
+		shake.Write([]byte("context"))
+		return shake
+	}
+	plain := newScheme()
+	empty := newScheme(WithContext(nil))
+	appA := newScheme(WithContext([]byte("application A")))
+	appB := newScheme(WithContext([]byte("application B")))
+	
+	message := make([]byte, 32)
+	rand.Read(message)
+	const epoch = 2
+	
+	pkPlain, _ := plain.KeyGen(seeded(), 0, 16)
+	pkEmpty, _ := empty.KeyGen(seeded(), 0, 16)
+	if !bytes.Equal(pkPlain.Root, pkEmpty.Root) {
+		t.Fatal("Empty context changed the key")
+	}
+	
+	pkA, skA := appA.KeyGen(seeded(), 0, 16)
+	pkB, skB := appB.KeyGen(seeded(), 0, 16)
+	if bytes.Equal(pkA.Root, pkB.Root) || bytes.Equal(pkA.Root, pkPlain.Root) {
+		t.Fatal("Contexts did not separate the keys")
+	}
+	
+	sigA, err := appA.Sign(rand.Reader, skA, epoch, message)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	if !appA.Verify(pkA, epoch, message, sigA) {
+		t.Fatal("Valid signature rejected")
+	}
+	if appB.Verify(pkA, epoch, message, sigA) || plain.Verify(pkA, epoch, message, sigA) {
+		t.Fatal("Signature verified under another context")
+	}
+	
+	// Even a signature from the same key material does not cross contexts
+	sigB, err := appB.Sign(rand.Reader, skB, epoch, message)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	if appA.Verify(pkB, epoch, message, sigB) {
+		t.Fatal("Context B signature verified under context A")
+	}
+	
+	// VerifyWithChunks takes chunks of the context-bound message
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	chunks := mhInstance.Hash(pkA.Parameter, appA.ContextMessage(message), sigA.Rho, epoch)
+	if !appA.VerifyWithChunks(pkA, epoch, chunks, sigA) {
+		t.Fatal("Chunks of the context message rejected")
+	}
+}
+
+// Test that the leaf KeyGen stores for an epoch is merkle.HashLeaf of the
+// chain ends a verifier completes from a signature, so signer and verifier
+// agree on the leaf-hashing convention
+func TestLeafHashSignerVerifierAgree(t *testing.T) {
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	encInstance := winternitz.NewWinternitzEncoding(mhInstance, 4, 3)
+	thInstance := tweak_hash.NewSHA3TweakableHash(24, 24)
+	xmss := NewGeneralizedXMSS(prf.NewSHA3PRF(24, 24), encInstance, thInstance, 4)
+	
+	pk, sk := xmss.KeyGen(rand.Reader, 2, 10)
+	message := make([]byte, 32)
+	rand.Read(message)
+	
+	for _, epoch := range []uint32{2, 7, 11} {
+		sig, err := xmss.Sign(rand.Reader, sk, epoch, message)
+		if err != nil {
+			t.Fatalf("Failed to sign: %v", err)
+		}
+		codeword, err := encInstance.Encode(pk.Parameter, message, sig.Rho, epoch)
+		if err != nil {
+			t.Fatalf("Failed to encode: %v", err)
+		}
+		chainEnds := make([]th.Domain, len(codeword))
+		for i, x := range codeword {
+			chainEnds[i] = th.Chain(thInstance, pk.Parameter, epoch, uint8(i), x, encInstance.Base()-1-int(x), sig.Hashes[i])
+		}
+		
+		leaves := sk.Tree.GetLayers()[0]
+		stored := leaves.GetNodes()[int(epoch)-leaves.GetStartIndex()]
+		if !bytes.Equal(merkle.HashLeaf(thInstance, pk.Parameter, epoch, chainEnds), stored) {
+			t.Fatalf("Verifier leaf differs from signer leaf at epoch %d", epoch)
+		}
+	}
+}
+
+// Test that VerifyPrepared agrees with Verify, with and without per-epoch parameters
+func TestVerifyPrepared(t *testing.T) {
+	perEpoch := NewPoseidonWinternitzW4Test(4)
+	WithPerEpochParameters()(perEpoch)
+	schemes := map[string]*GeneralizedXMSS{
+		"Poseidon":         NewPoseidonWinternitzW4Test(4),
+		"SHA3":             NewSHA3WinternitzW4Test(4),
+		"PerEpochPoseidon": perEpoch,
+	}
+	
+	for name, xmss := range schemes {
+		t.Run(name, func(t *testing.T) {
+			pk, sk := xmss.KeyGen(rand.Reader, 0, 16)
+			prepared := pk.Prepare(xmss.th)
+			message := make([]byte, 32)
+			rand.Read(message)
+			other := bytes.Clone(message)
+			other[0] ^= 1
+			
+			for _, epoch := range []uint32{0, 9, 15} {
+				sig, err := xmss.Sign(rand.Reader, sk, epoch, message)
+				if err != nil {
+					t.Fatalf("Failed to sign at epoch %d: %v", epoch, err)
+				}
+				if !xmss.VerifyPrepared(prepared, epoch, message, sig) {
+					t.Fatalf("Valid signature rejected at epoch %d", epoch)
+				}
+				if xmss.VerifyPrepared(prepared, epoch, other, sig) || xmss.VerifyPrepared(prepared, epoch+1, message, sig) {
+					t.Fatalf("Signature at epoch %d verified for the wrong message or epoch", epoch)
+				}
+			}
+		})
+	}
+}
+
+// Test verifying path-less signatures against a pinned tree snapshot
+func TestVerifyWithTree(t *testing.T) {
+	xmss := NewSHA3WinternitzW4Test(4)
+	pk, sk := xmss.KeyGen(rand.Reader, 2, 10)
+	_, otherSK := xmss.KeyGen(rand.Reader, 2, 10)
+	if !bytes.Equal(sk.Tree.Root(), pk.Root) {
+		t.Fatal("Snapshot root differs from the public key")
+	}
+	
+	message := make([]byte, 32)
+	rand.Read(message)
+	for _, epoch := range []uint32{2, 7, 11} {
+		sig, err := xmss.Sign(rand.Reader, sk, epoch, message)
+		if err != nil {
+			t.Fatalf("Failed to sign at epoch %d: %v", epoch, err)
+		}
+		compressed := sig.WithoutPath()
+		
+		// Round-trip the compressed form, which must be smaller
+		full, _ := sig.MarshalBinary()
+		data, err := compressed.MarshalBinary()
+		if err != nil {
+			t.Fatalf("Failed to marshal compressed signature: %v", err)
+		}
+		if len(data) >= len(full) {
+			t.Fatalf("Compressed signature is %d bytes, full is %d", len(data), len(full))
+		}
+		decoded, err := UnmarshalSignatureVersioned(data)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal compressed signature: %v", err)
+		}
+		
+		if !xmss.VerifyWithTree(sk.Tree, epoch, message, decoded) {
+			t.Fatalf("Compressed signature rejected at epoch %d", epoch)
+		}
+		if xmss.VerifyWithTree(sk.Tree, epoch, message, sig) {
+			t.Fatal("Signature carrying a co-path accepted")
+		}
+		if xmss.VerifyWithTree(sk.Tree, epoch+1, message, compressed) {
+			t.Fatal("Compressed signature accepted at the wrong epoch")
+		}
+		if xmss.VerifyWithTree(otherSK.Tree, epoch, message, compressed) {
+			t.Fatal("Compressed signature accepted against another key's tree")
+		}
+	}
+	
+	if xmss.VerifyWithTree(sk.Tree, 16, message, &Signature{}) {
+		t.Fatal("Epoch beyond the lifetime accepted")
+	}
+}
+
+// Test that KeyGen refuses the all-zero parameter of a broken RNG
+func TestKeyGenRejectsZeroReader(t *testing.T) {
+	schemes := map[string]*GeneralizedXMSS{
+		"SHA3":     NewSHA3WinternitzW4Test(2),
+		"Poseidon": NewPoseidonWinternitzW4Test(2),
+	}
+	
+	for name, xmss := range schemes {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("Expected KeyGen to panic on an all-zero RNG")
+				}
+			}()
+			xmss.KeyGen(bytes.NewReader(make([]byte, 1<<16)), 0, 4)
+		})
+	}
+}
+
+// Test that SignWithChainStarts reproduces Sign from the epoch's chain starts and path
+func TestSignWithChainStarts(t *testing.T) {
+	mhInstance := message_hash.NewSHA3MessageHash(24, 24, 48, 4)
+	// Counter-derived randomness makes both signatures deterministic
+	encInstance := targetsum.NewTargetSumEncodingCounterRho(mhInstance, 360, []byte("seed"))
+	xmss := NewGeneralizedXMSS(prf.NewSHA3PRF(24, 24), encInstance, tweak_hash.NewSHA3TweakableHash(24, 24), 4,
+		WithContext([]byte("hsm")))
+	pk, sk := xmss.KeyGen(rand.Reader, 0, 16)
+	message := make([]byte, 32)
+	rand.Read(message)
+	
+	for _, epoch := range []uint32{0, 5, 15} {
+		want, err := xmss.Sign(rand.Reader, sk, epoch, message)
+		if err != nil {
+			t.Fatalf("Failed to sign at epoch %d: %v", epoch, err)
+		}
+		starts := xmss.ChainStarts(sk.PRFKey, epoch)
+		got, err := xmss.SignWithChainStarts(rand.Reader, sk.Parameter, epoch, message, starts, sk.Tree.Path(epoch))
+		if err != nil {
+			t.Fatalf("SignWithChainStarts failed at epoch %d: %v", epoch, err)
+		}
+		if !got.Equal(want) || !xmss.Verify(pk, epoch, message, got) {
+			t.Fatalf("SignWithChainStarts differs from Sign at epoch %d", epoch)
+		}
+	}
+	
+	starts := xmss.ChainStarts(sk.PRFKey, 1)
+	path := sk.Tree.Path(1)
+	if _, err := xmss.SignWithChainStarts(rand.Reader, sk.Parameter, 1, message, starts[1:], path); err == nil {
+		t.Fatal("Expected an error for missing chain starts")
+	}
+	if _, err := xmss.SignWithChainStarts(rand.Reader, sk.Parameter, 1, message, starts, merkle.HashTreeOpening{}); err == nil {
+		t.Fatal("Expected an error for a missing co-path")
+	}
+	if _, err := xmss.SignWithChainStarts(rand.Reader, sk.Parameter, 16, message, starts, path); !errors.Is(err, ErrEpochOutOfRange) {
+		t.Fatalf("Expected ErrEpochOutOfRange, got %v", err)
+	}
+}